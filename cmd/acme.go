@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/luisenrique-varelarodriguez/ssl-tool/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	acmeDirectory  string
+	acmeEmails     []string
+	acmeAccountKey string
+	acmeEABKeyID   string
+	acmeEABHMACKey string
+	acmeDomains    []string
+	acmeKeyType    string
+	acmeHTTPListen string
+	acmeWebroot    string
+	acmeOutDir     string
+	acmeRoots      string
+	acmeTimeout    time.Duration
+
+	dnsProvider           string
+	dnsNameserver         string
+	dnsZone               string
+	dnsTSIGKey            string
+	dnsTSIGSecret         string
+	dnsTSIGAlgorithm      string
+	dnsExec               string
+	dnsPropagationTimeout time.Duration
+)
+
+// newACMECmd construye el comando "acme" y sus subcomandos.
+func newACMECmd() *cobra.Command {
+	acmeCmd := &cobra.Command{
+		Use:   "acme",
+		Short: "Obtain certificates from an ACME CA such as Let's Encrypt",
+	}
+	acmeCmd.PersistentFlags().StringVar(&acmeDirectory, "directory", internal.LetsEncryptDirectory, "ACME directory URL")
+	acmeCmd.PersistentFlags().StringVar(&acmeAccountKey, "account-key", "", "Account key file (default: "+internal.DefaultACMEAccountKey+" next to --config; created if it does not exist)")
+	acmeCmd.PersistentFlags().StringSliceVar(&acmeEmails, "email", nil, "Contact email for the account (repeat or separate with commas)")
+	acmeCmd.PersistentFlags().StringVar(&acmeRoots, "ca-roots", "", "PEM bundle of additional roots to trust for the directory (e.g. Pebble's)")
+	acmeCmd.PersistentFlags().DurationVar(&acmeTimeout, "timeout", internal.DefaultACMETimeout, "Timeout for the whole operation")
+
+	issueCmd := &cobra.Command{
+		Use:   "issue",
+		Short: "Issue a certificate solving HTTP-01 (built-in responder or webroot) or DNS-01",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(acmeDomains) == 0 && config.DefaultDomain != "" {
+				acmeDomains = append([]string{config.DefaultDomain}, config.DefaultSANs...)
+			}
+			if interactive {
+				acmeDomains = strings.Split(promptFor("Domains (comma-separated)", strings.Join(acmeDomains, ",")), ",")
+				acmeEmails = strings.Split(promptFor("Contact email", strings.Join(acmeEmails, ",")), ",")
+				acmeWebroot = promptFor("Webroot (empty to use the built-in responder)", acmeWebroot)
+			}
+			var domains []string
+			for _, domain := range acmeDomains {
+				if domain = strings.TrimSpace(domain); domain != "" && !slices.Contains(domains, domain) {
+					domains = append(domains, domain)
+				}
+			}
+			if len(domains) == 0 {
+				return errors.New("please provide --domain or use --interactive")
+			}
+			provider, err := newDNSProvider()
+			if err != nil {
+				return err
+			}
+			account, err := acmeAccountOptions()
+			if err != nil {
+				return err
+			}
+
+			result, err := internal.IssueCertificate(internal.ACMEOptions{
+				Account: account,
+				Domains: domains,
+				CSR: internal.CSRRequest{
+					Country:      orDefault(country, config.DefaultCountry),
+					Locality:     orDefault(locality, config.DefaultLocality),
+					Organization: orDefault(organization, config.DefaultOrganization),
+				},
+				KeyType:     acmeKeyType,
+				HTTPListen:  acmeHTTPListen,
+				Webroot:     acmeWebroot,
+				DNSProvider: provider,
+				OutDir:      acmeOutDir,
+				Timeout:     acmeTimeout,
+				Logf: func(format string, args ...interface{}) {
+					fmt.Printf(format+"\n", args...)
+				},
+			})
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Certificate issued for %s, valid until %s:\n- Private Key: %s\n- Certificate: %s\n",
+				strings.Join(domains, ", "), result.Chain[0].NotAfter.UTC().Format(time.DateOnly), result.KeyPath, result.CertPath)
+			registerInInventory(func(inv *internal.Inventory) error {
+				_, err := inv.AddFile(result.CertPath, "")
+				return err
+			})
+			return nil
+		},
+	}
+	issueCmd.Flags().StringSliceVar(&acmeDomains, "domain", nil, "Domain to include (repeat or separate with commas; the first one is the CN)")
+	issueCmd.Flags().StringVar(&acmeKeyType, "key-type", internal.KeyTypeRSA2048, "Certificate key type: "+strings.Join(internal.KeyTypes, ", "))
+	issueCmd.Flags().StringVar(&acmeHTTPListen, "http-listen", internal.DefaultACMEHTTPListen, "Address of the built-in HTTP-01 responder")
+	issueCmd.Flags().StringVar(&acmeWebroot, "webroot", "", "Write challenge files under this web server root instead of running the responder")
+	issueCmd.Flags().StringVar(&acmeOutDir, "output-dir", "", "Directory for the key and certificate (default: the domain directory, as generate-csr)")
+	issueCmd.Flags().StringVar(&country, "country", "", "Country (2 letters) for the CSR subject")
+	issueCmd.Flags().StringVar(&locality, "locality", "", "Locality (City) for the CSR subject")
+	issueCmd.Flags().StringVar(&organization, "organization", "", "Organization for the CSR subject")
+	addEABFlags(issueCmd)
+	addDNSFlags(issueCmd)
+
+	dnsCheckCmd := &cobra.Command{
+		Use:   "dns-check",
+		Short: "Publish and remove a test TXT record to check the DNS-01 provider configuration",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if interactive {
+				acmeDomains = []string{promptFor("Domain", strings.Join(acmeDomains, ","))}
+			}
+			if len(acmeDomains) != 1 || strings.TrimSpace(acmeDomains[0]) == "" {
+				return errors.New("please provide a single --domain or use --interactive")
+			}
+			if dnsProvider == "" {
+				return errors.New("please provide --dns-provider")
+			}
+			provider, err := newDNSProvider()
+			if err != nil {
+				return err
+			}
+
+			token := make([]byte, 16)
+			if _, err := rand.Read(token); err != nil {
+				return fmt.Errorf("error generating test value: %v", err)
+			}
+			fqdn := internal.DNS01RecordName(strings.TrimSpace(acmeDomains[0]))
+			value := "ssl-tool-check-" + hex.EncodeToString(token)
+			ctx, cancel := context.WithTimeout(context.Background(), acmeTimeout)
+			defer cancel()
+			if err := provider.Present(ctx, fqdn, value); err != nil {
+				return fmt.Errorf("error publishing %s: %v", fqdn, err)
+			}
+			fmt.Printf("Published TXT %s %q\n", fqdn, value)
+			if err := provider.CleanUp(ctx, fqdn, value); err != nil {
+				return fmt.Errorf("error removing %s: %v", fqdn, err)
+			}
+			fmt.Printf("Removed TXT %s %q\n", fqdn, value)
+			return nil
+		},
+	}
+	dnsCheckCmd.Flags().StringSliceVar(&acmeDomains, "domain", nil, "Domain whose _acme-challenge record is published")
+	addDNSFlags(dnsCheckCmd)
+
+	acmeCmd.AddCommand(issueCmd, dnsCheckCmd, newACMEAccountCmd(), newACMERevokeCmd())
+	return acmeCmd
+}
+
+// acmeAccountOptions reúne las opciones de la cuenta. Sin --account-key, la clave se guarda junto
+// al archivo de configuración.
+func acmeAccountOptions() (internal.ACMEAccountOptions, error) {
+	opts := internal.ACMEAccountOptions{
+		DirectoryURL:   acmeDirectory,
+		AccountKeyPath: acmeAccountKey,
+		Roots:          acmeRoots,
+		Contacts:       acmeEmails,
+		EABKeyID:       acmeEABKeyID,
+	}
+	if opts.AccountKeyPath == "" {
+		opts.AccountKeyPath = filepath.Join(filepath.Dir(configPath), internal.DefaultACMEAccountKey)
+	}
+	if acmeEABHMACKey != "" {
+		key, err := readPassphrase(acmeEABHMACKey)
+		if err != nil {
+			return opts, err
+		}
+		opts.EABHMACKey = strings.TrimSpace(string(key))
+	}
+	return opts, nil
+}
+
+// addEABFlags registra las opciones de vinculación de cuenta externa de las CAs comerciales.
+func addEABFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&acmeEABKeyID, "eab-kid", "", "External Account Binding key ID given by the CA (needed by some commercial CAs to register)")
+	cmd.Flags().StringVar(&acmeEABHMACKey, "eab-hmac-key", "", "Base64url External Account Binding HMAC key, literal or as pass:<key>, env:<VAR> or file:<path>")
+}
+
+// addDNSFlags registra las opciones de los proveedores DNS-01.
+func addDNSFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&dnsProvider, "dns-provider", "", "Solve DNS-01 instead of HTTP-01 with this provider: "+strings.Join(internal.DNSProviders, ", "))
+	cmd.Flags().StringVar(&dnsNameserver, "dns-nameserver", "", "Authoritative nameserver (host[:port]) that accepts RFC 2136 updates")
+	cmd.Flags().StringVar(&dnsZone, "dns-zone", "", "Zone to update (default: found through its SOA record)")
+	cmd.Flags().StringVar(&dnsTSIGKey, "tsig-key", "", "TSIG key name for RFC 2136 updates")
+	cmd.Flags().StringVar(&dnsTSIGSecret, "tsig-secret", "", "Base64 TSIG secret, literal or as pass:<secret>, env:<VAR> or file:<path>")
+	cmd.Flags().StringVar(&dnsTSIGAlgorithm, "tsig-algorithm", internal.DefaultTSIGAlgorithm, "TSIG algorithm: hmac-sha1, hmac-sha256 or hmac-sha512")
+	cmd.Flags().StringVar(&dnsExec, "dns-exec", "", "Script for the exec provider, run as <script> present|cleanup <fqdn> <value>")
+	cmd.Flags().DurationVar(&dnsPropagationTimeout, "dns-propagation-timeout", internal.DefaultDNSPropagationTimeout, "How long to wait for the RFC 2136 record to be visible on the nameserver")
+}
+
+// newDNSProvider crea el proveedor DNS-01 indicado con --dns-provider, o nil si no hay ninguno.
+func newDNSProvider() (internal.DNSProvider, error) {
+	switch dnsProvider {
+	case "":
+		return nil, nil
+	case internal.DNSProviderRFC2136:
+		if dnsNameserver == "" {
+			return nil, errors.New("please provide --dns-nameserver for the rfc2136 provider")
+		}
+		if (dnsTSIGKey == "") != (dnsTSIGSecret == "") {
+			return nil, errors.New("--tsig-key and --tsig-secret must be used together")
+		}
+		var secret string
+		if dnsTSIGSecret != "" {
+			value, err := readPassphrase(dnsTSIGSecret)
+			if err != nil {
+				return nil, err
+			}
+			secret = strings.TrimSpace(string(value))
+			if _, err := base64.StdEncoding.DecodeString(secret); err != nil {
+				return nil, fmt.Errorf("invalid TSIG secret: %v", err)
+			}
+		}
+		return &internal.RFC2136Provider{
+			Nameserver:         dnsNameserver,
+			Zone:               dnsZone,
+			TSIGKey:            dnsTSIGKey,
+			TSIGSecret:         secret,
+			TSIGAlgorithm:      dnsTSIGAlgorithm,
+			PropagationTimeout: dnsPropagationTimeout,
+		}, nil
+	case internal.DNSProviderExec:
+		if dnsExec == "" {
+			return nil, errors.New("please provide --dns-exec for the exec provider")
+		}
+		return &internal.ExecProvider{Command: dnsExec}, nil
+	}
+	return nil, fmt.Errorf("unsupported DNS provider: %s (expected %s)", dnsProvider, strings.Join(internal.DNSProviders, " or "))
+}
+
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/luisenrique-varelarodriguez/ssl-tool/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	acmeAccountKeyType string
+	acmeDeactivateYes  bool
+	revokeCertFile     string
+	revokeCertKey      string
+	revokeReason       string
+)
+
+// newACMEAccountCmd construye el comando "acme account" y sus subcomandos.
+func newACMEAccountCmd() *cobra.Command {
+	accountCmd := &cobra.Command{
+		Use:   "account",
+		Short: "Register and manage the ACME account",
+	}
+
+	registerCmd := &cobra.Command{
+		Use:   "register",
+		Short: "Register an account, creating its key if it does not exist",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if interactive {
+				acmeEmails = strings.Split(promptFor("Contact email", strings.Join(acmeEmails, ",")), ",")
+				acmeAccountKeyType = promptFor("Account key type ("+strings.Join(internal.KeyTypes, ", ")+")", acmeAccountKeyType)
+			}
+			return withACMEAccount(func(ctx context.Context, opts internal.ACMEAccountOptions) error {
+				opts.KeyType = acmeAccountKeyType
+				account, created, err := internal.RegisterACMEAccount(ctx, opts)
+				if err != nil {
+					return err
+				}
+				if created {
+					fmt.Println("Registered ACME account:")
+				} else {
+					fmt.Println("The key already has an ACME account:")
+				}
+				printACMEAccount(account)
+				return nil
+			})
+		},
+	}
+	registerCmd.Flags().StringVar(&acmeAccountKeyType, "key-type", internal.KeyTypeEC256, "Key type for a new account key: "+strings.Join(internal.KeyTypes, ", "))
+	addEABFlags(registerCmd)
+
+	showCmd := &cobra.Command{
+		Use:   "show",
+		Short: "Show the account of the account key",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withACMEAccount(func(ctx context.Context, opts internal.ACMEAccountOptions) error {
+				account, err := internal.GetACMEAccount(ctx, opts)
+				if err != nil {
+					return err
+				}
+				printACMEAccount(account)
+				return nil
+			})
+		},
+	}
+
+	updateContactCmd := &cobra.Command{
+		Use:   "update-contact",
+		Short: "Replace the contact emails of the account with --email",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if interactive {
+				acmeEmails = strings.Split(promptFor("Contact email", strings.Join(acmeEmails, ",")), ",")
+			}
+			if len(acmeEmails) == 0 {
+				return errors.New("please provide --email or use --interactive")
+			}
+			return withACMEAccount(func(ctx context.Context, opts internal.ACMEAccountOptions) error {
+				account, err := internal.UpdateACMEContact(ctx, opts)
+				if err != nil {
+					return err
+				}
+				fmt.Println("Updated ACME account:")
+				printACMEAccount(account)
+				return nil
+			})
+		},
+	}
+
+	keyRolloverCmd := &cobra.Command{
+		Use:   "key-rollover",
+		Short: "Replace the account key with a new one",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withACMEAccount(func(ctx context.Context, opts internal.ACMEAccountOptions) error {
+				opts.KeyType = acmeAccountKeyType
+				account, err := internal.RolloverACMEAccountKey(ctx, opts)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("Rolled over the account key (the previous key was kept in %s.old):\n", account.KeyPath)
+				printACMEAccount(account)
+				return nil
+			})
+		},
+	}
+	keyRolloverCmd.Flags().StringVar(&acmeAccountKeyType, "key-type", internal.KeyTypeEC256, "Type of the new account key: "+strings.Join(internal.KeyTypes, ", "))
+
+	deactivateCmd := &cobra.Command{
+		Use:   "deactivate",
+		Short: "Permanently deactivate the account",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if interactive && !acmeDeactivateYes {
+				acmeDeactivateYes = promptFor("The account cannot be reactivated. Deactivate it? (yes/no)", "no") == "yes"
+				if !acmeDeactivateYes {
+					return errors.New("deactivation cancelled")
+				}
+			}
+			if !acmeDeactivateYes {
+				return errors.New("deactivation cannot be undone; confirm with --yes or use --interactive")
+			}
+			return withACMEAccount(func(ctx context.Context, opts internal.ACMEAccountOptions) error {
+				if err := internal.DeactivateACMEAccount(ctx, opts); err != nil {
+					return err
+				}
+				fmt.Printf("Deactivated the ACME account of %s\n", opts.AccountKeyPath)
+				return nil
+			})
+		},
+	}
+	deactivateCmd.Flags().BoolVar(&acmeDeactivateYes, "yes", false, "Confirm the deactivation")
+
+	accountCmd.AddCommand(registerCmd, showCmd, updateContactCmd, keyRolloverCmd, deactivateCmd)
+	return accountCmd
+}
+
+// newACMERevokeCmd construye el comando "acme revoke".
+func newACMERevokeCmd() *cobra.Command {
+	var reasons []string
+	for reason := range internal.ACMERevocationReasons {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+
+	revokeCmd := &cobra.Command{
+		Use:   "revoke",
+		Short: "Revoke a certificate signing with the account key or with the certificate key",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if interactive {
+				revokeCertFile = promptFor("Path to certificate (.crt)", revokeCertFile)
+				revokeCertKey = promptFor("Path to the certificate key (empty to use the account key)", revokeCertKey)
+				revokeReason = promptFor("Reason ("+strings.Join(reasons, ", ")+")", revokeReason)
+			}
+			if revokeCertFile == "" {
+				return errors.New("please provide --cert or use --interactive")
+			}
+			return withACMEAccount(func(ctx context.Context, opts internal.ACMEAccountOptions) error {
+				cert, err := internal.RevokeACMECertificate(ctx, opts, revokeCertFile, revokeCertKey, revokeReason)
+				if err != nil {
+					return err
+				}
+				name := cert.Subject.String()
+				if len(cert.DNSNames) > 0 {
+					name = strings.Join(cert.DNSNames, ", ")
+				}
+				fmt.Printf("Revoked certificate for %s (serial %s, reason %s)\n", name, cert.SerialNumber.Text(16), revokeReason)
+				return nil
+			})
+		},
+	}
+	revokeCmd.Flags().StringVar(&revokeCertFile, "cert", "", "Certificate to revoke (the first one of the file)")
+	revokeCmd.Flags().StringVar(&revokeCertKey, "cert-key", "", "Sign with the certificate key instead of the account key")
+	revokeCmd.Flags().StringVar(&revokeReason, "reason", "unspecified", "Revocation reason: "+strings.Join(reasons, ", "))
+	return revokeCmd
+}
+
+// withACMEAccount ejecuta fn con las opciones de la cuenta y un contexto limitado por --timeout.
+func withACMEAccount(fn func(ctx context.Context, opts internal.ACMEAccountOptions) error) error {
+	opts, err := acmeAccountOptions()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), acmeTimeout)
+	defer cancel()
+	return fn(ctx, opts)
+}
+
+func printACMEAccount(account internal.ACMEAccount) {
+	fmt.Printf("- Account: %s\n", account.URL)
+	if account.Status != "" {
+		fmt.Printf("- Status: %s\n", account.Status)
+	}
+	if len(account.Contacts) > 0 {
+		fmt.Printf("- Contacts: %s\n", strings.Join(account.Contacts, ", "))
+	} else {
+		fmt.Println("- Contacts: none")
+	}
+	if account.OrdersURL != "" {
+		fmt.Printf("- Orders: %s\n", account.OrdersURL)
+	}
+	key := fmt.Sprintf("%s %d", account.Key.Algorithm, account.Key.Size)
+	if account.Key.Curve != "" {
+		key = fmt.Sprintf("%s %s", account.Key.Algorithm, account.Key.Curve)
+	}
+	fmt.Printf("- Key: %s (%s)\n", account.KeyPath, key)
+	fmt.Printf("- JWK Thumbprint: %s\n", account.Thumbprint)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/luisenrique-varelarodriguez/ssl-tool/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	chainIntermediates string
+	chainRoots         string
+	chainSystemRoots   bool
+	chainHostname      string
+	chainAt            string
+	chainPurpose       string
+)
+
+// newVerifyChainCmd construye el comando "verify-chain".
+func newVerifyChainCmd() *cobra.Command {
+	verifyChainCmd := &cobra.Command{
+		Use:   "verify-chain",
+		Short: "Build and validate the certificate chain up to a trusted root",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var certs []*x509.Certificate
+			if remoteAddress != "" {
+				chain, err := fetchRemoteChain()
+				if err != nil {
+					return err
+				}
+				certs = chain
+				// Por defecto se comprueba el nombre con el que se ha contactado al servidor
+				if chainHostname == "" {
+					chainHostname = remoteSNI
+					if chainHostname == "" {
+						chainHostname, _ = internal.RemoteHost(remoteAddress, "")
+					}
+				}
+			} else {
+				if interactive {
+					certFile = promptFor("Path to certificate (.crt)", certFile)
+					chainIntermediates = promptFor("Path to intermediates bundle (optional)", chainIntermediates)
+					chainRoots = promptFor("Path to roots bundle (empty for system roots)", chainRoots)
+					chainHostname = promptFor("Hostname (optional)", chainHostname)
+				} else if certFile == "" {
+					return errors.New("please provide --cert, --remote or use --interactive")
+				}
+
+				if !fileExists(certFile) {
+					return fmt.Errorf("certificate file does not exist: %s", certFile)
+				}
+
+				// El archivo puede ser un fullchain: los certificados tras la hoja se usan como intermedios
+				chain, err := internal.LoadCertificates(certFile)
+				if err != nil {
+					return err
+				}
+				certs = chain
+			}
+
+			if chainRoots != "" && chainSystemRoots {
+				return errors.New("--roots and --system-roots are mutually exclusive")
+			}
+
+			opts := internal.ChainOptions{
+				Intermediates: certs[1:],
+				SystemRoots:   chainSystemRoots,
+				Hostname:      chainHostname,
+				Purpose:       chainPurpose,
+			}
+
+			var err error
+			if chainIntermediates != "" {
+				intermediates, err := internal.LoadCertificates(chainIntermediates)
+				if err != nil {
+					return fmt.Errorf("error loading intermediates: %v", err)
+				}
+				opts.Intermediates = append(opts.Intermediates, intermediates...)
+			}
+			if chainRoots != "" {
+				opts.Roots, err = internal.LoadCertificates(chainRoots)
+				if err != nil {
+					return fmt.Errorf("error loading roots: %v", err)
+				}
+			}
+			if chainAt != "" {
+				opts.At, err = time.Parse(time.RFC3339, chainAt)
+				if err != nil {
+					return fmt.Errorf("invalid --at time (expected RFC3339): %v", err)
+				}
+			}
+
+			// A partir de aquí los errores son de verificación, no de uso
+			cmd.SilenceUsage = true
+			chains, err := internal.VerifyChain(certs[0], opts)
+			if err != nil {
+				return fmt.Errorf("chain verification failed: %v", err)
+			}
+
+			fmt.Printf("Chain is valid: %d path(s) found\n", len(chains))
+			for i, chain := range chains {
+				fmt.Printf("Path %d:\n", i+1)
+				for depth, cert := range chain {
+					fmt.Printf("  %d: %s (valid until %s)\n", depth, cert.Subject.String(), cert.NotAfter.UTC().Format(time.RFC3339))
+				}
+			}
+			return nil
+		},
+	}
+	verifyChainCmd.Flags().StringVar(&certFile, "cert", "", "Path to the leaf certificate (may include the chain)")
+	verifyChainCmd.Flags().StringVar(&chainIntermediates, "intermediates", "", "Path to a PEM bundle of intermediate certificates")
+	verifyChainCmd.Flags().StringVar(&chainRoots, "roots", "", "Path to a PEM bundle of trusted roots")
+	verifyChainCmd.Flags().BoolVar(&chainSystemRoots, "system-roots", false, "Trust the system roots (default when --roots is not given)")
+	verifyChainCmd.Flags().StringVar(&chainHostname, "hostname", "", "Hostname the leaf certificate must be valid for")
+	verifyChainCmd.Flags().StringVar(&chainAt, "at", "", "Verify at this time instead of now (RFC3339)")
+	verifyChainCmd.Flags().StringVar(&chainPurpose, "purpose", internal.PurposeServer, "Required extended key usage: server, client or any")
+	addRemoteFlags(verifyChainCmd)
+	return verifyChainCmd
+}
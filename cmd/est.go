@@ -0,0 +1,218 @@
+package main
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/luisenrique-varelarodriguez/ssl-tool/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	estServer     string
+	estLabel      string
+	estRoots      string
+	estUsername   string
+	estPassword   string
+	estClientCert string
+	estClientKey  string
+	estTimeout    time.Duration
+	estCSRFile    string
+	estCertFile   string
+	estKeyFile    string
+	estOut        string
+)
+
+// newESTCmd construye el comando "est" y sus subcomandos.
+func newESTCmd() *cobra.Command {
+	estCmd := &cobra.Command{
+		Use:   "est",
+		Short: "Enroll certificates with an EST (RFC 7030) server",
+	}
+	estCmd.PersistentFlags().StringVar(&estServer, "server", "", "EST server URL (https://host[:port]; /.well-known/est is added)")
+	estCmd.PersistentFlags().StringVar(&estLabel, "label", "", "CA label on the EST server (optional)")
+	estCmd.PersistentFlags().StringVar(&estRoots, "ca-roots", "", "PEM bundle to verify the EST server (default: system roots)")
+	estCmd.PersistentFlags().StringVar(&estUsername, "username", "", "User for HTTP basic authentication")
+	estCmd.PersistentFlags().StringVar(&estPassword, "password", "", "Password for HTTP basic authentication: pass:<value>, env:<VAR> or file:<path>")
+	estCmd.PersistentFlags().StringVar(&estClientCert, "client-cert", "", "Certificate for TLS client authentication")
+	estCmd.PersistentFlags().StringVar(&estClientKey, "client-key", "", "Key of --client-cert")
+	estCmd.PersistentFlags().DurationVar(&estTimeout, "timeout", internal.DefaultESTTimeout, "Timeout for each request")
+
+	cacertsCmd := &cobra.Command{
+		Use:   "cacerts",
+		Short: "Download the CA certificates published by the EST server",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts, err := estOptions()
+			if err != nil {
+				return err
+			}
+			certs, err := internal.ESTCACerts(opts)
+			if err != nil {
+				return err
+			}
+			data := internal.EncodeCertificatesPEM(certs)
+			if estOut == "" {
+				fmt.Print(string(data))
+				return nil
+			}
+			if err := os.WriteFile(estOut, data, 0644); err != nil {
+				return fmt.Errorf("error writing CA certificates: %v", err)
+			}
+			fmt.Printf("Saved %d CA certificate(s) to %s\n", len(certs), estOut)
+			return nil
+		},
+	}
+	cacertsCmd.Flags().StringVar(&estOut, "out", "", "File for the PEM certificates (default: standard output)")
+
+	enrollCmd := &cobra.Command{
+		Use:   "enroll",
+		Short: "Request a certificate for a new key and CSR (as generate-csr) or for an existing CSR",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if interactive && estServer == "" {
+				estServer = promptFor("EST server URL", estServer)
+			}
+			opts, err := estOptions()
+			if err != nil {
+				return err
+			}
+
+			csrPath := estCSRFile
+			if csrPath == "" {
+				if err := prepareCSRParams(); err != nil {
+					return err
+				}
+				if csrPath, err = generateCSRFiles(); err != nil {
+					return err
+				}
+			}
+			csr, err := readCSRDER(csrPath)
+			if err != nil {
+				return err
+			}
+			out := estOut
+			if out == "" {
+				out = strings.TrimSuffix(csrPath, ".csr") + ".crt"
+			}
+
+			certs, err := internal.ESTEnroll(opts, csr, false)
+			if err != nil {
+				return err
+			}
+			return saveEnrolled(out, certs)
+		},
+	}
+	enrollCmd.Flags().StringVar(&estCSRFile, "csr", "", "Existing CSR to send instead of generating a key and CSR")
+	enrollCmd.Flags().StringVar(&estOut, "out", "", "File for the certificate chain (default: the CSR path with .crt)")
+	enrollCmd.Flags().StringVar(&domain, "domain", "", "Domain name for the CSR")
+	enrollCmd.Flags().StringVar(&country, "country", "", "Country (2 letters)")
+	enrollCmd.Flags().StringVar(&locality, "locality", "", "Locality (City)")
+	enrollCmd.Flags().StringVar(&organization, "organization", "", "Organization")
+
+	reenrollCmd := &cobra.Command{
+		Use:   "reenroll",
+		Short: "Renew a certificate with the same subject, SANs and key",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if interactive {
+				estCertFile = promptFor("Path to current certificate", estCertFile)
+				estKeyFile = promptFor("Path to its private key", estKeyFile)
+			}
+			if estCertFile == "" || estKeyFile == "" {
+				return errors.New("please provide --cert and --key or use --interactive")
+			}
+			// Por defecto el servidor autentica la renovación con el certificado actual
+			if estClientCert == "" && estClientKey == "" {
+				estClientCert, estClientKey = estCertFile, estKeyFile
+			}
+			opts, err := estOptions()
+			if err != nil {
+				return err
+			}
+
+			certs, err := internal.LoadCertificates(estCertFile)
+			if err != nil {
+				return err
+			}
+			if len(certs) == 0 {
+				return fmt.Errorf("no certificates found in %s", estCertFile)
+			}
+			key, err := internal.LoadPrivateKey(estKeyFile, nil)
+			if err != nil {
+				return err
+			}
+			csr, err := internal.ESTReenrollCSR(certs[0], key)
+			if err != nil {
+				return err
+			}
+			issued, err := internal.ESTEnroll(opts, csr, true)
+			if err != nil {
+				return err
+			}
+			out := estOut
+			if out == "" {
+				out = estCertFile
+			}
+			return saveEnrolled(out, issued)
+		},
+	}
+	reenrollCmd.Flags().StringVar(&estCertFile, "cert", "", "Current certificate")
+	reenrollCmd.Flags().StringVar(&estKeyFile, "key", "", "Private key of the current certificate (reused for the new one)")
+	reenrollCmd.Flags().StringVar(&estOut, "out", "", "File for the renewed chain (default: overwrite --cert)")
+
+	estCmd.AddCommand(cacertsCmd, enrollCmd, reenrollCmd)
+	return estCmd
+}
+
+// estOptions reúne las opciones de conexión y autenticación de los subcomandos.
+func estOptions() (internal.ESTOptions, error) {
+	if estServer == "" {
+		return internal.ESTOptions{}, errors.New("please provide --server or use --interactive")
+	}
+	password, err := readPassphrase(estPassword)
+	if err != nil {
+		return internal.ESTOptions{}, err
+	}
+	if estUsername != "" && len(password) == 0 {
+		return internal.ESTOptions{}, errors.New("--username requires --password")
+	}
+	return internal.ESTOptions{
+		ServerURL:  estServer,
+		Label:      estLabel,
+		Roots:      estRoots,
+		Username:   estUsername,
+		Password:   string(password),
+		ClientCert: estClientCert,
+		ClientKey:  estClientKey,
+		Timeout:    estTimeout,
+	}, nil
+}
+
+// readCSRDER lee un CSR en PEM o DER.
+func readCSRDER(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading CSR: %v", err)
+	}
+	if block, _ := pem.Decode(data); block != nil {
+		return block.Bytes, nil
+	}
+	return data, nil
+}
+
+// saveEnrolled guarda la cadena emitida, informa de la validez y la registra en el inventario.
+func saveEnrolled(path string, certs []*x509.Certificate) error {
+	if err := os.WriteFile(path, internal.EncodeCertificatesPEM(certs), 0644); err != nil {
+		return fmt.Errorf("error writing certificate: %v", err)
+	}
+	fmt.Printf("Certificate enrolled for %s, valid until %s:\n- Certificate: %s\n",
+		certs[0].Subject, certs[0].NotAfter.UTC().Format(time.DateOnly), path)
+	registerInInventory(func(inv *internal.Inventory) error {
+		_, err := inv.AddFile(path, "")
+		return err
+	})
+	return nil
+}
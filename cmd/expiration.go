@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/luisenrique-varelarodriguez/ssl-tool/internal"
+	"github.com/luisenrique-varelarodriguez/ssl-tool/pkg/ssltool"
+	"github.com/spf13/cobra"
+)
+
+// Códigos de salida compatibles con los plugins de Nagios.
+const (
+	nagiosOK       = 0
+	nagiosWarning  = 1
+	nagiosCritical = 2
+	nagiosUnknown  = 3
+)
+
+var nagiosLabels = map[int]string{
+	nagiosOK:       "OK",
+	nagiosWarning:  "WARNING",
+	nagiosCritical: "CRITICAL",
+	nagiosUnknown:  "UNKNOWN",
+}
+
+var (
+	scanPath      string
+	scanRecursive bool
+	scanInclude   string
+	warnDays      int
+	critDays      int
+	expiryAt      string
+)
+
+// exitError indica a main que termine con un código de salida concreto sin imprimir nada más.
+type exitError struct {
+	code int
+}
+
+func (e exitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+// nagiosExit devuelve el error que termina el proceso con el código indicado (nil si es OK).
+func nagiosExit(cmd *cobra.Command, code int) error {
+	if code == nagiosOK {
+		return nil
+	}
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	return exitError{code: code}
+}
+
+// worseNagiosState compara dos estados: CRITICAL > UNKNOWN > WARNING > OK.
+func worseNagiosState(a, b int) int {
+	rank := map[int]int{nagiosOK: 0, nagiosWarning: 1, nagiosUnknown: 2, nagiosCritical: 3}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
+
+// expiryState clasifica un certificado según los umbrales --warn y --crit. Los certificados
+// caducados o todavía no válidos son siempre críticos.
+func expiryState(status internal.ExpiryStatus) int {
+	switch {
+	case !status.Usable():
+		return nagiosCritical
+	case status.Remaining <= daysDuration(critDays):
+		return nagiosCritical
+	case status.Remaining <= daysDuration(warnDays):
+		return nagiosWarning
+	default:
+		return nagiosOK
+	}
+}
+
+// expiryTime interpreta --at; sin él se evalúa en el momento actual.
+func expiryTime() (time.Time, error) {
+	if expiryAt == "" {
+		return time.Time{}, nil
+	}
+	at, err := time.Parse(time.RFC3339, expiryAt)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --at time (expected RFC3339): %v", err)
+	}
+	return at, nil
+}
+
+func daysDuration(n int) time.Duration {
+	return time.Duration(n) * 24 * time.Hour
+}
+
+// runExpirationScan revisa todos los certificados de --path y termina con un código de Nagios.
+func runExpirationScan(cmd *cobra.Command) error {
+	var include []string
+	for _, pattern := range strings.Split(scanInclude, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			include = append(include, pattern)
+		}
+	}
+
+	at, err := expiryTime()
+	if err != nil {
+		return err
+	}
+	query, err := parseListing()
+	if err != nil {
+		return err
+	}
+
+	results, err := internal.ScanCertificates(internal.ScanOptions{
+		Root:      scanPath,
+		Recursive: scanRecursive,
+		Include:   include,
+	})
+	if err != nil {
+		fmt.Printf("CERT UNKNOWN - %v\n", err)
+		return nagiosExit(cmd, nagiosUnknown)
+	}
+	internal.SortByExpiration(results)
+	registerInInventory(func(inv *internal.Inventory) error {
+		for _, r := range results {
+			if r.Err != nil {
+				continue
+			}
+			if _, _, err := inv.AddCertificate(r.Cert, r.Path, ""); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if len(results) == 0 {
+		fmt.Printf("CERT UNKNOWN - no certificates found in %s\n", scanPath)
+		return nagiosExit(cmd, nagiosUnknown)
+	}
+	if results, err = filterResults(query, results, at); err != nil {
+		return err
+	}
+	return reportExpiry(cmd, results, at)
+}
+
+// checkExpiryFile evalúa el certificado de --cert en el instante at.
+func checkExpiryFile(path string, at time.Time) (internal.ExpiryStatus, error) {
+	if !fileExists(path) {
+		return internal.ExpiryStatus{}, fmt.Errorf("certificate file does not exist: %s", path)
+	}
+	return ssltool.CheckExpiry(context.Background(), ssltool.ExpiryOptions{File: path, At: at, Warn: daysDuration(warnDays)})
+}
+
+// runExpirationRemote revisa la cadena presentada por --remote, empezando por la hoja.
+func runExpirationRemote(cmd *cobra.Command) error {
+	at, err := expiryTime()
+	if err != nil {
+		return err
+	}
+	query, err := parseListing()
+	if err != nil {
+		return err
+	}
+
+	chain, err := fetchRemoteChain()
+	if err != nil {
+		fmt.Printf("CERT UNKNOWN - %v\n", err)
+		return nagiosExit(cmd, nagiosUnknown)
+	}
+
+	results := make([]internal.ScanResult, len(chain))
+	for i, cert := range chain {
+		results[i] = internal.ScanResult{Path: remoteAddress, Index: i, Cert: cert}
+	}
+	if results, err = filterResults(query, results, at); err != nil {
+		return err
+	}
+	return reportExpiry(cmd, results, at)
+}
+
+// reportExpiry imprime el resumen y la tabla de certificados y termina con el peor estado.
+func reportExpiry(cmd *cobra.Command, results []internal.ScanResult, at time.Time) error {
+	state := nagiosOK
+	counts := map[int]int{}
+	for _, r := range results {
+		s := nagiosUnknown
+		if r.Err == nil {
+			s = expiryState(internal.CheckExpiry(r.Cert, at, daysDuration(warnDays)))
+		}
+		counts[s]++
+		state = worseNagiosState(state, s)
+	}
+
+	if outputFormat == outputJSON {
+		if err := printExpiryJSON(results, at, state); err != nil {
+			return err
+		}
+		if notifyEnabled {
+			notifyExpiry(results, at)
+		}
+		return nagiosExit(cmd, state)
+	}
+
+	fmt.Printf("CERT %s - %d certificate(s): %d critical, %d warning, %d unknown, %d ok\n",
+		nagiosLabels[state], len(results), counts[nagiosCritical], counts[nagiosWarning], counts[nagiosUnknown], counts[nagiosOK])
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, r := range results {
+		if r.Err != nil {
+			fmt.Fprintf(w, "%s\terror\t-\t%s\t%v\n", nagiosLabels[nagiosUnknown], r.Path, r.Err)
+			continue
+		}
+		status := internal.CheckExpiry(r.Cert, at, daysDuration(warnDays))
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", nagiosLabels[expiryState(status)], status.State, remainingLabel(status), scanLocation(r), r.Cert.Subject.String())
+	}
+	w.Flush()
+
+	if notifyEnabled {
+		notifyExpiry(results, at)
+	}
+	return nagiosExit(cmd, state)
+}
+
+// printExpiryJSON muestra el resultado de check-expiration con el modelo de campos de --where y el
+// estado de cada certificado.
+func printExpiryJSON(results []internal.ScanResult, at time.Time, state int) error {
+	certificates := []internal.QueryFields{}
+	for _, r := range results {
+		if r.Err != nil {
+			certificates = append(certificates, internal.QueryFields{
+				"location": scanLocation(r),
+				"state":    nagiosLabels[nagiosUnknown],
+				"error":    r.Err.Error(),
+			})
+			continue
+		}
+		status := internal.CheckExpiry(r.Cert, at, daysDuration(warnDays))
+		fields := internal.CertificateFields(r.Cert, r.Path, at)
+		fields["location"] = scanLocation(r)
+		fields["state"] = nagiosLabels[expiryState(status)]
+		fields["status"] = status.State
+		certificates = append(certificates, fields)
+	}
+	return printJSON(map[string]interface{}{
+		"state":        nagiosLabels[state],
+		"certificates": certificates,
+	})
+}
+
+// remainingLabel resume el tiempo restante para la tabla del escaneo.
+func remainingLabel(status internal.ExpiryStatus) string {
+	switch status.State {
+	case internal.ExpiryExpired:
+		return internal.HumanDuration(status.Remaining) + " ago"
+	case internal.ExpiryNotYetValid:
+		return "from " + status.NotBefore.UTC().Format(time.DateOnly)
+	default:
+		return internal.HumanDuration(status.Remaining)
+	}
+}
+
+// scanLocation identifica un certificado dentro de un archivo que puede contener varios.
+func scanLocation(r internal.ScanResult) string {
+	if r.Index == 0 {
+		return r.Path
+	}
+	return fmt.Sprintf("%s#%d", r.Path, r.Index)
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/luisenrique-varelarodriguez/ssl-tool/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	inventoryPath  string
+	inventoryOwner string
+)
+
+// withInventory abre el inventario de --inventory para un subcomando de inventory.
+func withInventory(fn func(inv *internal.Inventory) error) error {
+	inv, err := internal.OpenInventory(inventoryPath)
+	if err != nil {
+		return err
+	}
+	defer inv.Close()
+	return fn(inv)
+}
+
+// registerInInventory registra automáticamente lo que ven otros comandos. Solo lo hace si la base de
+// datos ya existe (se crea con "inventory add" o "inventory scan") y un fallo no interrumpe el comando.
+func registerInInventory(fn func(inv *internal.Inventory) error) {
+	if !fileExists(inventoryPath) {
+		return
+	}
+	if err := withInventory(fn); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not update inventory: %v\n", err)
+	}
+}
+
+// registerChain registra la cadena presentada por un endpoint remoto.
+func registerChain(address string, chain []*x509.Certificate) {
+	registerInInventory(func(inv *internal.Inventory) error {
+		for _, cert := range chain {
+			if _, _, err := inv.AddCertificate(cert, "tls://"+address, ""); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// newInventoryCmd construye el comando "inventory" y sus subcomandos.
+func newInventoryCmd() *cobra.Command {
+	inventoryCmd := &cobra.Command{
+		Use:   "inventory",
+		Short: "Keep a database of the certificates ssl-tool has seen",
+	}
+
+	addCmd := &cobra.Command{
+		Use:   "add file...",
+		Short: "Register the certificates or CSR of one or more PEM files",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if interactive && len(args) == 0 {
+				args = []string{promptFor("Path to certificate or CSR file", "")}
+			}
+			if len(args) == 0 {
+				return errors.New("please provide at least one file or use --interactive")
+			}
+			for _, path := range args {
+				if !fileExists(path) {
+					return fmt.Errorf("file does not exist: %s", path)
+				}
+			}
+			return withInventory(func(inv *internal.Inventory) error {
+				for _, path := range args {
+					entries, err := inv.AddFile(path, inventoryOwner)
+					if err != nil {
+						return err
+					}
+					for _, entry := range entries {
+						fmt.Printf("Registered %s %s (%s)\n", shortFingerprint(entry.Fingerprint), entryName(entry), path)
+					}
+				}
+				return nil
+			})
+		},
+	}
+	addCmd.Flags().StringVar(&inventoryOwner, "owner", "", "Owner annotation for the registered entries")
+
+	scanCmd := &cobra.Command{
+		Use:   "scan dir",
+		Short: "Register every certificate found in a directory",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 1 {
+				scanPath = args[0]
+			}
+			if interactive {
+				scanPath = promptFor("Directory to scan", scanPath)
+			} else if scanPath == "" {
+				return errors.New("please provide a directory or use --interactive")
+			}
+
+			var include []string
+			for _, pattern := range strings.Split(scanInclude, ",") {
+				if pattern = strings.TrimSpace(pattern); pattern != "" {
+					include = append(include, pattern)
+				}
+			}
+			results, err := internal.ScanCertificates(internal.ScanOptions{Root: scanPath, Recursive: scanRecursive, Include: include})
+			if err != nil {
+				return err
+			}
+			return withInventory(func(inv *internal.Inventory) error {
+				added, updated := 0, 0
+				for _, r := range results {
+					if r.Err != nil {
+						fmt.Fprintf(os.Stderr, "Skipping %s: %v\n", r.Path, r.Err)
+						continue
+					}
+					_, created, err := inv.AddCertificate(r.Cert, r.Path, inventoryOwner)
+					if err != nil {
+						return err
+					}
+					if created {
+						added++
+					} else {
+						updated++
+					}
+				}
+				fmt.Printf("Scanned %s: %d new, %d already known\n", scanPath, added, updated)
+				return nil
+			})
+		},
+	}
+	scanCmd.Flags().BoolVar(&scanRecursive, "recursive", false, "Scan subdirectories")
+	scanCmd.Flags().StringVar(&scanInclude, "include", strings.Join(internal.DefaultScanInclude, ","), "Comma-separated file patterns to scan")
+	scanCmd.Flags().StringVar(&inventoryOwner, "owner", "", "Owner annotation for the registered entries")
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the inventory, soonest expiry first",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			query, err := parseListing()
+			if err != nil {
+				return err
+			}
+			return withInventory(func(inv *internal.Inventory) error {
+				entries, err := inv.List()
+				if err != nil {
+					return err
+				}
+				entries, err = filterEntries(query, entries)
+				if err != nil {
+					return err
+				}
+				if outputFormat == outputJSON {
+					fields := []internal.QueryFields{}
+					for _, entry := range entries {
+						fields = append(fields, internal.InventoryFields(entry, time.Time{}))
+					}
+					return printJSON(fields)
+				}
+				if len(entries) == 0 {
+					if query != nil {
+						fmt.Println("No inventory entries match the query")
+					} else {
+						fmt.Println("Inventory is empty")
+					}
+					return nil
+				}
+				w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+				fmt.Fprintln(w, "FINGERPRINT\tKIND\tEXPIRES\tNAME\tOWNER\tSOURCES")
+				for _, entry := range entries {
+					expires := "-"
+					if !entry.NotAfter.IsZero() {
+						expires = entry.NotAfter.UTC().Format(time.DateOnly)
+					}
+					fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\n", shortFingerprint(entry.Fingerprint), entry.Kind, expires, entryName(entry), orDash(entry.Owner), len(entry.Sources))
+				}
+				return w.Flush()
+			})
+		},
+	}
+
+	addQueryFlags(listCmd)
+
+	showCmd := &cobra.Command{
+		Use:   "show fingerprint",
+		Short: "Show every recorded detail of an entry (a unique fingerprint prefix is enough)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withInventory(func(inv *internal.Inventory) error {
+				entry, err := inv.Get(args[0])
+				if err != nil {
+					return err
+				}
+				printInventoryEntry(entry)
+				return nil
+			})
+		},
+	}
+
+	removeCmd := &cobra.Command{
+		Use:   "remove fingerprint",
+		Short: "Remove an entry from the inventory",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withInventory(func(inv *internal.Inventory) error {
+				entry, err := inv.Remove(args[0])
+				if err != nil {
+					return err
+				}
+				fmt.Printf("Removed %s %s\n", shortFingerprint(entry.Fingerprint), entryName(entry))
+				return nil
+			})
+		},
+	}
+
+	inventoryCmd.AddCommand(addCmd)
+	inventoryCmd.AddCommand(scanCmd)
+	inventoryCmd.AddCommand(listCmd)
+	inventoryCmd.AddCommand(showCmd)
+	inventoryCmd.AddCommand(removeCmd)
+	return inventoryCmd
+}
+
+// filterEntries deja solo los registros del inventario que cumplen la consulta.
+func filterEntries(query *internal.Query, entries []internal.InventoryEntry) ([]internal.InventoryEntry, error) {
+	if query == nil {
+		return entries, nil
+	}
+	now := time.Now()
+	var matched []internal.InventoryEntry
+	for _, entry := range entries {
+		ok, err := query.Match(internal.InventoryFields(entry, now))
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matched = append(matched, entry)
+		}
+	}
+	return matched, nil
+}
+
+// printInventoryEntry muestra todos los campos de un registro.
+func printInventoryEntry(entry internal.InventoryEntry) {
+	fmt.Printf("Fingerprint (SHA256): %s\n", entry.Fingerprint)
+	fmt.Printf("Kind: %s\n", entry.Kind)
+	fmt.Printf("Subject: %s\n", entry.Subject)
+	if len(entry.SANs) > 0 {
+		fmt.Printf("SANs: %s\n", strings.Join(entry.SANs, ", "))
+	}
+	if entry.Kind == internal.InventoryCertificate {
+		fmt.Printf("Issuer: %s\n", entry.Issuer)
+		fmt.Printf("Serial: %s\n", entry.Serial)
+		fmt.Printf("Valid from: %s\n", entry.NotBefore.UTC().Format(time.RFC3339))
+		fmt.Printf("Valid until: %s\n", entry.NotAfter.UTC().Format(time.RFC3339))
+	}
+	fmt.Printf("Owner: %s\n", orDash(entry.Owner))
+	fmt.Printf("First seen: %s\n", entry.FirstSeen.Format(time.RFC3339))
+	fmt.Printf("Last seen: %s\n", entry.LastSeen.Format(time.RFC3339))
+	fmt.Println("Sources:")
+	for _, source := range entry.Sources {
+		fmt.Printf("- %s\n", source)
+	}
+}
+
+func shortFingerprint(fingerprint string) string {
+	if len(fingerprint) > 16 {
+		return fingerprint[:16]
+	}
+	return fingerprint
+}
+
+func entryName(entry internal.InventoryEntry) string {
+	if entry.CommonName != "" {
+		return entry.CommonName
+	}
+	return entry.Subject
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
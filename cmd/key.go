@@ -0,0 +1,183 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/luisenrique-varelarodriguez/ssl-tool/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	keyIn      string
+	keyOut     string
+	keyTo      string
+	keyFormat  string
+	keyPassIn  string
+	keyPassOut string
+)
+
+// newKeyCmd construye el grupo de comandos "key".
+func newKeyCmd() *cobra.Command {
+	keyCmd := &cobra.Command{
+		Use:   "key",
+		Short: "Convert private keys and extract public keys",
+	}
+
+	// Comando: key convert
+	convertCmd := &cobra.Command{
+		Use:   "convert",
+		Short: "Convert a private key between PKCS#1, PKCS#8 and SEC1",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if interactive {
+				keyIn = promptFor("Path to private key", keyIn)
+				keyOut = promptFor("Output path", keyOut)
+				keyTo = promptFor("Output format (pkcs1, pkcs8, sec1)", keyTo)
+			} else if keyIn == "" || keyOut == "" {
+				return errors.New("missing required parameters: --in, --out. Provide flags or use --interactive")
+			}
+
+			if !fileExists(keyIn) {
+				return fmt.Errorf("key file does not exist: %s", keyIn)
+			}
+
+			passin, err := readPassphrase(keyPassIn)
+			if err != nil {
+				return err
+			}
+			passout, err := readPassphrase(keyPassOut)
+			if err != nil {
+				return err
+			}
+
+			if err := internal.ConvertPrivateKey(keyIn, keyOut, keyTo, passin, passout); err != nil {
+				return err
+			}
+			fmt.Printf("Key written to %s (%s)\n", keyOut, keyTo)
+			return nil
+		},
+	}
+	convertCmd.Flags().StringVar(&keyIn, "in", "", "Path to the private key")
+	convertCmd.Flags().StringVar(&keyOut, "out", "", "Path to write the converted key")
+	convertCmd.Flags().StringVar(&keyTo, "to", internal.KeyFormatPKCS8, "Output format: pkcs1, pkcs8 or sec1")
+	convertCmd.Flags().StringVar(&keyPassIn, "passin", "", "Passphrase of the input key (pass:, env: or file: prefix)")
+	convertCmd.Flags().StringVar(&keyPassOut, "passout", "", "Encrypt the output key with this passphrase (PKCS#8 only)")
+
+	// Comando: key pub
+	pubCmd := &cobra.Command{
+		Use:   "pub",
+		Short: "Extract the public key as PEM, OpenSSH authorized_keys or JWK",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if interactive {
+				keyIn = promptFor("Path to key, CSR or certificate", keyIn)
+			} else if keyIn == "" {
+				return errors.New("please provide --in or use --interactive")
+			}
+
+			if !fileExists(keyIn) {
+				return fmt.Errorf("file does not exist: %s", keyIn)
+			}
+
+			passin, err := readPassphrase(keyPassIn)
+			if err != nil {
+				return err
+			}
+			pub, err := internal.LoadPublicKey(keyIn, passin)
+			if err != nil {
+				return err
+			}
+			data, err := internal.EncodePublicKey(pub, keyFormat)
+			if err != nil {
+				return err
+			}
+
+			if keyOut == "" {
+				fmt.Print(string(data))
+				return nil
+			}
+			if err := os.WriteFile(keyOut, data, 0644); err != nil {
+				return fmt.Errorf("error writing public key: %v", err)
+			}
+			fmt.Printf("Public key written to %s\n", keyOut)
+			return nil
+		},
+	}
+	pubCmd.Flags().StringVar(&keyIn, "in", "", "Path to the private key, public key, CSR or certificate")
+	pubCmd.Flags().StringVar(&keyOut, "out", "", "Path to write the public key (default: stdout)")
+	pubCmd.Flags().StringVar(&keyFormat, "format", internal.PublicKeyFormatPEM, "Output format: pem, openssh or jwk")
+	pubCmd.Flags().StringVar(&keyPassIn, "passin", "", "Passphrase of the input key (pass:, env: or file: prefix)")
+
+	// Comando: key info
+	infoCmd := &cobra.Command{
+		Use:   "info",
+		Short: "Show the algorithm, size and SPKI fingerprint of a key",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if interactive {
+				keyIn = promptFor("Path to key, CSR or certificate", keyIn)
+			} else if keyIn == "" {
+				return errors.New("please provide --in or use --interactive")
+			}
+
+			if !fileExists(keyIn) {
+				return fmt.Errorf("file does not exist: %s", keyIn)
+			}
+
+			passin, err := readPassphrase(keyPassIn)
+			if err != nil {
+				return err
+			}
+			pub, err := internal.LoadPublicKey(keyIn, passin)
+			if err != nil {
+				return err
+			}
+			info, err := internal.DescribePublicKey(pub)
+			if err != nil {
+				return err
+			}
+
+			fmt.Println("Key Info:")
+			fmt.Printf("- Algorithm: %s\n", info.Algorithm)
+			fmt.Printf("- Size: %d bits\n", info.Size)
+			if info.Curve != "" {
+				fmt.Printf("- Curve: %s\n", info.Curve)
+			}
+			fmt.Printf("- SPKI SHA256: %s\n", info.SPKIFingerprint)
+			return nil
+		},
+	}
+	infoCmd.Flags().StringVar(&keyIn, "in", "", "Path to the private key, public key, CSR or certificate")
+	infoCmd.Flags().StringVar(&keyPassIn, "passin", "", "Passphrase of the input key (pass:, env: or file: prefix)")
+
+	keyCmd.AddCommand(convertCmd)
+	keyCmd.AddCommand(pubCmd)
+	keyCmd.AddCommand(infoCmd)
+	return keyCmd
+}
+
+// readPassphrase interpreta una passphrase al estilo de OpenSSL: "pass:valor", "env:VAR",
+// "file:ruta" o el valor literal si no lleva prefijo.
+func readPassphrase(spec string) ([]byte, error) {
+	switch {
+	case spec == "":
+		return nil, nil
+	case strings.HasPrefix(spec, "pass:"):
+		return []byte(strings.TrimPrefix(spec, "pass:")), nil
+	case strings.HasPrefix(spec, "env:"):
+		name := strings.TrimPrefix(spec, "env:")
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return nil, fmt.Errorf("environment variable %s is not set", name)
+		}
+		return []byte(value), nil
+	case strings.HasPrefix(spec, "file:"):
+		data, err := os.ReadFile(strings.TrimPrefix(spec, "file:"))
+		if err != nil {
+			return nil, fmt.Errorf("error reading passphrase file: %v", err)
+		}
+		return []byte(strings.TrimRight(string(data), "\r\n")), nil
+	default:
+		return []byte(spec), nil
+	}
+}
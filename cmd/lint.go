@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/luisenrique-varelarodriguez/ssl-tool/internal"
+	"github.com/spf13/cobra"
+)
+
+var lintFailOn string
+
+// newLintCmd construye el comando "lint".
+func newLintCmd() *cobra.Command {
+	lintCmd := &cobra.Command{
+		Use:   "lint [file...]",
+		Short: "Check certificates and CSRs against CA/Browser Forum and RFC 5280 rules",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if interactive && len(args) == 0 && remoteAddress == "" {
+				args = []string{promptFor("Path to certificate or CSR", "")}
+			}
+			if len(args) == 0 && remoteAddress == "" {
+				return errors.New("please provide at least one certificate or CSR file, --remote or use --interactive")
+			}
+			if outputFormat != outputText && outputFormat != outputJSON {
+				return fmt.Errorf("unsupported output format: %s (expected text or json)", outputFormat)
+			}
+			if !internal.LintSeverityAtLeast(lintFailOn, internal.LintNotice) {
+				return fmt.Errorf("unsupported severity: %s (expected %s)", lintFailOn, strings.Join(internal.LintSeverities, ", "))
+			}
+
+			var results []internal.LintResult
+			for _, path := range args {
+				fileResults, err := internal.LintFile(path)
+				if err != nil {
+					return fmt.Errorf("%s: %v", path, err)
+				}
+				results = append(results, fileResults...)
+			}
+			if remoteAddress != "" {
+				chain, err := fetchRemoteChain()
+				if err != nil {
+					return err
+				}
+				for i, cert := range chain {
+					results = append(results, internal.LintResult{Source: remoteAddress, Index: i, Kind: internal.InventoryCertificate,
+						Subject: cert.Subject.String(), Findings: internal.LintCertificate(cert)})
+				}
+			}
+
+			counts := internal.LintCount(results)
+			if outputFormat == outputJSON {
+				if err := printJSON(map[string]interface{}{"results": results, "counts": counts}); err != nil {
+					return err
+				}
+			} else {
+				printLintResults(results, counts)
+			}
+
+			failed := 0
+			for _, r := range results {
+				for _, f := range r.Findings {
+					if internal.LintSeverityAtLeast(f.Severity, lintFailOn) {
+						failed++
+						break
+					}
+				}
+			}
+			if failed > 0 {
+				cmd.SilenceUsage = true
+				return fmt.Errorf("%d of %d object(s) have findings of severity %s or higher", failed, len(results), lintFailOn)
+			}
+			return nil
+		},
+	}
+	lintCmd.Flags().StringVar(&outputFormat, "format", outputText, "Output format: text or json")
+	lintCmd.Flags().StringVar(&lintFailOn, "fail-on", internal.LintError, "Exit with an error if any finding has this severity or higher: "+strings.Join(internal.LintSeverities, ", "))
+	addRemoteFlags(lintCmd)
+	return lintCmd
+}
+
+// printLintResults muestra los hallazgos de cada objeto y el total por gravedad.
+func printLintResults(results []internal.LintResult, counts map[string]int) {
+	for _, r := range results {
+		fmt.Printf("%s [%d] %s %s\n", r.Source, r.Index, r.Kind, orDash(r.Subject))
+		if len(r.Findings) == 0 {
+			fmt.Println("  no findings")
+			continue
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, f := range r.Findings {
+			fmt.Fprintf(w, "  %s\t%s\t%s\n", strings.ToUpper(f.Severity), f.Rule, f.Message)
+		}
+		w.Flush()
+	}
+	fmt.Printf("%d error(s), %d warning(s), %d notice(s)\n", counts[internal.LintError], counts[internal.LintWarn], counts[internal.LintNotice])
+}
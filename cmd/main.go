@@ -1,6 +1,7 @@
 package main
 
 import (
+    "context"
     "errors"
     "fmt"
     "os"
@@ -8,6 +9,7 @@ import (
 
     "github.com/spf13/cobra"
     "github.com/luisenrique-varelarodriguez/ssl-tool/internal"
+    "github.com/luisenrique-varelarodriguez/ssl-tool/pkg/ssltool"
 )
 
 var (
@@ -20,8 +22,13 @@ var (
     country      string
     locality     string
     organization string
+    csrKeyType   = internal.KeyTypeRSA2048
     configPath   string
+    profile      string
     interactive  bool
+    fingerprintAlgo   string
+    fingerprintFormat string
+    fingerprintSPKI   bool
     config       internal.Config
 )
 
@@ -37,6 +44,13 @@ func main() {
                     return fmt.Errorf("error loading config: %v", err)
                 }
                 config = cfg
+                // extract-info puede crear el perfil, así que no se exige que exista
+                if cmd.Name() != "extract-info" {
+                    config, err = cfg.WithProfile(profile)
+                    if err != nil {
+                        return err
+                    }
+                }
             }
             return nil
         },
@@ -50,6 +64,8 @@ func main() {
     rootCmd.PersistentFlags().BoolVarP(&interactive, "interactive", "i", false, "Enable interactive mode")
     configPath = "ssl-tool-config.yaml"
     rootCmd.PersistentFlags().StringVar(&configPath, "config", "ssl-tool-config.yaml", "Path to the configuration file")
+    rootCmd.PersistentFlags().StringVar(&inventoryPath, "inventory", internal.DefaultInventoryPath, "Inventory database; other commands register what they see only if it exists")
+    rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "Named profile of the configuration file to use")
 
     // Comando: generate-config
     generateConfigCmd := &cobra.Command{
@@ -66,56 +82,32 @@ func main() {
         Use:   "generate-csr",
         Short: "Generate a new CSR and private key",
         RunE: func(cmd *cobra.Command, args []string) error {
-            // Usar valores predeterminados de la configuración si no se proporcionan flags
-            if domain == "" && config.DefaultDomain != "" {
-                domain = config.DefaultDomain
-            }
-            if country == "" && config.DefaultCountry != "" {
-                country = config.DefaultCountry
-            }
-            if locality == "" && config.DefaultLocality != "" {
-                locality = config.DefaultLocality
-            }
-            if organization == "" && config.DefaultOrganization != "" {
-                organization = config.DefaultOrganization
-            }
-
-            requiredParams := []string{"domain", "country", "locality", "organization"}
-
-            if interactive {
-                // En modo interactivo, preguntar por todos los datos
-                domain = promptFor("Domain", domain)
-                country = promptFor("Country (2 letters)", country)
-                locality = promptFor("Locality (City)", locality)
-                organization = promptFor("Organization", organization)
-            } else {
-                // Validar que todos los parámetros requeridos estén presentes
-                for _, p := range requiredParams {
-                    val := getParamValue(p)
-                    if val == "" {
-                        return fmt.Errorf("missing required parameter: %s. Provide flags or use --interactive", p)
-                    }
-                }
-            }
-
-            // Validar parámetros antes de generar el CSR
-            if err := internal.ValidateCSRParams(domain, country, locality, organization); err != nil {
+            if err := prepareCSRParams(); err != nil {
                 return err
             }
 
             // Generar el CSR
-            return internal.GenerateCSR(domain, country, locality, organization)
+            csrPath, err := generateCSRFiles()
+            if err != nil {
+                return err
+            }
+            registerInInventory(func(inv *internal.Inventory) error {
+                _, err := inv.AddFile(csrPath, "")
+                return err
+            })
+            return nil
         },
     }
     generateCSRCmd.Flags().StringVar(&domain, "domain", "", "Domain name for the CSR")
     generateCSRCmd.Flags().StringVar(&country, "country", "", "Country (2 letters)")
     generateCSRCmd.Flags().StringVar(&locality, "locality", "", "Locality (City)")
     generateCSRCmd.Flags().StringVar(&organization, "organization", "", "Organization")
+    generateCSRCmd.Flags().StringVar(&csrKeyType, "key-type", internal.KeyTypeRSA2048, "Key type: "+strings.Join(internal.KeyTypes, ", "))
 
     // Comando: extract-info
     extractInfoCmd := &cobra.Command{
         Use:   "extract-info",
-        Short: "Extract information from a CRT or CSR and save it to ssl-tool-config.yaml",
+        Short: "Extract information from a CRT or CSR and merge it into the configuration file",
         RunE: func(cmd *cobra.Command, args []string) error {
             if interactive {
                 filePath = promptFor("Path to CRT or CSR file", filePath)
@@ -127,9 +119,20 @@ func main() {
                 return fmt.Errorf("file does not exist: %s", filePath)
             }
 
-            // Utiliza ssl-tool-config.yaml como destino
-            outputPath := "ssl-tool-config.yaml"
-            return internal.ExtractInfo(filePath, outputPath)
+            info, err := ssltool.ExtractInfo(context.Background(), filePath)
+            if err != nil {
+                return err
+            }
+            // Combina la información con el archivo indicado por --config (y --profile, si se da)
+            if err := ssltool.SaveInfo(context.Background(), info, ssltool.ConfigOptions{Path: configPath, Profile: profile}); err != nil {
+                return err
+            }
+            if profile != "" {
+                fmt.Printf("Information saved to %s (profile %s)\n", configPath, profile)
+            } else {
+                fmt.Printf("Information saved to %s\n", configPath)
+            }
+            return nil
         },
     }
     extractInfoCmd.Flags().StringVar(&filePath, "file", "", "Path to the CRT or CSR file")
@@ -137,13 +140,31 @@ func main() {
     // Comando: check-expiration
     checkExpirationCmd := &cobra.Command{
         Use:   "check-expiration",
-        Short: "Check how many days until a certificate expires",
+        Short: "Check how many days until a certificate, or every certificate in a directory, expires",
         RunE: func(cmd *cobra.Command, args []string) error {
+            // Los avisos son reales y actualizan el estado de deduplicación, así que no se envían
+            // para un instante hipotético
+            if notifyEnabled && expiryAt != "" {
+                return errors.New("--notify cannot be used with --at: alerts are only sent for the current time")
+            }
+
+            // Con --path o --remote se revisan varios certificados y se devuelve un código de Nagios
+            if scanPath != "" {
+                return runExpirationScan(cmd)
+            }
+            if remoteAddress != "" {
+                return runExpirationRemote(cmd)
+            }
+
+            if cmd.Flags().Changed("where") || cmd.Flags().Changed("format") {
+                return errors.New("--where and --format require --path or --remote")
+            }
+
             if interactive {
                 certFile = promptFor("Path to certificate (.crt)", certFile)
             } else {
                 if certFile == "" {
-                    return errors.New("please provide --cert or use --interactive")
+                    return errors.New("please provide --cert, --path or use --interactive")
                 }
             }
 
@@ -151,50 +172,123 @@ func main() {
                 return errors.New("certificate path cannot be empty")
             }
 
-            if !fileExists(certFile) {
-                return fmt.Errorf("certificate file does not exist: %s", certFile)
+            // Los umbrales solo cambian el código de salida si se indican explícitamente
+            thresholds := cmd.Flags().Changed("warn") || cmd.Flags().Changed("crit")
+            at, err := expiryTime()
+            if err != nil {
+                return err
             }
-
-            days, err := internal.DaysUntilExpiration(certFile)
+            status, err := checkExpiryFile(certFile, at)
             if err != nil {
+                // Como chequeo de Nagios, un certificado que no se puede leer es UNKNOWN
+                if thresholds {
+                    fmt.Printf("CERT UNKNOWN - %v\n", err)
+                    return nagiosExit(cmd, nagiosUnknown)
+                }
+                return err
+            }
+            fmt.Printf("Certificate %s.\n", status.Describe())
+            fmt.Printf("Status: %s\n", status.State)
+
+            registerInInventory(func(inv *internal.Inventory) error {
+                _, err := inv.AddFile(certFile, "")
                 return err
+            })
+            if notifyEnabled {
+                certs, err := internal.LoadCertificates(certFile)
+                if err != nil {
+                    return err
+                }
+                notifyExpiry([]internal.ScanResult{{Path: certFile, Cert: certs[0]}}, at)
+            }
+
+            if thresholds {
+                return nagiosExit(cmd, expiryState(status))
             }
-            fmt.Printf("Certificate expires in %d days.\n", days)
             return nil
         },
     }
     checkExpirationCmd.Flags().StringVar(&certFile, "cert", "", "Path to the certificate file")
+    checkExpirationCmd.Flags().StringVar(&scanPath, "path", "", "Directory to scan for certificates")
+    checkExpirationCmd.Flags().BoolVar(&scanRecursive, "recursive", false, "Scan subdirectories of --path")
+    checkExpirationCmd.Flags().StringVar(&scanInclude, "include", strings.Join(internal.DefaultScanInclude, ","), "Comma-separated file patterns to scan")
+    checkExpirationCmd.Flags().IntVar(&warnDays, "warn", 30, "Warning threshold in days (exit code 1)")
+    checkExpirationCmd.Flags().IntVar(&critDays, "crit", 7, "Critical threshold in days (exit code 2)")
+    checkExpirationCmd.Flags().StringVar(&expiryAt, "at", "", "Evaluate at this time instead of now (RFC3339)")
+    checkExpirationCmd.Flags().BoolVar(&notifyEnabled, "notify", false, "Send alerts through the notify section of --config")
+    addQueryFlags(checkExpirationCmd)
+    addRemoteFlags(checkExpirationCmd)
 
     // Comando: fingerprint
     fingerprintCmd := &cobra.Command{
         Use:   "fingerprint",
-        Short: "Show the SHA256 fingerprint of a certificate",
+        Short: "Show the fingerprint of a certificate, or the SPKI pin of a certificate, CSR or key",
         RunE: func(cmd *cobra.Command, args []string) error {
+            opts := ssltool.FingerprintOptions{
+                Algorithm: fingerprintAlgo,
+                Format:    fingerprintFormat,
+                SPKI:      fingerprintSPKI,
+            }
+            label := strings.ToUpper(fingerprintAlgo) + " Fingerprint"
+            if fingerprintSPKI {
+                label = "SPKI " + label
+            }
+
+            // Con --remote se usa el certificado hoja presentado por el servidor
+            if remoteAddress != "" {
+                chain, err := fetchRemoteChain()
+                if err != nil {
+                    return err
+                }
+                fp, err := ssltool.FingerprintCertificate(chain[0], opts)
+                if err != nil {
+                    return err
+                }
+                fmt.Printf("%s: %s\n", label, fp)
+                return nil
+            }
+
+            if filePath == "" {
+                filePath = certFile
+            }
+
             if interactive {
-                certFile = promptFor("Path to certificate (.crt)", certFile)
+                filePath = promptFor("Path to certificate, CSR or key", filePath)
             } else {
-                if certFile == "" {
+                if filePath == "" {
                     return errors.New("please provide --cert or use --interactive")
                 }
             }
 
-            if certFile == "" {
+            if filePath == "" {
                 return errors.New("certificate path cannot be empty")
             }
 
-            if !fileExists(certFile) {
-                return fmt.Errorf("certificate file does not exist: %s", certFile)
+            if !fileExists(filePath) {
+                return fmt.Errorf("certificate file does not exist: %s", filePath)
+            }
+
+            passin, err := readPassphrase(keyPassIn)
+            if err != nil {
+                return err
             }
+            opts.File, opts.Passphrase = filePath, passin
 
-            fp, err := internal.CertificateFingerprint(certFile)
+            fp, err := ssltool.Fingerprint(context.Background(), opts)
             if err != nil {
                 return err
             }
-            fmt.Printf("SHA256 Fingerprint: %s\n", fp)
+            fmt.Printf("%s: %s\n", label, fp)
             return nil
         },
     }
     fingerprintCmd.Flags().StringVar(&certFile, "cert", "", "Path to the certificate")
+    fingerprintCmd.Flags().StringVar(&filePath, "file", "", "Path to the certificate, CSR or key (alias of --cert)")
+    fingerprintCmd.Flags().StringVar(&fingerprintAlgo, "algo", "sha256", "Hash algorithm: sha1, sha256, sha384 or sha512")
+    fingerprintCmd.Flags().StringVar(&fingerprintFormat, "format", internal.FingerprintHex, "Output format: hex, colon or base64")
+    fingerprintCmd.Flags().BoolVar(&fingerprintSPKI, "spki", false, "Fingerprint the SubjectPublicKeyInfo (HPKP-style pin) instead of the whole certificate")
+    fingerprintCmd.Flags().StringVar(&keyPassIn, "passin", "", "Passphrase of an encrypted private key (pass:, env: or file: prefix)")
+    addRemoteFlags(fingerprintCmd)
 
     // Comando: verify-hashes
     verifyHashesCmd := &cobra.Command{
@@ -226,7 +320,17 @@ func main() {
                 return fmt.Errorf("certificate file does not exist: %s", certFile)
             }
 
-            return internal.VerifyHashes(keyFile, csrFile, certFile)
+            hashes, err := ssltool.VerifyHashes(context.Background(), ssltool.HashesOptions{KeyFile: keyFile, CSRFile: csrFile, CertFile: certFile})
+            var mismatch *ssltool.HashMismatchError
+            if errors.As(err, &mismatch) {
+                fmt.Printf("Hashes do not match:\n- Key: %s\n- CSR: %s\n- Certificate: %s\n", hashes.Key, hashes.CSR, hashes.Certificate)
+                return nil
+            }
+            if err != nil {
+                return err
+            }
+            fmt.Println("Hashes match! The private key, CSR, and certificate are consistent.")
+            return nil
         },
     }
     verifyHashesCmd.Flags().StringVar(&keyFile, "key", "", "Path to the private key file")
@@ -239,8 +343,26 @@ func main() {
     rootCmd.AddCommand(checkExpirationCmd)
     rootCmd.AddCommand(fingerprintCmd)
     rootCmd.AddCommand(verifyHashesCmd)
+    rootCmd.AddCommand(newKeyCmd())
+    rootCmd.AddCommand(newVerifyChainCmd())
+    rootCmd.AddCommand(newInspectCmd())
+    rootCmd.AddCommand(newProbeCmd())
+    rootCmd.AddCommand(newServeMetricsCmd())
+    rootCmd.AddCommand(newInventoryCmd())
+    rootCmd.AddCommand(newReportCmd())
+    rootCmd.AddCommand(newWatchCmd())
+    rootCmd.AddCommand(newACMECmd())
+    rootCmd.AddCommand(newESTCmd())
+    rootCmd.AddCommand(newSCEPCmd())
+    rootCmd.AddCommand(newTrustStoreCmd())
+    rootCmd.AddCommand(newLintCmd())
 
     if err := rootCmd.Execute(); err != nil {
+        // Los comandos de monitorización ya han impreso su salida y solo fijan el código
+        var exit exitError
+        if errors.As(err, &exit) {
+            os.Exit(exit.code)
+        }
         fmt.Println(err)
         os.Exit(1)
     }
@@ -252,6 +374,63 @@ func readLine() string {
     return strings.TrimSpace(line)
 }
 
+// prepareCSRParams completa domain, country, locality y organization con la configuración o
+// preguntando en modo interactivo, y los valida. Lo comparten generate-csr y est enroll.
+func prepareCSRParams() error {
+    // Usar valores predeterminados de la configuración si no se proporcionan flags
+    if domain == "" && config.DefaultDomain != "" {
+        domain = config.DefaultDomain
+    }
+    if country == "" && config.DefaultCountry != "" {
+        country = config.DefaultCountry
+    }
+    if locality == "" && config.DefaultLocality != "" {
+        locality = config.DefaultLocality
+    }
+    if organization == "" && config.DefaultOrganization != "" {
+        organization = config.DefaultOrganization
+    }
+
+    requiredParams := []string{"domain", "country", "locality", "organization"}
+
+    if interactive {
+        // En modo interactivo, preguntar por todos los datos
+        domain = promptFor("Domain", domain)
+        country = promptFor("Country (2 letters)", country)
+        locality = promptFor("Locality (City)", locality)
+        organization = promptFor("Organization", organization)
+    } else {
+        // Validar que todos los parámetros requeridos estén presentes
+        for _, p := range requiredParams {
+            val := getParamValue(p)
+            if val == "" {
+                return fmt.Errorf("missing required parameter: %s. Provide flags or use --interactive", p)
+            }
+        }
+    }
+
+    // Validar parámetros antes de generar el CSR
+    return internal.ValidateCSRParams(domain, country, locality, organization)
+}
+
+// generateCSRFiles genera la clave (de tipo csrKeyType, RSA 2048 salvo en generate-csr) y el CSR en
+// el directorio del dominio (example_com/) y devuelve la ruta del CSR.
+func generateCSRFiles() (string, error) {
+    csr, err := ssltool.GenerateCSR(context.Background(), ssltool.CSROptions{
+        Domain:       domain,
+        Country:      country,
+        Locality:     locality,
+        Organization: organization,
+        KeyType:      csrKeyType,
+        OutputDir:    internal.CSRDirName(domain),
+    })
+    if err != nil {
+        return "", err
+    }
+    fmt.Printf("Files generated successfully:\n- Private Key: %s\n- CSR: %s\n", csr.KeyPath, csr.CSRPath)
+    return csr.CSRPath, nil
+}
+
 // promptFor muestra un prompt con un valor por defecto. Si el usuario presiona Enter, se mantiene el valor por defecto.
 func promptFor(label, defaultVal string) string {
     if defaultVal != "" {
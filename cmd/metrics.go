@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/luisenrique-varelarodriguez/ssl-tool/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	metricsListen   string
+	metricsTargets  string
+	metricsInterval time.Duration
+)
+
+// newServeMetricsCmd construye el comando "serve-metrics".
+func newServeMetricsCmd() *cobra.Command {
+	serveMetricsCmd := &cobra.Command{
+		Use:   "serve-metrics",
+		Short: "Expose certificate expiry and verification metrics for Prometheus",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if interactive {
+				metricsTargets = promptFor("Path to targets file", metricsTargets)
+			} else if metricsTargets == "" {
+				return errors.New("please provide --targets or use --interactive")
+			}
+			if !fileExists(metricsTargets) {
+				return fmt.Errorf("targets file does not exist: %s", metricsTargets)
+			}
+
+			targets, err := internal.LoadMetricsTargets(metricsTargets)
+			if err != nil {
+				return err
+			}
+			if metricsInterval > 0 {
+				targets.Interval = metricsInterval
+			}
+
+			var notifier *internal.Notifier
+			if notifyEnabled {
+				if notifier, err = newNotifier(); err != nil {
+					return err
+				}
+			}
+			cmd.SilenceUsage = true
+			return serveMetrics(targets, notifier)
+		},
+	}
+	serveMetricsCmd.Flags().StringVar(&metricsListen, "listen", ":9219", "Address to serve /metrics on")
+	serveMetricsCmd.Flags().StringVar(&metricsTargets, "targets", "", "YAML file with the files, directories and endpoints to monitor")
+	serveMetricsCmd.Flags().DurationVar(&metricsInterval, "interval", 0, "Rescan interval (default: the targets file value, or 5m)")
+	serveMetricsCmd.Flags().BoolVar(&notifyEnabled, "notify", false, "Send expiry alerts after each scan through the notify section of --config")
+	return serveMetricsCmd
+}
+
+// serveMetrics revisa los objetivos periódicamente y sirve las métricas hasta recibir SIGINT o SIGTERM.
+// Con un notificador, después de cada revisión se envían los avisos de caducidad pendientes.
+func serveMetrics(targets internal.MetricsTargets, notifier *internal.Notifier) error {
+	exporter := internal.NewMetricsExporter(targets)
+	collect := func() {
+		for _, err := range exporter.Collect() {
+			log.Printf("scan error: %v", err)
+		}
+		if notifier == nil {
+			return
+		}
+		sent, err := notifier.Notify(exporter.Results(), time.Time{})
+		for _, alert := range sent {
+			log.Printf("notified: %s", alert.Message)
+		}
+		if err != nil {
+			log.Printf("notification error: %v", err)
+		}
+	}
+	collect()
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", exporter)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintln(w, `<html><head><title>ssl-tool exporter</title></head><body><a href="/metrics">Metrics</a></body></html>`)
+	})
+	server := &http.Server{Addr: metricsListen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		ticker := time.NewTicker(targets.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				collect()
+			}
+		}
+	}()
+
+	errs := make(chan error, 1)
+	go func() {
+		log.Printf("serving metrics for %d target(s) on %s every %s", len(targets.Targets), metricsListen, targets.Interval)
+		errs <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return fmt.Errorf("error serving metrics: %v", err)
+	case <-ctx.Done():
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return server.Shutdown(shutdown)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/luisenrique-varelarodriguez/ssl-tool/internal"
+)
+
+var notifyEnabled bool
+
+// newNotifier crea el notificador a partir de la sección notify de --config.
+func newNotifier() (*internal.Notifier, error) {
+	if config.Notify == nil {
+		return nil, fmt.Errorf("--notify requires a notify section in %s", configPath)
+	}
+	return internal.NewNotifier(*config.Notify)
+}
+
+// notifyExpiry envía los avisos pendientes de check-expiration. Los fallos de envío se muestran por
+// la salida de error y no cambian el código de salida, que sigue reflejando el estado de los certificados.
+func notifyExpiry(results []internal.ScanResult, at time.Time) {
+	notifier, err := newNotifier()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Notification error: %v\n", err)
+		return
+	}
+	sent, err := notifier.Notify(results, at)
+	// Con --format json la salida estándar queda reservada para el documento JSON
+	out := os.Stdout
+	if outputFormat == outputJSON {
+		out = os.Stderr
+	}
+	for _, alert := range sent {
+		fmt.Fprintf(out, "Notified: %s\n", alert.Message)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Notification error: %v\n", err)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/luisenrique-varelarodriguez/ssl-tool/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	probeALPN    string
+	probeRequire string
+)
+
+// newProbeCmd construye el comando "probe".
+func newProbeCmd() *cobra.Command {
+	probeCmd := &cobra.Command{
+		Use:   "probe host:port",
+		Short: "Enumerate the TLS versions, cipher suites, curves and features accepted by an endpoint",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 1 {
+				remoteAddress = args[0]
+			}
+			if interactive {
+				remoteAddress = promptFor("Endpoint to probe (host:port)", remoteAddress)
+			} else if remoteAddress == "" {
+				return errors.New("please provide host:port or use --interactive")
+			}
+			if probeRequire != "" && probeRequire != internal.ProfileModern && probeRequire != internal.ProfileIntermediate && probeRequire != internal.ProfileOld {
+				return fmt.Errorf("invalid --require profile: %s (use %s, %s or %s)", probeRequire, internal.ProfileModern, internal.ProfileIntermediate, internal.ProfileOld)
+			}
+
+			opts := internal.ProbeOptions{Remote: remoteOptions()}
+			for _, protocol := range strings.Split(probeALPN, ",") {
+				if protocol = strings.TrimSpace(protocol); protocol != "" {
+					opts.ALPN = append(opts.ALPN, protocol)
+				}
+			}
+
+			cmd.SilenceUsage = true
+			result, err := internal.ProbeTLS(opts)
+			if err != nil {
+				return err
+			}
+			printProbeResult(result)
+
+			if probeRequire != "" {
+				check, err := result.Profile(probeRequire)
+				if err != nil {
+					return err
+				}
+				if !check.Compliant() {
+					return fmt.Errorf("%s does not meet the Mozilla %s profile", result.Address, probeRequire)
+				}
+			}
+			return nil
+		},
+	}
+	probeCmd.Flags().StringVar(&probeALPN, "alpn", strings.Join(internal.DefaultProbeALPN, ","), "Comma-separated ALPN protocols to test")
+	probeCmd.Flags().StringVar(&probeRequire, "require", "", "Fail unless the endpoint meets this Mozilla profile: modern|intermediate|old")
+	addConnectionFlags(probeCmd)
+	return probeCmd
+}
+
+// printProbeResult muestra el resultado de probe agrupado por apartados.
+func printProbeResult(result *internal.ProbeResult) {
+	fmt.Printf("Probe of %s\n", result.Address)
+	if len(result.Chain) > 0 {
+		leaf := result.Chain[0]
+		fmt.Printf("Certificate: %s (expires %s)\n", leaf.Subject.CommonName, leaf.NotAfter.Format("2006-01-02"))
+	}
+
+	fmt.Println("\nProtocol versions:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, version := range []uint16{tls.VersionTLS13, tls.VersionTLS12, tls.VersionTLS11, tls.VersionTLS10} {
+		fmt.Fprintf(w, "  %s\t%s\n", tls.VersionName(version), yesNo(slices.Contains(result.Versions, version)))
+	}
+	w.Flush()
+
+	fmt.Println("\nCipher suites:")
+	for _, version := range internal.SortedVersions(result.Versions) {
+		if version == tls.VersionTLS13 {
+			fmt.Printf("  %s: %s (negotiated; TLS 1.3 suites cannot be enumerated)\n", tls.VersionName(version), tls.CipherSuiteName(result.TLS13Suite))
+			continue
+		}
+		fmt.Printf("  %s:\n", tls.VersionName(version))
+		for _, suite := range result.CipherSuites[version] {
+			if internal.InsecureCipherSuite(suite) {
+				fmt.Printf("    %s (insecure)\n", tls.CipherSuiteName(suite))
+			} else {
+				fmt.Printf("    %s\n", tls.CipherSuiteName(suite))
+			}
+		}
+	}
+
+	var curves []string
+	for _, curve := range result.Curves {
+		curves = append(curves, curve.String())
+	}
+	fmt.Printf("\nCurves: %s\n", listOrNone(curves))
+	fmt.Printf("ALPN: %s\n", listOrNone(result.ALPN))
+	if result.OCSPStapled {
+		if result.OCSPNext.IsZero() {
+			fmt.Printf("OCSP stapling: %s\n", result.OCSPStatus)
+		} else {
+			fmt.Printf("OCSP stapling: %s (next update %s)\n", result.OCSPStatus, result.OCSPNext.Format("2006-01-02 15:04 MST"))
+		}
+	} else {
+		fmt.Println("OCSP stapling: not stapled")
+	}
+	fmt.Printf("Session resumption: %s\n", yesNo(result.Resumption))
+
+	fmt.Println("\nMozilla profiles:")
+	for _, check := range result.Profiles {
+		if check.Compliant() {
+			fmt.Printf("  %-13s compliant\n", check.Name)
+			continue
+		}
+		fmt.Printf("  %-13s not compliant\n", check.Name)
+		for _, issue := range check.Issues {
+			fmt.Printf("    - %s\n", issue)
+		}
+	}
+	fmt.Printf("\nGrade: %s\n", result.Grade())
+}
+
+func yesNo(value bool) string {
+	if value {
+		return "yes"
+	}
+	return "no"
+}
+
+func listOrNone(values []string) string {
+	if len(values) == 0 {
+		return "none"
+	}
+	return strings.Join(values, ", ")
+}
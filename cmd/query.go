@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/luisenrique-varelarodriguez/ssl-tool/internal"
+	"github.com/spf13/cobra"
+)
+
+// Formatos de --format para los listados de certificados.
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+var (
+	whereExpr    string
+	outputFormat string
+)
+
+// addQueryFlags añade --where y --format a un comando que lista certificados.
+func addQueryFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&whereExpr, "where", "", `Only include certificates matching this expression, e.g. 'days < 30 && san contains "*.corp"'`)
+	cmd.Flags().StringVar(&outputFormat, "format", outputText, "Output format: text or json")
+}
+
+// parseListing valida --format y analiza --where en los comandos que usan addQueryFlags.
+func parseListing() (*internal.Query, error) {
+	if outputFormat != outputText && outputFormat != outputJSON {
+		return nil, fmt.Errorf("unsupported output format: %s (expected text or json)", outputFormat)
+	}
+	return parseWhere()
+}
+
+// parseWhere analiza --where; devuelve nil si no se ha indicado ninguna expresión.
+func parseWhere() (*internal.Query, error) {
+	if whereExpr == "" {
+		return nil, nil
+	}
+	return internal.ParseQuery(whereExpr)
+}
+
+// filterResults deja solo los certificados que cumplen la consulta. Los archivos que no se han podido
+// leer no tienen campos que evaluar y se descartan.
+func filterResults(query *internal.Query, results []internal.ScanResult, at time.Time) ([]internal.ScanResult, error) {
+	if query == nil {
+		return results, nil
+	}
+	var matched []internal.ScanResult
+	for _, r := range results {
+		if r.Err != nil {
+			continue
+		}
+		ok, err := query.Match(internal.CertificateFields(r.Cert, r.Path, at))
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matched = append(matched, r)
+		}
+	}
+	return matched, nil
+}
+
+// printJSON escribe v con sangría por la salida estándar.
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
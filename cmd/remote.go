@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/luisenrique-varelarodriguez/ssl-tool/internal"
+	"github.com/luisenrique-varelarodriguez/ssl-tool/pkg/ssltool"
+	"github.com/spf13/cobra"
+)
+
+var (
+	remoteAddress  string
+	remoteSNI      string
+	remoteTimeout  = internal.DefaultRemoteTimeout
+	remoteStartTLS string
+)
+
+// addRemoteFlags añade a un comando los flags para leer la cadena de un endpoint TLS.
+func addRemoteFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&remoteAddress, "remote", "", "Fetch the certificate chain from a TLS endpoint (host:port)")
+	addConnectionFlags(cmd)
+}
+
+// addConnectionFlags añade los flags que controlan la conexión con el endpoint.
+func addConnectionFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&remoteSNI, "sni", "", "Server name to send in the TLS handshake (default: the endpoint host)")
+	cmd.Flags().DurationVar(&remoteTimeout, "timeout", internal.DefaultRemoteTimeout, "Timeout for each connection and handshake with the endpoint")
+	cmd.Flags().StringVar(&remoteStartTLS, "starttls", "", "Upgrade to TLS with STARTTLS before the handshake: "+strings.Join(internal.StartTLSProtocols(), "|"))
+}
+
+// remoteOptions construye las opciones de conexión a partir de los flags.
+func remoteOptions() internal.RemoteOptions {
+	return internal.RemoteOptions{
+		Address:  remoteAddress,
+		SNI:      remoteSNI,
+		Timeout:  remoteTimeout,
+		StartTLS: remoteStartTLS,
+	}
+}
+
+// fetchRemoteChain obtiene la cadena presentada por --remote.
+// La cadena se registra en el inventario si existe.
+func fetchRemoteChain() ([]*x509.Certificate, error) {
+	chain, err := internal.FetchRemoteChain(remoteOptions())
+	if err != nil {
+		return nil, err
+	}
+	registerChain(remoteAddress, chain)
+	return chain, nil
+}
+
+// newInspectCmd construye el comando "inspect".
+func newInspectCmd() *cobra.Command {
+	inspectCmd := &cobra.Command{
+		Use:   "inspect",
+		Short: "Show the details of a certificate, CSR or the chain presented by a TLS endpoint",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if remoteAddress != "" {
+				chain, err := fetchRemoteChain()
+				if err != nil {
+					return err
+				}
+				fmt.Printf("Chain presented by %s (%d certificate(s)):\n", remoteAddress, len(chain))
+				for _, cert := range chain {
+					printCertificateInfo(cert)
+				}
+				return nil
+			}
+
+			if interactive {
+				filePath = promptFor("Path to CRT or CSR file", filePath)
+			} else if filePath == "" {
+				return errors.New("please provide --file, --remote or use --interactive")
+			}
+
+			if !fileExists(filePath) {
+				return fmt.Errorf("file does not exist: %s", filePath)
+			}
+			objects, err := ssltool.Inspect(context.Background(), filePath)
+			if err != nil {
+				return err
+			}
+			for _, object := range objects {
+				if object.Certificate != nil {
+					printCertificateInfo(object.Certificate)
+				} else {
+					printCSRInfo(object.CSR)
+				}
+			}
+			return nil
+		},
+	}
+	inspectCmd.Flags().StringVar(&filePath, "file", "", "Path to the CRT or CSR file")
+	addRemoteFlags(inspectCmd)
+	return inspectCmd
+}
+
+// printCertificateInfo muestra los datos principales de un certificado.
+func printCertificateInfo(cert *x509.Certificate) {
+	fmt.Println("Certificate Info:")
+	fmt.Printf("- Common Name: %s\n", cert.Subject.CommonName)
+	fmt.Printf("- Organization: %v\n", cert.Subject.Organization)
+	fmt.Printf("- Locality: %v\n", cert.Subject.Locality)
+	fmt.Printf("- Country: %v\n", cert.Subject.Country)
+	fmt.Printf("- Issuer: %s\n", cert.Issuer.CommonName)
+	fmt.Printf("- Serial: %s\n", cert.SerialNumber.Text(16))
+	fmt.Printf("- SANs: %v\n", internal.SubjectAltNames(cert.DNSNames, cert.IPAddresses, cert.EmailAddresses))
+	fmt.Printf("- Valid From: %s\n", cert.NotBefore)
+	fmt.Printf("- Valid To: %s\n", cert.NotAfter)
+}
+
+// printCSRInfo muestra los datos principales de un CSR.
+func printCSRInfo(csr *x509.CertificateRequest) {
+	fmt.Println("CSR Info:")
+	fmt.Printf("- Common Name: %s\n", csr.Subject.CommonName)
+	fmt.Printf("- Organization: %v\n", csr.Subject.Organization)
+	fmt.Printf("- Locality: %v\n", csr.Subject.Locality)
+	fmt.Printf("- Country: %v\n", csr.Subject.Country)
+	fmt.Printf("- SANs: %v\n", internal.SubjectAltNames(csr.DNSNames, csr.IPAddresses, csr.EmailAddresses))
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/luisenrique-varelarodriguez/ssl-tool/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	reportFormat string
+	reportOutput string
+)
+
+// newReportCmd construye el comando "report".
+func newReportCmd() *cobra.Command {
+	reportCmd := &cobra.Command{
+		Use:   "report",
+		Short: "Write a certificate report (expiry buckets, issuers, weak crypto, owners) for a directory or the inventory",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if interactive {
+				scanPath = promptFor("Directory to scan (empty to use the inventory)", scanPath)
+				reportFormat = promptFor("Report format (csv, html or markdown)", reportFormat)
+				reportOutput = promptFor("Output file (empty for standard output)", reportOutput)
+			}
+			if reportFormat != internal.ReportCSV && reportFormat != internal.ReportHTML && reportFormat != internal.ReportMarkdown {
+				return fmt.Errorf("unsupported report format: %s (expected csv, html or markdown)", reportFormat)
+			}
+			at, err := expiryTime()
+			if err != nil {
+				return err
+			}
+			query, err := parseWhere()
+			if err != nil {
+				return err
+			}
+
+			var fields []internal.QueryFields
+			source := scanPath
+			if scanPath != "" {
+				fields, err = scanReportFields(at)
+			} else {
+				source = "inventory " + inventoryPath
+				fields, err = inventoryReportFields(at)
+			}
+			if err != nil {
+				return err
+			}
+
+			var certs []internal.ReportCertificate
+			for _, f := range fields {
+				if query != nil {
+					ok, err := query.Match(f)
+					if err != nil {
+						return err
+					}
+					if !ok {
+						continue
+					}
+				}
+				certs = append(certs, internal.NewReportCertificate(f))
+			}
+			report := internal.BuildReport(certs, source, at)
+
+			if reportOutput == "" {
+				return internal.WriteReport(os.Stdout, report, reportFormat)
+			}
+			file, err := os.Create(reportOutput)
+			if err != nil {
+				return fmt.Errorf("error creating report file: %v", err)
+			}
+			defer file.Close()
+			if err := internal.WriteReport(file, report, reportFormat); err != nil {
+				return err
+			}
+			fmt.Printf("Report with %d certificate(s) saved to %s\n", len(report.Certificates), reportOutput)
+			return nil
+		},
+	}
+	reportCmd.Flags().StringVar(&reportFormat, "format", internal.ReportMarkdown, "Report format: csv, html or markdown")
+	reportCmd.Flags().StringVar(&reportOutput, "output", "", "Path to save the report (default: standard output)")
+	reportCmd.Flags().StringVar(&scanPath, "path", "", "Directory to scan instead of the inventory")
+	reportCmd.Flags().BoolVar(&scanRecursive, "recursive", false, "Scan subdirectories of --path")
+	reportCmd.Flags().StringVar(&scanInclude, "include", strings.Join(internal.DefaultScanInclude, ","), "Comma-separated file patterns to scan")
+	reportCmd.Flags().StringVar(&expiryAt, "at", "", "Evaluate at this time instead of now (RFC3339)")
+	reportCmd.Flags().StringVar(&whereExpr, "where", "", "Only include certificates matching this expression")
+	return reportCmd
+}
+
+// scanReportFields escanea --path. Si el inventario existe, los propietarios se toman de él.
+func scanReportFields(at time.Time) ([]internal.QueryFields, error) {
+	var include []string
+	for _, pattern := range strings.Split(scanInclude, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			include = append(include, pattern)
+		}
+	}
+	results, err := internal.ScanCertificates(internal.ScanOptions{Root: scanPath, Recursive: scanRecursive, Include: include})
+	if err != nil {
+		return nil, err
+	}
+
+	var inv *internal.Inventory
+	if fileExists(inventoryPath) {
+		if inv, err = internal.OpenInventory(inventoryPath); err != nil {
+			return nil, err
+		}
+		defer inv.Close()
+	}
+
+	var fields []internal.QueryFields
+	for _, r := range results {
+		if r.Err != nil {
+			fmt.Fprintf(os.Stderr, "Skipping %s: %v\n", r.Path, r.Err)
+			continue
+		}
+		f := internal.CertificateFields(r.Cert, scanLocation(r), at)
+		if inv != nil {
+			if entry, err := inv.Get(f["fingerprint"].(string)); err == nil {
+				f["owner"] = entry.Owner
+			}
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// inventoryReportFields lee los certificados del inventario (los CSR no entran en el informe).
+func inventoryReportFields(at time.Time) ([]internal.QueryFields, error) {
+	if !fileExists(inventoryPath) {
+		return nil, errors.New("please provide --path or create the inventory with \"inventory add\" or \"inventory scan\"")
+	}
+	var fields []internal.QueryFields
+	err := withInventory(func(inv *internal.Inventory) error {
+		entries, err := inv.List()
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if entry.Kind == internal.InventoryCertificate {
+				fields = append(fields, internal.InventoryFields(entry, at))
+			}
+		}
+		return nil
+	})
+	return fields, err
+}
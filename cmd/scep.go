@@ -0,0 +1,172 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/luisenrique-varelarodriguez/ssl-tool/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	scepServer        string
+	scepCAIdentifier  string
+	scepCAFingerprint string
+	scepRoots         string
+	scepTimeout       time.Duration
+	scepChallenge     string
+	scepCSRFile       string
+	scepKeyFile       string
+	scepOut           string
+	scepPollInterval  time.Duration
+	scepPollTimeout   time.Duration
+	scepResume        bool
+)
+
+// newSCEPCmd construye el comando "scep" y sus subcomandos.
+func newSCEPCmd() *cobra.Command {
+	scepCmd := &cobra.Command{
+		Use:   "scep",
+		Short: "Enroll certificates with a SCEP (RFC 8894) server",
+	}
+	scepCmd.PersistentFlags().StringVar(&scepServer, "server", "", "SCEP service URL (e.g. http://mdm.example.com/scep)")
+	scepCmd.PersistentFlags().StringVar(&scepCAIdentifier, "ca-identifier", "", "CA identifier sent as the message parameter of GetCACert (optional)")
+	scepCmd.PersistentFlags().StringVar(&scepCAFingerprint, "ca-fingerprint", "", "Expected SHA-256 fingerprint of the CA certificate (hex, colons allowed)")
+	scepCmd.PersistentFlags().StringVar(&scepRoots, "ca-roots", "", "PEM bundle to verify an https SCEP server (default: system roots)")
+	scepCmd.PersistentFlags().DurationVar(&scepTimeout, "timeout", internal.DefaultSCEPTimeout, "Timeout for each request")
+
+	getCACertCmd := &cobra.Command{
+		Use:   "getcacert",
+		Short: "Download the CA (and RA) certificates of the SCEP server",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts, err := scepOptions()
+			if err != nil {
+				return err
+			}
+			certs, err := internal.SCEPCACerts(opts)
+			if err != nil {
+				return err
+			}
+			caps, err := internal.SCEPCACaps(opts)
+			if err != nil {
+				return err
+			}
+
+			data := internal.EncodeCertificatesPEM(certs)
+			if scepOut == "" {
+				fmt.Print(string(data))
+			} else {
+				if err := os.WriteFile(scepOut, data, 0644); err != nil {
+					return fmt.Errorf("error writing CA certificates: %v", err)
+				}
+				fmt.Printf("Saved %d CA certificate(s) to %s\n", len(certs), scepOut)
+			}
+			// Las huellas sirven para fijar la CA con --ca-fingerprint en enroll
+			for _, cert := range certs {
+				fmt.Fprintf(os.Stderr, "- %s (SHA-256 %X)\n", cert.Subject, sha256.Sum256(cert.Raw))
+			}
+			if len(caps) > 0 {
+				fmt.Fprintf(os.Stderr, "Capabilities: %s\n", strings.Join(caps, ", "))
+			}
+			return nil
+		},
+	}
+	getCACertCmd.Flags().StringVar(&scepOut, "out", "", "File for the PEM certificates (default: standard output)")
+
+	enrollCmd := &cobra.Command{
+		Use:   "enroll",
+		Short: "Request a certificate for a new RSA key and CSR (as generate-csr) or for an existing CSR and key",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if interactive {
+				scepServer = promptFor("SCEP server URL", scepServer)
+				if !scepResume {
+					scepChallenge = promptFor("Challenge password", scepChallenge)
+				}
+			}
+			opts, err := scepOptions()
+			if err != nil {
+				return err
+			}
+			challenge, err := readPassphrase(scepChallenge)
+			if err != nil {
+				return err
+			}
+			if (scepCSRFile == "") != (scepKeyFile == "") {
+				return errors.New("--csr and --key must be used together")
+			}
+			if scepResume && scepCSRFile == "" {
+				return errors.New("--resume needs the --csr and --key of the pending request")
+			}
+
+			csrPath, keyPath := scepCSRFile, scepKeyFile
+			if csrPath == "" {
+				if err := prepareCSRParams(); err != nil {
+					return err
+				}
+				if csrPath, err = generateCSRFiles(); err != nil {
+					return err
+				}
+				keyPath = strings.TrimSuffix(csrPath, ".csr") + ".key"
+			}
+			csr, err := readCSRDER(csrPath)
+			if err != nil {
+				return err
+			}
+			key, err := internal.LoadPrivateKey(keyPath, nil)
+			if err != nil {
+				return err
+			}
+			out := scepOut
+			if out == "" {
+				out = strings.TrimSuffix(csrPath, ".csr") + ".crt"
+			}
+
+			opts.PollInterval, opts.PollTimeout, opts.Resume = scepPollInterval, scepPollTimeout, scepResume
+			opts.Logf = func(format string, args ...interface{}) {
+				fmt.Printf(format+"\n", args...)
+			}
+			cert, err := internal.SCEPEnroll(opts, csr, key, string(challenge))
+			var pending *internal.SCEPPendingError
+			if errors.As(err, &pending) {
+				return fmt.Errorf("%v (scep enroll --resume --csr %s --key %s)", err, csrPath, keyPath)
+			}
+			if err != nil {
+				return err
+			}
+			return saveEnrolled(out, []*x509.Certificate{cert})
+		},
+	}
+	enrollCmd.Flags().StringVar(&scepChallenge, "challenge", "", "Challenge password: pass:<value>, env:<VAR>, file:<path> or the value")
+	enrollCmd.Flags().StringVar(&scepCSRFile, "csr", "", "Existing CSR to send instead of generating a key and CSR (needs --key)")
+	enrollCmd.Flags().StringVar(&scepKeyFile, "key", "", "RSA private key of --csr")
+	enrollCmd.Flags().StringVar(&scepOut, "out", "", "File for the certificate (default: the CSR path with .crt)")
+	enrollCmd.Flags().DurationVar(&scepPollInterval, "poll-interval", internal.DefaultSCEPPollInterval, "Interval between retries while the request is pending")
+	enrollCmd.Flags().DurationVar(&scepPollTimeout, "poll-timeout", internal.DefaultSCEPPollTimeout, "Maximum time to wait for a pending request")
+	enrollCmd.Flags().BoolVar(&scepResume, "resume", false, "Keep polling a pending request of --csr and --key (CertPoll) instead of sending it again")
+	enrollCmd.Flags().StringVar(&domain, "domain", "", "Domain name for the CSR")
+	enrollCmd.Flags().StringVar(&country, "country", "", "Country (2 letters)")
+	enrollCmd.Flags().StringVar(&locality, "locality", "", "Locality (City)")
+	enrollCmd.Flags().StringVar(&organization, "organization", "", "Organization")
+
+	scepCmd.AddCommand(getCACertCmd, enrollCmd)
+	return scepCmd
+}
+
+// scepOptions reúne las opciones de conexión de los subcomandos.
+func scepOptions() (internal.SCEPOptions, error) {
+	if scepServer == "" {
+		return internal.SCEPOptions{}, errors.New("please provide --server or use --interactive")
+	}
+	return internal.SCEPOptions{
+		ServerURL:     scepServer,
+		CAIdentifier:  scepCAIdentifier,
+		CAFingerprint: scepCAFingerprint,
+		Roots:         scepRoots,
+		Timeout:       scepTimeout,
+	}, nil
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/luisenrique-varelarodriguez/ssl-tool/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	trustBundle      string
+	trustOut         string
+	trustLabel       string
+	trustKeepExpired bool
+	trustCanonical   bool
+	trustPurpose     string
+	trustDistrusted  bool
+	trustDryRun      bool
+)
+
+// newTrustStoreCmd construye el comando "truststore" y sus subcomandos.
+func newTrustStoreCmd() *cobra.Command {
+	trustCmd := &cobra.Command{
+		Use:   "truststore",
+		Short: "Maintain PEM CA bundles",
+	}
+	trustCmd.PersistentFlags().StringVar(&trustBundle, "bundle", "", "PEM CA bundle to work on")
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the certificates of the bundle",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			anchors, err := loadTrustBundle(false)
+			if err != nil {
+				return err
+			}
+			if len(anchors) == 0 {
+				fmt.Printf("%s has no certificates\n", trustBundle)
+				return nil
+			}
+			now := time.Now()
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "FINGERPRINT\tEXPIRES\tSTATUS\tNAME\tSUBJECT")
+			for _, anchor := range anchors {
+				status := "valid"
+				if now.After(anchor.Cert.NotAfter) {
+					status = "expired"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", shortFingerprint(anchor.Fingerprint), anchor.Cert.NotAfter.UTC().Format(time.DateOnly),
+					status, anchor.Name(), anchor.Cert.Subject)
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			fmt.Printf("%d certificate(s)\n", len(anchors))
+			return nil
+		},
+	}
+
+	addCmd := &cobra.Command{
+		Use:   "add file...",
+		Short: "Add the certificates of one or more PEM files (the bundle is created if it does not exist)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if interactive && len(args) == 0 {
+				args = []string{promptFor("Path to CA certificate", "")}
+			}
+			if len(args) == 0 {
+				return errors.New("please provide at least one certificate file or use --interactive")
+			}
+			anchors, err := loadTrustBundle(true)
+			if err != nil {
+				return err
+			}
+			added := 0
+			for _, path := range args {
+				certs, err := internal.LoadCertificates(path)
+				if err != nil {
+					return fmt.Errorf("%s: %v", path, err)
+				}
+				for _, cert := range certs {
+					anchor := internal.NewTrustAnchor(cert, trustLabel)
+					if _, err := internal.FindTrustAnchor(anchors, anchor.Fingerprint); err == nil {
+						fmt.Printf("Already in the bundle: %s %s\n", shortFingerprint(anchor.Fingerprint), anchor.Name())
+						continue
+					}
+					if !cert.IsCA {
+						fmt.Fprintf(os.Stderr, "Warning: %s is not a CA certificate\n", anchor.Name())
+					}
+					anchors = append(anchors, anchor)
+					added++
+					fmt.Printf("Added %s %s\n", shortFingerprint(anchor.Fingerprint), anchor.Name())
+				}
+			}
+			return writeTrustBundle(anchors, fmt.Sprintf("%d certificate(s) added", added))
+		},
+	}
+	addCmd.Flags().StringVar(&trustLabel, "label", "", "Label for the added certificates (default: their CN)")
+
+	removeCmd := &cobra.Command{
+		Use:   "remove fingerprint...",
+		Short: "Remove certificates by SHA-256 fingerprint (a unique prefix is enough)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if interactive && len(args) == 0 {
+				args = []string{promptFor("Fingerprint of the certificate to remove", "")}
+			}
+			if len(args) == 0 {
+				return errors.New("please provide at least one fingerprint or use --interactive")
+			}
+			anchors, err := loadTrustBundle(false)
+			if err != nil {
+				return err
+			}
+			for _, id := range args {
+				i, err := internal.FindTrustAnchor(anchors, id)
+				if err != nil {
+					return err
+				}
+				// Se eliminan también las copias repetidas del mismo certificado
+				removed := anchors[i]
+				kept := anchors[:0]
+				for _, anchor := range anchors {
+					if anchor.Fingerprint != removed.Fingerprint {
+						kept = append(kept, anchor)
+					}
+				}
+				anchors = kept
+				fmt.Printf("Removed %s %s\n", shortFingerprint(removed.Fingerprint), removed.Name())
+			}
+			return writeTrustBundle(anchors, fmt.Sprintf("%d certificate(s) removed", len(args)))
+		},
+	}
+
+	dedupeCmd := &cobra.Command{
+		Use:   "dedupe",
+		Short: "Remove duplicate and expired certificates and rewrite the bundle sorted and commented",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			anchors, err := loadTrustBundle(false)
+			if err != nil {
+				return err
+			}
+			kept, duplicates, expired := internal.DedupeTrustBundle(anchors, !trustKeepExpired, time.Now())
+			for _, anchor := range duplicates {
+				fmt.Printf("Removed duplicate %s %s\n", shortFingerprint(anchor.Fingerprint), anchor.Name())
+			}
+			for _, anchor := range expired {
+				fmt.Printf("Removed expired %s %s (expired %s)\n", shortFingerprint(anchor.Fingerprint), anchor.Name(),
+					anchor.Cert.NotAfter.UTC().Format(time.DateOnly))
+			}
+			return writeTrustBundle(kept, fmt.Sprintf("%d duplicate(s) and %d expired certificate(s) removed", len(duplicates), len(expired)))
+		},
+	}
+	dedupeCmd.Flags().BoolVar(&trustKeepExpired, "keep-expired", false, "Only remove duplicates")
+
+	verifyCmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Check that every certificate is a valid, unique, self-signed CA with a strong key",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			anchors, err := loadTrustBundle(false)
+			if err != nil {
+				return err
+			}
+			issues := internal.VerifyTrustBundle(anchors, time.Now())
+			for _, issue := range issues {
+				fmt.Printf("%s %s: %s\n", shortFingerprint(issue.Anchor.Fingerprint), issue.Anchor.Name(), issue.Problem)
+			}
+			if trustCanonical {
+				data, err := os.ReadFile(trustBundle)
+				if err != nil {
+					return fmt.Errorf("error reading bundle: %v", err)
+				}
+				sorted := append([]internal.TrustAnchor(nil), anchors...)
+				internal.SortTrustBundle(sorted)
+				if !bytes.Equal(data, internal.EncodeTrustBundle(sorted)) {
+					fmt.Printf("%s is not sorted and commented as ssl-tool writes it; run truststore dedupe\n", trustBundle)
+					issues = append(issues, internal.TrustIssue{Problem: "not canonical"})
+				}
+			}
+			if len(issues) > 0 {
+				return fmt.Errorf("%s has %d problem(s)", trustBundle, len(issues))
+			}
+			fmt.Printf("%s: %d certificate(s), no problems found\n", trustBundle, len(anchors))
+			return nil
+		},
+	}
+	verifyCmd.Flags().BoolVar(&trustCanonical, "canonical", false, "Also require the bundle to be sorted and commented as ssl-tool writes it")
+
+	importCmd := &cobra.Command{
+		Use:   "import-certdata certdata.txt",
+		Short: "Build the bundle from Mozilla's NSS certdata.txt, showing the roots added and removed",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			certdata := ""
+			if len(args) == 1 {
+				certdata = args[0]
+			}
+			if interactive {
+				certdata = promptFor("Path to certdata.txt", certdata)
+				trustBundle = promptFor("Bundle to write (empty for standard output)", trustBundle)
+			}
+			if certdata == "" {
+				return errors.New("please provide the certdata.txt file or use --interactive")
+			}
+			entries, err := internal.LoadCertdata(certdata)
+			if err != nil {
+				return err
+			}
+			anchors, skipped, err := internal.SelectCertdataAnchors(entries, trustPurpose, time.Now(), trustDistrusted)
+			if err != nil {
+				return err
+			}
+			internal.SortTrustBundle(anchors)
+
+			// Sin --bundle ni --out el almacén va a la salida estándar y el resumen a la de error
+			target := trustOut
+			if target == "" {
+				target = trustBundle
+			}
+			report := os.Stdout
+			if target == "" {
+				report = os.Stderr
+			}
+			fmt.Fprintf(report, "Imported %d of %d certificates trusted for %s from %s\n", len(anchors), len(entries), trustPurpose, certdata)
+			for _, skip := range skipped {
+				if strings.HasPrefix(skip.Reason, "distrusted") {
+					fmt.Fprintf(report, "Skipped %s: %s\n", skip.Entry.Label, skip.Reason)
+				}
+			}
+			if target == "" {
+				if trustDryRun {
+					return errors.New("--dry-run needs --bundle or --out to compare with")
+				}
+				fmt.Print(string(internal.EncodeTrustBundle(anchors)))
+				return nil
+			}
+
+			if fileExists(target) {
+				previous, err := internal.LoadTrustBundle(target)
+				if err != nil {
+					return err
+				}
+				printTrustDiff(previous, anchors)
+			}
+			if trustDryRun {
+				return nil
+			}
+			return writeTrustBundle(anchors, "imported from "+certdata)
+		},
+	}
+	importCmd.Flags().StringVar(&trustPurpose, "purpose", internal.CertdataPurposeServer, "Trust bit to honour: server (TLS server authentication) or email")
+	importCmd.Flags().BoolVar(&trustDistrusted, "keep-distrusted", false, "Keep roots whose distrust-after date has passed")
+	importCmd.Flags().BoolVar(&trustDryRun, "dry-run", false, "Only show the differences with the existing bundle")
+
+	diffCmd := &cobra.Command{
+		Use:   "diff other.pem",
+		Short: "Show the certificates added and removed in other.pem with respect to the bundle",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			previous, err := loadTrustBundle(false)
+			if err != nil {
+				return err
+			}
+			next, err := internal.LoadTrustBundle(args[0])
+			if err != nil {
+				return err
+			}
+			printTrustDiff(previous, next)
+			return nil
+		},
+	}
+
+	for _, c := range []*cobra.Command{addCmd, removeCmd, dedupeCmd, importCmd} {
+		c.Flags().StringVar(&trustOut, "out", "", "Write the result to this file instead of --bundle")
+	}
+	trustCmd.AddCommand(listCmd, addCmd, removeCmd, dedupeCmd, verifyCmd, importCmd, diffCmd)
+	return trustCmd
+}
+
+// loadTrustBundle lee --bundle; con allowMissing, un archivo inexistente es un almacén vacío.
+func loadTrustBundle(allowMissing bool) ([]internal.TrustAnchor, error) {
+	if interactive {
+		trustBundle = promptFor("Path to CA bundle", trustBundle)
+	}
+	if trustBundle == "" {
+		return nil, errors.New("please provide --bundle or use --interactive")
+	}
+	if allowMissing && !fileExists(trustBundle) {
+		return nil, nil
+	}
+	return internal.LoadTrustBundle(trustBundle)
+}
+
+// printTrustDiff muestra los certificados añadidos (+) y quitados (-) de previous a next.
+func printTrustDiff(previous, next []internal.TrustAnchor) {
+	added, removed := internal.DiffTrustBundles(previous, next)
+	for _, anchor := range added {
+		fmt.Printf("+ %s %s (expires %s)\n", shortFingerprint(anchor.Fingerprint), anchor.Name(), anchor.Cert.NotAfter.UTC().Format(time.DateOnly))
+	}
+	for _, anchor := range removed {
+		fmt.Printf("- %s %s (expires %s)\n", shortFingerprint(anchor.Fingerprint), anchor.Name(), anchor.Cert.NotAfter.UTC().Format(time.DateOnly))
+	}
+	fmt.Printf("%d added, %d removed, %d unchanged\n", len(added), len(removed), len(next)-len(added))
+}
+
+// writeTrustBundle guarda el almacén ordenado en --out o, si no se indica, en --bundle.
+func writeTrustBundle(anchors []internal.TrustAnchor, summary string) error {
+	out := trustOut
+	if out == "" {
+		out = trustBundle
+	}
+	internal.SortTrustBundle(anchors)
+	if err := os.WriteFile(out, internal.EncodeTrustBundle(anchors), 0644); err != nil {
+		return fmt.Errorf("error writing bundle: %v", err)
+	}
+	fmt.Printf("Saved %d certificate(s) to %s (%s)\n", len(anchors), out, summary)
+	return nil
+}
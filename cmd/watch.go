@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/luisenrique-varelarodriguez/ssl-tool/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	watchLogFormat string
+	watchDebounce  time.Duration
+)
+
+// newWatchCmd construye el comando "watch".
+func newWatchCmd() *cobra.Command {
+	watchCmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Watch a directory and re-check certificates whenever files are created, modified or renamed",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if interactive {
+				scanPath = promptFor("Directory to watch", scanPath)
+			} else if scanPath == "" {
+				return errors.New("please provide --path or use --interactive")
+			}
+
+			var handler slog.Handler
+			switch watchLogFormat {
+			case "text":
+				handler = slog.NewTextHandler(os.Stdout, nil)
+			case "json":
+				handler = slog.NewJSONHandler(os.Stdout, nil)
+			default:
+				return fmt.Errorf("unsupported log format: %s (expected text or json)", watchLogFormat)
+			}
+
+			var notifier *internal.Notifier
+			if notifyEnabled {
+				var err error
+				if notifier, err = newNotifier(); err != nil {
+					return err
+				}
+			}
+
+			var include []string
+			for _, pattern := range strings.Split(scanInclude, ",") {
+				if pattern = strings.TrimSpace(pattern); pattern != "" {
+					include = append(include, pattern)
+				}
+			}
+			opts := internal.WatchOptions{
+				Scan:     internal.ScanOptions{Root: scanPath, Recursive: scanRecursive, Include: include},
+				Debounce: watchDebounce,
+			}
+			return watchCertificates(opts, slog.New(handler), notifier)
+		},
+	}
+	watchCmd.Flags().StringVar(&scanPath, "path", "", "Directory to watch")
+	watchCmd.Flags().BoolVar(&scanRecursive, "recursive", false, "Watch subdirectories of --path")
+	watchCmd.Flags().StringVar(&scanInclude, "include", strings.Join(internal.DefaultScanInclude, ","), "Comma-separated file patterns to watch")
+	watchCmd.Flags().IntVar(&warnDays, "warn", 30, "Warning threshold in days")
+	watchCmd.Flags().IntVar(&critDays, "crit", 7, "Critical threshold in days")
+	watchCmd.Flags().BoolVar(&notifyEnabled, "notify", false, "Send alerts through the notify section of --config")
+	watchCmd.Flags().StringVar(&watchLogFormat, "log-format", "text", "Event log format: text or json")
+	watchCmd.Flags().DurationVar(&watchDebounce, "debounce", internal.DefaultWatchDebounce, "Time a file must stay unchanged before it is checked")
+	return watchCmd
+}
+
+// watchCertificates registra un evento por cada certificado de los archivos que cambian hasta recibir
+// SIGINT o SIGTERM. El nivel del evento refleja su estado: WARN para WARNING y ERROR para CRITICAL o
+// archivos ilegibles.
+func watchCertificates(opts internal.WatchOptions, logger *slog.Logger, notifier *internal.Notifier) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	logger.Info("watching", "path", opts.Scan.Root, "recursive", opts.Scan.Recursive)
+	// Los errores del watcher se registran y la vigilancia continúa
+	opts.OnError = func(err error) {
+		logger.Error("watch error", "path", opts.Scan.Root, "error", err.Error())
+	}
+	err := internal.WatchCertificates(ctx, opts, func(event internal.WatchEvent) {
+		if len(event.Results) == 0 {
+			logger.Info("file changed", "event", event.Op, "path", event.Path, "certificates", 0)
+			return
+		}
+
+		var checked []internal.ScanResult
+		for _, r := range event.Results {
+			if r.Err != nil {
+				logger.Error("unreadable certificate file", "event", event.Op, "path", r.Path, "error", r.Err.Error())
+				continue
+			}
+			checked = append(checked, r)
+			status := internal.CheckExpiry(r.Cert, time.Time{}, daysDuration(warnDays))
+			state := expiryState(status)
+			level := slog.LevelInfo
+			switch state {
+			case nagiosWarning:
+				level = slog.LevelWarn
+			case nagiosCritical:
+				level = slog.LevelError
+			}
+			logger.Log(context.Background(), level, "certificate checked",
+				"event", event.Op,
+				"path", scanLocation(r),
+				"subject", r.Cert.Subject.String(),
+				"issuer", r.Cert.Issuer.String(),
+				"dns_names", strings.Join(r.Cert.DNSNames, ","),
+				"serial", r.Cert.SerialNumber.Text(16),
+				"not_after", r.Cert.NotAfter.UTC().Format(time.RFC3339),
+				"remaining", remainingLabel(status),
+				"status", status.State,
+				"state", nagiosLabels[state],
+			)
+		}
+
+		registerInInventory(func(inv *internal.Inventory) error {
+			for _, r := range checked {
+				if _, _, err := inv.AddCertificate(r.Cert, r.Path, ""); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if notifier != nil && len(checked) > 0 {
+			sent, err := notifier.Notify(checked, time.Time{})
+			for _, alert := range sent {
+				logger.Info("notified", "path", alert.Source, "message", alert.Message)
+			}
+			if err != nil {
+				logger.Error("notification error", "error", err.Error())
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	logger.Info("stopped watching", "path", opts.Scan.Root)
+	return nil
+}
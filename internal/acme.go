@@ -0,0 +1,365 @@
+package internal
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/acme"
+)
+
+// Valores por defecto del cliente ACME.
+const (
+	LetsEncryptDirectory    = acme.LetsEncryptURL
+	DefaultACMEAccountKey   = "acme-account.key"
+	DefaultACMEHTTPListen   = ":80"
+	DefaultACMETimeout      = 5 * time.Minute
+	acmeChallengePathPrefix = "/.well-known/acme-challenge/"
+)
+
+// acmeTokenPattern es el alfabeto base64url que RFC 8555 (sección 8.3) exige a los tokens. El token
+// se usa como nombre de archivo en el webroot, así que cualquier otro carácter (como "../") se rechaza.
+var acmeTokenPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// ACMEOptions describe una emisión de certificado con ACME.
+type ACMEOptions struct {
+	Account     ACMEAccountOptions // la cuenta se registra si su clave no existe o no tiene cuenta
+	Domains     []string
+	CSR         CSRRequest  // campos del sujeto; el CN y los SANs se toman de Domains
+	KeyType     string      // tipo de la clave del certificado (ver GeneratePrivateKey)
+	HTTPListen  string      // dirección del servidor HTTP-01 propio si no hay Webroot
+	Webroot     string      // directorio servido por un servidor web existente
+	DNSProvider DNSProvider // si se indica, se resuelven retos DNS-01 en lugar de HTTP-01
+	OutDir      string      // por defecto, el directorio del dominio como en GenerateCSR
+	Timeout     time.Duration
+	Logf        func(format string, args ...interface{}) // progreso; nil no muestra nada
+}
+
+// ACMEResult es el resultado de una emisión.
+type ACMEResult struct {
+	AccountURL string
+	NewAccount bool
+	KeyPath    string
+	CertPath   string
+	Chain      []*x509.Certificate
+}
+
+// IssueCertificate registra o reutiliza la cuenta, crea el pedido, resuelve los retos HTTP-01 o
+// DNS-01, finaliza con un CSR creado con CreateCSR y guarda la clave y la cadena.
+func IssueCertificate(opts ACMEOptions) (ACMEResult, error) {
+	var result ACMEResult
+	logf := opts.Logf
+	if logf == nil {
+		logf = func(string, ...interface{}) {}
+	}
+	if len(opts.Domains) == 0 {
+		return result, errors.New("at least one domain is required")
+	}
+	for _, domain := range opts.Domains {
+		if strings.HasPrefix(domain, "*.") && opts.DNSProvider == nil {
+			return result, fmt.Errorf("wildcard domain %s cannot be validated with HTTP-01; use a DNS provider", domain)
+		}
+	}
+	if opts.KeyType == "" {
+		opts.KeyType = KeyTypeRSA2048
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultACMETimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	client, err := newACMEClient(opts.Account, true)
+	if err != nil {
+		return result, err
+	}
+	account, created, err := acmeAccount(ctx, client, opts.Account)
+	if err != nil {
+		return result, err
+	}
+	result.AccountURL, result.NewAccount = account.URI, created
+	if created {
+		logf("Registered ACME account %s", account.URI)
+	} else {
+		logf("Using ACME account %s", account.URI)
+	}
+
+	order, err := client.AuthorizeOrder(ctx, acme.DomainIDs(opts.Domains...))
+	if err != nil {
+		return result, fmt.Errorf("error creating order: %v", err)
+	}
+	logf("Created order %s", order.URI)
+
+	var solver challengeSolver
+	if opts.DNSProvider != nil {
+		solver = &dns01Solver{provider: opts.DNSProvider}
+	} else {
+		responder, err := newHTTP01Responder(opts)
+		if err != nil {
+			return result, err
+		}
+		defer responder.close()
+		solver = responder
+	}
+
+	for _, authzURL := range order.AuthzURLs {
+		if err := solveAuthorization(ctx, client, authzURL, solver, logf); err != nil {
+			return result, err
+		}
+	}
+	// WaitOrder devuelve el pedido sin su URL (va en la cabecera Location, que solo llega al crearlo)
+	orderURL := order.URI
+	if order, err = client.WaitOrder(ctx, orderURL); err != nil {
+		return result, fmt.Errorf("order failed: %v", err)
+	}
+	order.URI = orderURL
+
+	key, err := GeneratePrivateKey(opts.KeyType)
+	if err != nil {
+		return result, err
+	}
+	csrRequest := opts.CSR
+	csrRequest.Domain = opts.Domains[0]
+	csrRequest.DNSNames = opts.Domains
+	csr, err := CreateCSR(csrRequest, key)
+	if err != nil {
+		return result, err
+	}
+	ders, err := finalizeOrder(ctx, client, order, csr)
+	if err != nil {
+		return result, err
+	}
+	for _, der := range ders {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return result, fmt.Errorf("error parsing issued certificate: %v", err)
+		}
+		result.Chain = append(result.Chain, cert)
+	}
+
+	result.KeyPath, result.CertPath, err = saveIssued(opts, key, result.Chain)
+	return result, err
+}
+
+// finalizeOrder envía el CSR y descarga la cadena completa. Si la CA responde que el pedido sigue en
+// proceso sin indicar su URL (como Pebble), se espera con la URL conocida del pedido.
+func finalizeOrder(ctx context.Context, client *acme.Client, order *acme.Order, csr []byte) ([][]byte, error) {
+	ders, _, err := client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err == nil {
+		return ders, nil
+	}
+	var problem *acme.Error
+	if errors.As(err, &problem) {
+		return nil, fmt.Errorf("error finalising order: %v", err)
+	}
+	if order, err = client.WaitOrder(ctx, order.URI); err != nil {
+		return nil, fmt.Errorf("error finalising order: %v", err)
+	}
+	if order.Status != acme.StatusValid || order.CertURL == "" {
+		return nil, fmt.Errorf("error finalising order: order is %s", order.Status)
+	}
+	ders, err = client.FetchCert(ctx, order.CertURL, true)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading certificate: %v", err)
+	}
+	return ders, nil
+}
+
+// challengeSolver publica la respuesta a un tipo de reto y devuelve la función que la retira.
+type challengeSolver interface {
+	challengeType() string
+	present(ctx context.Context, client *acme.Client, domain string, challenge *acme.Challenge) (func(), error)
+}
+
+// solveAuthorization publica la respuesta al reto de una autorización y espera a que sea válida.
+func solveAuthorization(ctx context.Context, client *acme.Client, authzURL string, solver challengeSolver, logf func(string, ...interface{})) error {
+	authz, err := client.GetAuthorization(ctx, authzURL)
+	if err != nil {
+		return fmt.Errorf("error fetching authorization: %v", err)
+	}
+	domain := authz.Identifier.Value
+	if authz.Wildcard {
+		domain = "*." + domain
+	}
+	if authz.Status == acme.StatusValid {
+		logf("Authorization for %s is already valid", domain)
+		return nil
+	}
+
+	var challenge *acme.Challenge
+	for _, c := range authz.Challenges {
+		if c.Type == solver.challengeType() {
+			challenge = c
+			break
+		}
+	}
+	if challenge == nil {
+		return fmt.Errorf("the server did not offer a %s challenge for %s", solver.challengeType(), domain)
+	}
+
+	logf("Solving %s challenge for %s", challenge.Type, domain)
+	cleanup, err := solver.present(ctx, client, domain, challenge)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	if _, err := client.Accept(ctx, challenge); err != nil {
+		return fmt.Errorf("error accepting challenge for %s: %v", domain, err)
+	}
+	if _, err := client.WaitAuthorization(ctx, authz.URI); err != nil {
+		return fmt.Errorf("authorization for %s failed: %v", domain, err)
+	}
+	logf("Authorization for %s is valid", domain)
+	return nil
+}
+
+// dns01Solver publica el registro TXT del reto con un DNSProvider.
+type dns01Solver struct {
+	provider DNSProvider
+}
+
+func (s *dns01Solver) challengeType() string { return "dns-01" }
+
+func (s *dns01Solver) present(ctx context.Context, client *acme.Client, domain string, challenge *acme.Challenge) (func(), error) {
+	value, err := client.DNS01ChallengeRecord(challenge.Token)
+	if err != nil {
+		return nil, fmt.Errorf("error computing challenge response: %v", err)
+	}
+	fqdn := DNS01RecordName(domain)
+	if err := s.provider.Present(ctx, fqdn, value); err != nil {
+		return nil, fmt.Errorf("error publishing %s: %v", fqdn, err)
+	}
+	return func() {
+		// El contexto de la emisión puede haber caducado; el registro se retira igualmente
+		cleanupCtx, cancel := context.WithTimeout(context.Background(), dnsExchangeTimeout)
+		defer cancel()
+		s.provider.CleanUp(cleanupCtx, fqdn, value)
+	}, nil
+}
+
+// http01Responder publica las respuestas HTTP-01 con un servidor propio o en un webroot.
+type http01Responder struct {
+	webroot string
+	server  *http.Server
+	mu      sync.Mutex
+	tokens  map[string]string
+}
+
+func newHTTP01Responder(opts ACMEOptions) (*http01Responder, error) {
+	r := &http01Responder{webroot: opts.Webroot, tokens: map[string]string{}}
+	if r.webroot != "" {
+		return r, nil
+	}
+
+	listen := opts.HTTPListen
+	if listen == "" {
+		listen = DefaultACMEHTTPListen
+	}
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		return nil, fmt.Errorf("error starting the HTTP-01 responder: %v", err)
+	}
+	r.server = &http.Server{Handler: r, ReadHeaderTimeout: 10 * time.Second}
+	go r.server.Serve(listener)
+	return r, nil
+}
+
+func (r *http01Responder) challengeType() string { return "http-01" }
+
+func (r *http01Responder) present(ctx context.Context, client *acme.Client, domain string, challenge *acme.Challenge) (func(), error) {
+	if !acmeTokenPattern.MatchString(challenge.Token) {
+		return nil, fmt.Errorf("invalid HTTP-01 token %q from the ACME server", challenge.Token)
+	}
+	response, err := client.HTTP01ChallengeResponse(challenge.Token)
+	if err != nil {
+		return nil, fmt.Errorf("error computing challenge response: %v", err)
+	}
+	if err := r.publish(challenge.Token, response); err != nil {
+		return nil, err
+	}
+	return func() { r.remove(challenge.Token) }, nil
+}
+
+func (r *http01Responder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	response, ok := r.tokens[strings.TrimPrefix(req.URL.Path, acmeChallengePathPrefix)]
+	r.mu.Unlock()
+	if !ok || !strings.HasPrefix(req.URL.Path, acmeChallengePathPrefix) {
+		http.NotFound(w, req)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(response))
+}
+
+func (r *http01Responder) publish(token, response string) error {
+	if r.webroot == "" {
+		r.mu.Lock()
+		r.tokens[token] = response
+		r.mu.Unlock()
+		return nil
+	}
+	dir := filepath.Join(r.webroot, filepath.FromSlash(acmeChallengePathPrefix))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("error creating challenge directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, token), []byte(response), 0644); err != nil {
+		return fmt.Errorf("error writing challenge file: %v", err)
+	}
+	return nil
+}
+
+func (r *http01Responder) remove(token string) {
+	if r.webroot == "" {
+		r.mu.Lock()
+		delete(r.tokens, token)
+		r.mu.Unlock()
+		return
+	}
+	os.Remove(filepath.Join(r.webroot, filepath.FromSlash(acmeChallengePathPrefix), token))
+}
+
+func (r *http01Responder) close() {
+	if r.server != nil {
+		r.server.Close()
+	}
+}
+
+// saveIssued guarda la clave (PKCS#8, 0600) y la cadena completa en el directorio de salida.
+func saveIssued(opts ACMEOptions, key crypto.Signer, chain []*x509.Certificate) (string, string, error) {
+	name := strings.ReplaceAll(strings.Replace(opts.Domains[0], "*", "wildcard", 1), ".", "_")
+	dir := opts.OutDir
+	if dir == "" {
+		dir = name
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", "", fmt.Errorf("error creating directory: %v", err)
+	}
+
+	keyData, err := EncodePrivateKeyPEM(key, KeyFormatPKCS8, nil)
+	if err != nil {
+		return "", "", err
+	}
+	keyPath := filepath.Join(dir, name+".key")
+	if err := os.WriteFile(keyPath, keyData, 0600); err != nil {
+		return "", "", fmt.Errorf("error writing private key: %v", err)
+	}
+
+	certPath := filepath.Join(dir, name+".crt")
+	if err := os.WriteFile(certPath, EncodeCertificatesPEM(chain), 0644); err != nil {
+		return "", "", fmt.Errorf("error writing certificate: %v", err)
+	}
+	return keyPath, certPath, nil
+}
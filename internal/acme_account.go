@@ -0,0 +1,348 @@
+package internal
+
+import (
+	"context"
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/acme"
+)
+
+// Motivos de revocación que aceptan las CAs ACME (RFC 5280, 5.3.1).
+var ACMERevocationReasons = map[string]acme.CRLReasonCode{
+	"unspecified":          acme.CRLReasonUnspecified,
+	"keyCompromise":        acme.CRLReasonKeyCompromise,
+	"affiliationChanged":   acme.CRLReasonAffiliationChanged,
+	"superseded":           acme.CRLReasonSuperseded,
+	"cessationOfOperation": acme.CRLReasonCessationOfOperation,
+}
+
+// ACMEAccountOptions identifica la cuenta ACME: el directorio y la clave con la que se firma.
+type ACMEAccountOptions struct {
+	DirectoryURL   string
+	AccountKeyPath string
+	KeyType        string   // tipo de la clave si hay que crearla (por defecto ec256)
+	Roots          string   // CA en PEM en la que confiar para el directorio (p. ej. Pebble)
+	Contacts       []string // emails; se les añade "mailto:" si no lo llevan
+	EABKeyID       string   // vinculación de cuenta externa (RFC 8555, 7.3.4) para CAs comerciales
+	EABHMACKey     string   // clave HMAC de la EAB en base64url
+}
+
+// ACMEAccount resume una cuenta registrada.
+type ACMEAccount struct {
+	URL        string
+	Status     string
+	Contacts   []string
+	OrdersURL  string
+	KeyPath    string
+	Key        KeyInfo
+	Thumbprint string // huella JWK (RFC 7638) de la clave de la cuenta
+}
+
+// RegisterACMEAccount registra la cuenta aceptando los términos de servicio, creando la clave si no
+// existe. Si la clave ya tiene cuenta, la devuelve sin cambios e indica created=false.
+func RegisterACMEAccount(ctx context.Context, opts ACMEAccountOptions) (ACMEAccount, bool, error) {
+	client, err := newACMEClient(opts, true)
+	if err != nil {
+		return ACMEAccount{}, false, err
+	}
+	account, created, err := acmeAccount(ctx, client, opts)
+	if err != nil {
+		return ACMEAccount{}, false, err
+	}
+	result, err := describeACMEAccount(client, opts, account)
+	return result, created, err
+}
+
+// GetACMEAccount consulta la cuenta asociada a la clave.
+func GetACMEAccount(ctx context.Context, opts ACMEAccountOptions) (ACMEAccount, error) {
+	client, err := newACMEClient(opts, false)
+	if err != nil {
+		return ACMEAccount{}, err
+	}
+	account, err := client.GetReg(ctx, "")
+	if err != nil {
+		return ACMEAccount{}, acmeAccountError("error fetching ACME account", err)
+	}
+	return describeACMEAccount(client, opts, account)
+}
+
+// UpdateACMEContact sustituye los contactos de la cuenta por opts.Contacts.
+func UpdateACMEContact(ctx context.Context, opts ACMEAccountOptions) (ACMEAccount, error) {
+	if len(opts.Contacts) == 0 {
+		return ACMEAccount{}, errors.New("at least one contact is required")
+	}
+	client, err := newACMEClient(opts, false)
+	if err != nil {
+		return ACMEAccount{}, err
+	}
+	account, err := client.UpdateReg(ctx, &acme.Account{Contact: acmeContacts(opts.Contacts)})
+	if err != nil {
+		return ACMEAccount{}, acmeAccountError("error updating ACME account", err)
+	}
+	return describeACMEAccount(client, opts, account)
+}
+
+// RolloverACMEAccountKey sustituye la clave de la cuenta por una nueva de tipo opts.KeyType. La
+// clave nueva se escribe primero junto a la actual y solo la reemplaza si la CA acepta el cambio;
+// la anterior se conserva con la extensión .old.
+func RolloverACMEAccountKey(ctx context.Context, opts ACMEAccountOptions) (ACMEAccount, error) {
+	client, err := newACMEClient(opts, false)
+	if err != nil {
+		return ACMEAccount{}, err
+	}
+	keyType := opts.KeyType
+	if keyType == "" {
+		keyType = KeyTypeEC256
+	}
+	newKey, err := GeneratePrivateKey(keyType)
+	if err != nil {
+		return ACMEAccount{}, err
+	}
+	keyPath := accountKeyPath(opts)
+	pendingPath := keyPath + ".new"
+	if err := writeAccountKey(pendingPath, newKey); err != nil {
+		return ACMEAccount{}, err
+	}
+
+	if err := client.AccountKeyRollover(ctx, newKey); err != nil {
+		os.Remove(pendingPath)
+		return ACMEAccount{}, acmeAccountError("error rolling over the account key", err)
+	}
+	if err := os.Rename(keyPath, keyPath+".old"); err != nil {
+		return ACMEAccount{}, fmt.Errorf("error keeping the old account key (the new one is in %s): %v", pendingPath, err)
+	}
+	if err := os.Rename(pendingPath, keyPath); err != nil {
+		return ACMEAccount{}, fmt.Errorf("error replacing the account key (the new one is in %s): %v", pendingPath, err)
+	}
+
+	account, err := client.GetReg(ctx, "")
+	if err != nil {
+		return ACMEAccount{}, acmeAccountError("error fetching ACME account", err)
+	}
+	return describeACMEAccount(client, opts, account)
+}
+
+// DeactivateACMEAccount desactiva la cuenta de forma permanente.
+func DeactivateACMEAccount(ctx context.Context, opts ACMEAccountOptions) error {
+	client, err := newACMEClient(opts, false)
+	if err != nil {
+		return err
+	}
+	if err := client.DeactivateReg(ctx); err != nil {
+		return acmeAccountError("error deactivating ACME account", err)
+	}
+	return nil
+}
+
+// RevokeACMECertificate revoca el primer certificado de certPath. Si certKeyPath no está vacío la
+// petición se firma con la clave del certificado y no hace falta cuenta; si no, con la de la cuenta.
+func RevokeACMECertificate(ctx context.Context, opts ACMEAccountOptions, certPath, certKeyPath, reason string) (*x509.Certificate, error) {
+	code, ok := ACMERevocationReasons[reason]
+	if reason == "" {
+		code, ok = acme.CRLReasonUnspecified, true
+	}
+	if !ok {
+		return nil, fmt.Errorf("unsupported revocation reason: %s", reason)
+	}
+	certs, err := LoadCertificates(certPath)
+	if err != nil {
+		return nil, err
+	}
+	cert := certs[0]
+
+	var client *acme.Client
+	var key crypto.Signer
+	if certKeyPath != "" {
+		if key, err = LoadPrivateKey(certKeyPath, nil); err != nil {
+			return nil, err
+		}
+		pub, err := x509.MarshalPKIXPublicKey(key.Public())
+		if err != nil {
+			return nil, fmt.Errorf("error encoding public key: %v", err)
+		}
+		if string(pub) != string(cert.RawSubjectPublicKeyInfo) {
+			return nil, fmt.Errorf("the key %s does not match the certificate %s", certKeyPath, certPath)
+		}
+		client = &acme.Client{DirectoryURL: opts.DirectoryURL, UserAgent: "ssl-tool"}
+		if client.HTTPClient, err = acmeHTTPClient(opts.Roots); err != nil {
+			return nil, err
+		}
+		if client.DirectoryURL == "" {
+			client.DirectoryURL = LetsEncryptDirectory
+		}
+	} else if client, err = newACMEClient(opts, false); err != nil {
+		return nil, err
+	}
+
+	if err := client.RevokeCert(ctx, key, cert.Raw, code); err != nil {
+		return nil, acmeAccountError("error revoking certificate", err)
+	}
+	return cert, nil
+}
+
+// newACMEClient crea el cliente para el directorio indicado, confiando además en opts.Roots. Con
+// create se genera la clave de la cuenta si no existe; si no, su ausencia es un error.
+func newACMEClient(opts ACMEAccountOptions, create bool) (*acme.Client, error) {
+	keyPath := accountKeyPath(opts)
+	var key crypto.Signer
+	var err error
+	if _, statErr := os.Stat(keyPath); statErr == nil {
+		key, err = LoadPrivateKey(keyPath, nil)
+	} else if create {
+		key, err = createAccountKey(keyPath, opts.KeyType)
+	} else {
+		err = fmt.Errorf("account key %s does not exist (run acme account register first)", keyPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	directory := opts.DirectoryURL
+	if directory == "" {
+		directory = LetsEncryptDirectory
+	}
+	client := &acme.Client{Key: key, DirectoryURL: directory, UserAgent: "ssl-tool"}
+	if client.HTTPClient, err = acmeHTTPClient(opts.Roots); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// acmeHTTPClient devuelve un cliente HTTP que confía en las raíces del sistema y en roots, o nil
+// (el cliente por defecto) si roots está vacío.
+func acmeHTTPClient(roots string) (*http.Client, error) {
+	if roots == "" {
+		return nil, nil
+	}
+	certs, err := LoadCertificates(roots)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	for _, root := range certs {
+		pool.AddCert(root)
+	}
+	return &http.Client{Transport: &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: &tls.Config{RootCAs: pool},
+	}}, nil
+}
+
+func accountKeyPath(opts ACMEAccountOptions) string {
+	if opts.AccountKeyPath == "" {
+		return DefaultACMEAccountKey
+	}
+	return opts.AccountKeyPath
+}
+
+// createAccountKey genera la clave de la cuenta y la guarda en PKCS#8 con permisos 0600.
+func createAccountKey(path, keyType string) (crypto.Signer, error) {
+	if keyType == "" {
+		keyType = KeyTypeEC256
+	}
+	key, err := GeneratePrivateKey(keyType)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeAccountKey(path, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func writeAccountKey(path string, key crypto.Signer) error {
+	data, err := EncodePrivateKeyPEM(key, KeyFormatPKCS8, nil)
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return fmt.Errorf("error creating directory: %v", err)
+		}
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("error writing account key: %v", err)
+	}
+	return nil
+}
+
+// acmeAccount registra la cuenta aceptando los términos de servicio o recupera la existente.
+func acmeAccount(ctx context.Context, client *acme.Client, opts ACMEAccountOptions) (*acme.Account, bool, error) {
+	account := &acme.Account{Contact: acmeContacts(opts.Contacts)}
+	if opts.EABKeyID != "" || opts.EABHMACKey != "" {
+		if opts.EABKeyID == "" || opts.EABHMACKey == "" {
+			return nil, false, errors.New("external account binding needs both the key ID and the HMAC key")
+		}
+		hmacKey, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(opts.EABHMACKey, "="))
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid EAB HMAC key (expected base64url): %v", err)
+		}
+		account.ExternalAccountBinding = &acme.ExternalAccountBinding{KID: opts.EABKeyID, Key: hmacKey}
+	}
+
+	registered, err := client.Register(ctx, account, acme.AcceptTOS)
+	if err == nil {
+		return registered, true, nil
+	}
+	if !errors.Is(err, acme.ErrAccountAlreadyExists) {
+		return nil, false, fmt.Errorf("error registering ACME account: %v", err)
+	}
+	existing, err := client.GetReg(ctx, "")
+	if err != nil {
+		return nil, false, fmt.Errorf("error fetching ACME account: %v", err)
+	}
+	return existing, false, nil
+}
+
+func acmeContacts(contacts []string) []string {
+	var result []string
+	for _, contact := range contacts {
+		if contact = strings.TrimSpace(contact); contact == "" {
+			continue
+		}
+		if !strings.Contains(contact, ":") {
+			contact = "mailto:" + contact
+		}
+		result = append(result, contact)
+	}
+	return result
+}
+
+// acmeAccountError añade contexto a los errores de cuenta y explica el caso de la clave sin cuenta.
+func acmeAccountError(message string, err error) error {
+	if errors.Is(err, acme.ErrNoAccount) {
+		return fmt.Errorf("%s: no ACME account is registered for this key", message)
+	}
+	return fmt.Errorf("%s: %v", message, err)
+}
+
+func describeACMEAccount(client *acme.Client, opts ACMEAccountOptions, account *acme.Account) (ACMEAccount, error) {
+	result := ACMEAccount{
+		URL:       account.URI,
+		Status:    account.Status,
+		Contacts:  account.Contact,
+		OrdersURL: account.OrdersURL,
+		KeyPath:   accountKeyPath(opts),
+	}
+	if result.URL == "" {
+		result.URL = string(client.KID)
+	}
+	var err error
+	if result.Key, err = DescribePublicKey(client.Key.Public()); err != nil {
+		return result, err
+	}
+	result.Thumbprint, err = JWKThumbprint(client.Key.Public())
+	return result, err
+}
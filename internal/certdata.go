@@ -0,0 +1,254 @@
+package internal
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Propósitos de confianza que se pueden extraer de certdata.txt.
+const (
+	CertdataPurposeServer = "server"
+	CertdataPurposeEmail  = "email"
+)
+
+// Valores de CK_TRUST de NSS.
+const (
+	CertdataTrustedDelegator = "CKT_NSS_TRUSTED_DELEGATOR"
+	CertdataMustVerify       = "CKT_NSS_MUST_VERIFY_TRUST"
+	CertdataNotTrusted       = "CKT_NSS_NOT_TRUSTED"
+)
+
+// CertdataEntry es un certificado de certdata.txt (NSS) con su confianza para cada propósito.
+type CertdataEntry struct {
+	Label               string
+	Cert                *x509.Certificate
+	OutsidePolicy       bool   // CKA_NSS_MOZILLA_CA_POLICY falso: no forma parte del programa de Mozilla
+	ServerAuth          string // CKA_TRUST_SERVER_AUTH; vacío si no hay objeto de confianza
+	EmailProtection     string // CKA_TRUST_EMAIL_PROTECTION
+	ServerDistrustAfter time.Time
+	EmailDistrustAfter  time.Time
+}
+
+// CertdataSkip es un certificado de certdata.txt que no se incluye en el almacén y el motivo.
+type CertdataSkip struct {
+	Entry  CertdataEntry
+	Reason string
+}
+
+// certdataAttribute es un atributo de un objeto PKCS#11 en texto: tipo y valor ya decodificado.
+type certdataAttribute struct {
+	Type  string
+	Value []byte
+}
+
+// LoadCertdata lee un certdata.txt; ver ParseCertdata.
+func LoadCertdata(path string) ([]CertdataEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading certdata: %v", err)
+	}
+	defer file.Close()
+	return ParseCertdata(file)
+}
+
+// ParseCertdata interpreta el formato certdata.txt de NSS: una lista de objetos PKCS#11 en texto
+// separados por líneas en blanco. Cada certificado (CKO_CERTIFICATE) se asocia a su objeto de
+// confianza (CKO_NSS_TRUST) por la huella SHA-1; los objetos de confianza sin certificado, como
+// los de certificados vetados expresamente, se ignoran.
+func ParseCertdata(r io.Reader) ([]CertdataEntry, error) {
+	objects, err := parseCertdataObjects(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []CertdataEntry
+	byHash := map[[sha1.Size]byte]int{}
+	trusts := map[[sha1.Size]byte]map[string]certdataAttribute{}
+	for _, object := range objects {
+		switch string(object["CKA_CLASS"].Value) {
+		case "CKO_CERTIFICATE":
+			value, ok := object["CKA_VALUE"]
+			if !ok {
+				return nil, fmt.Errorf("certificate %q has no CKA_VALUE", object["CKA_LABEL"].Value)
+			}
+			cert, err := x509.ParseCertificate(value.Value)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing certificate %q: %v", object["CKA_LABEL"].Value, err)
+			}
+			entry := CertdataEntry{
+				Label:         string(object["CKA_LABEL"].Value),
+				Cert:          cert,
+				OutsidePolicy: string(object["CKA_NSS_MOZILLA_CA_POLICY"].Value) == "CK_FALSE",
+			}
+			if entry.ServerDistrustAfter, err = certdataDate(object["CKA_NSS_SERVER_DISTRUST_AFTER"]); err != nil {
+				return nil, fmt.Errorf("certificate %q: %v", entry.Label, err)
+			}
+			if entry.EmailDistrustAfter, err = certdataDate(object["CKA_NSS_EMAIL_DISTRUST_AFTER"]); err != nil {
+				return nil, fmt.Errorf("certificate %q: %v", entry.Label, err)
+			}
+			byHash[sha1.Sum(value.Value)] = len(entries)
+			entries = append(entries, entry)
+		case "CKO_NSS_TRUST":
+			var hash [sha1.Size]byte
+			copy(hash[:], object["CKA_CERT_SHA1_HASH"].Value)
+			trusts[hash] = object
+		}
+	}
+	for hash, trust := range trusts {
+		if i, ok := byHash[hash]; ok {
+			entries[i].ServerAuth = string(trust["CKA_TRUST_SERVER_AUTH"].Value)
+			entries[i].EmailProtection = string(trust["CKA_TRUST_EMAIL_PROTECTION"].Value)
+		}
+	}
+	return entries, nil
+}
+
+// SelectCertdataAnchors devuelve los certificados en los que Mozilla confía como CA para purpose
+// (server o email) y los descartados con su motivo. Los que tienen una fecha de desconfianza
+// anterior a at se descartan salvo keepDistrusted; los demás la conservan en DistrustAfter.
+func SelectCertdataAnchors(entries []CertdataEntry, purpose string, at time.Time, keepDistrusted bool) ([]TrustAnchor, []CertdataSkip, error) {
+	if at.IsZero() {
+		at = time.Now()
+	}
+	var anchors []TrustAnchor
+	var skipped []CertdataSkip
+	for _, entry := range entries {
+		trust, distrustAfter := entry.ServerAuth, entry.ServerDistrustAfter
+		switch purpose {
+		case CertdataPurposeServer:
+		case CertdataPurposeEmail:
+			trust, distrustAfter = entry.EmailProtection, entry.EmailDistrustAfter
+		default:
+			return nil, nil, fmt.Errorf("unsupported purpose: %s (expected %s or %s)", purpose, CertdataPurposeServer, CertdataPurposeEmail)
+		}
+
+		switch {
+		case entry.OutsidePolicy:
+			skipped = append(skipped, CertdataSkip{entry, "outside the Mozilla CA policy"})
+		case trust == "":
+			skipped = append(skipped, CertdataSkip{entry, "no trust object"})
+		case trust != CertdataTrustedDelegator:
+			skipped = append(skipped, CertdataSkip{entry, fmt.Sprintf("not trusted for %s (%s)", purpose, trust)})
+		case !distrustAfter.IsZero() && !distrustAfter.After(at) && !keepDistrusted:
+			skipped = append(skipped, CertdataSkip{entry, "distrusted after " + distrustAfter.UTC().Format(time.DateOnly)})
+		default:
+			anchor := NewTrustAnchor(entry.Cert, entry.Label)
+			anchor.DistrustAfter = distrustAfter
+			anchors = append(anchors, anchor)
+		}
+	}
+	return anchors, skipped, nil
+}
+
+// DiffTrustBundles compara dos almacenes por huella y devuelve los certificados de next que no
+// están en previous y los de previous que ya no están en next.
+func DiffTrustBundles(previous, next []TrustAnchor) (added, removed []TrustAnchor) {
+	inPrevious, inNext := map[string]bool{}, map[string]bool{}
+	for _, anchor := range previous {
+		inPrevious[anchor.Fingerprint] = true
+	}
+	for _, anchor := range next {
+		inNext[anchor.Fingerprint] = true
+		if !inPrevious[anchor.Fingerprint] {
+			added = append(added, anchor)
+			inPrevious[anchor.Fingerprint] = true
+		}
+	}
+	for _, anchor := range previous {
+		if !inNext[anchor.Fingerprint] {
+			removed = append(removed, anchor)
+			inNext[anchor.Fingerprint] = true
+		}
+	}
+	SortTrustBundle(added)
+	SortTrustBundle(removed)
+	return added, removed
+}
+
+// parseCertdataObjects lee los objetos como mapas de atributo a valor. Los valores UTF8 se
+// devuelven sin comillas, los MULTILINE_OCTAL decodificados y el resto tal cual (CK_TRUE, ...).
+func parseCertdataObjects(r io.Reader) ([]map[string]certdataAttribute, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var objects []map[string]certdataAttribute
+	var object map[string]certdataAttribute
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		switch {
+		case text == "":
+			object = nil
+			continue
+		case strings.HasPrefix(text, "#"):
+			continue
+		case strings.HasPrefix(text, "CKA_CLASS "):
+			object = map[string]certdataAttribute{}
+			objects = append(objects, object)
+		case object == nil:
+			// Cabecera del archivo (CVS_ID, BEGINDATA...)
+			continue
+		}
+
+		fields := strings.SplitN(text, " ", 3)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: malformed attribute %q", line, text)
+		}
+		attribute := certdataAttribute{Type: fields[1]}
+		switch fields[1] {
+		case "MULTILINE_OCTAL":
+			for {
+				if !scanner.Scan() {
+					return nil, fmt.Errorf("line %d: unterminated MULTILINE_OCTAL value", line)
+				}
+				line++
+				octal := strings.TrimSpace(scanner.Text())
+				if octal == "END" {
+					break
+				}
+				decoded, err := strconv.Unquote(`"` + octal + `"`)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: invalid octal data", line)
+				}
+				attribute.Value = append(attribute.Value, decoded...)
+			}
+		case "UTF8":
+			if len(fields) < 3 {
+				return nil, fmt.Errorf("line %d: missing UTF8 value", line)
+			}
+			value, err := strconv.Unquote(fields[2])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid UTF8 value %s", line, fields[2])
+			}
+			attribute.Value = []byte(value)
+		default:
+			if len(fields) == 3 {
+				attribute.Value = []byte(fields[2])
+			}
+		}
+		object[fields[0]] = attribute
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading certdata: %v", err)
+	}
+	return objects, nil
+}
+
+// certdataDate interpreta CKA_NSS_*_DISTRUST_AFTER: CK_FALSE o un UTCTime (AAMMDDhhmmssZ).
+func certdataDate(attribute certdataAttribute) (time.Time, error) {
+	if attribute.Type != "MULTILINE_OCTAL" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse("060102150405Z0700", string(attribute.Value))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid distrust-after date %q", attribute.Value)
+	}
+	return t, nil
+}
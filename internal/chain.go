@@ -0,0 +1,173 @@
+package internal
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Propósitos de verificación de una cadena.
+const (
+	PurposeServer = "server"
+	PurposeClient = "client"
+	PurposeAny    = "any"
+)
+
+// ChainOptions agrupa los parámetros de VerifyChain.
+type ChainOptions struct {
+	Intermediates []*x509.Certificate
+	Roots         []*x509.Certificate
+	SystemRoots   bool      // usar el almacén del sistema (también si Roots está vacío)
+	Hostname      string    // nombre que debe cubrir el certificado hoja
+	At            time.Time // instante de la verificación; vacío significa ahora
+	Purpose       string    // server, client o any
+}
+
+// LoadCertificates lee todos los bloques CERTIFICATE de un archivo PEM.
+func LoadCertificates(path string) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %v", err)
+	}
+	return ParseCertificates(data)
+}
+
+// EncodeCertificatesPEM codifica los certificados en bloques CERTIFICATE consecutivos.
+func EncodeCertificatesPEM(certs []*x509.Certificate) []byte {
+	var data []byte
+	for _, cert := range certs {
+		data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})...)
+	}
+	return data
+}
+
+// ParseCertificates decodifica todos los bloques CERTIFICATE de un contenido PEM, ignorando el resto.
+func ParseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing certificate: %v", err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no certificates found")
+	}
+	return certs, nil
+}
+
+// VerifyChain construye y valida todas las rutas desde leaf hasta una raíz de confianza.
+// Si falla, el error explica el motivo concreto (ver ExplainVerifyError).
+func VerifyChain(leaf *x509.Certificate, opts ChainOptions) ([][]*x509.Certificate, error) {
+	verifyOpts := x509.VerifyOptions{
+		DNSName:       opts.Hostname,
+		Intermediates: x509.NewCertPool(),
+		CurrentTime:   opts.At,
+	}
+	for _, cert := range opts.Intermediates {
+		verifyOpts.Intermediates.AddCert(cert)
+	}
+
+	if len(opts.Roots) > 0 {
+		roots := x509.NewCertPool()
+		if opts.SystemRoots {
+			system, err := x509.SystemCertPool()
+			if err != nil {
+				return nil, fmt.Errorf("error loading system roots: %v", err)
+			}
+			roots = system
+		}
+		for _, cert := range opts.Roots {
+			roots.AddCert(cert)
+		}
+		verifyOpts.Roots = roots
+	}
+
+	switch opts.Purpose {
+	case "", PurposeServer:
+		verifyOpts.KeyUsages = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	case PurposeClient:
+		verifyOpts.KeyUsages = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	case PurposeAny:
+		verifyOpts.KeyUsages = []x509.ExtKeyUsage{x509.ExtKeyUsageAny}
+	default:
+		return nil, fmt.Errorf("unsupported purpose: %s", opts.Purpose)
+	}
+
+	chains, err := leaf.Verify(verifyOpts)
+	if err != nil {
+		return nil, errors.New(ExplainVerifyError(err, opts))
+	}
+	return chains, nil
+}
+
+// ExplainVerifyError traduce un error de x509.Certificate.Verify a una explicación concreta.
+func ExplainVerifyError(err error, opts ChainOptions) string {
+	at := opts.At
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	var unknownAuthority x509.UnknownAuthorityError
+	var invalid x509.CertificateInvalidError
+	var hostname x509.HostnameError
+
+	switch {
+	case errors.As(err, &unknownAuthority):
+		if unknownAuthority.Cert != nil {
+			return fmt.Sprintf("unknown authority: no trusted root or provided intermediate issued %q (issuer %q)",
+				unknownAuthority.Cert.Subject.String(), unknownAuthority.Cert.Issuer.String())
+		}
+		return "unknown authority: the chain does not lead to a trusted root"
+
+	case errors.As(err, &invalid):
+		subject := ""
+		if invalid.Cert != nil {
+			subject = invalid.Cert.Subject.String()
+		}
+		switch invalid.Reason {
+		case x509.Expired:
+			if invalid.Cert != nil && at.Before(invalid.Cert.NotBefore) {
+				return fmt.Sprintf("not yet valid link: %q is valid from %s (checked at %s)",
+					subject, invalid.Cert.NotBefore.UTC().Format(time.RFC3339), at.UTC().Format(time.RFC3339))
+			}
+			if invalid.Cert != nil {
+				return fmt.Sprintf("expired link: %q expired on %s (checked at %s)",
+					subject, invalid.Cert.NotAfter.UTC().Format(time.RFC3339), at.UTC().Format(time.RFC3339))
+			}
+			return "expired link: a certificate in the chain is outside its validity period"
+		case x509.CANotAuthorizedForThisName:
+			return fmt.Sprintf("name constraint violation: %s", invalid.Detail)
+		case x509.IncompatibleUsage:
+			purpose := opts.Purpose
+			if purpose == "" {
+				purpose = PurposeServer
+			}
+			return fmt.Sprintf("EKU mismatch: no path allows %s authentication (offending certificate %q)", purpose, subject)
+		case x509.NotAuthorizedToSign:
+			return fmt.Sprintf("invalid issuer: %q is not a CA and cannot sign certificates", subject)
+		case x509.TooManyIntermediates:
+			return fmt.Sprintf("path length constraint violation: %q has too many intermediates below it", subject)
+		default:
+			return fmt.Sprintf("invalid certificate %q: %v", subject, err)
+		}
+
+	case errors.As(err, &hostname):
+		return fmt.Sprintf("hostname mismatch: %v", err)
+
+	default:
+		return err.Error()
+	}
+}
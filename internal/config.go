@@ -16,6 +16,55 @@ type Config struct {
 	DefaultOrganizationalUnit string `yaml:"default_organizational_unit,omitempty"`
 	DefaultEmail             string `yaml:"default_email,omitempty"`             // Correo electrónico
 	DefaultKeySize           int    `yaml:"default_key_size,omitempty"`          // Tamaño de clave
+	DefaultKeyAlgorithm      string   `yaml:"default_key_algorithm,omitempty"`   // Algoritmo de clave (RSA, ECDSA, Ed25519)
+	DefaultSANs              []string `yaml:"default_sans,omitempty"`            // Subject Alternative Names
+	Profiles                 map[string]Config `yaml:"profiles,omitempty"`      // Perfiles con nombre
+	Notify                   *NotifyConfig     `yaml:"notify,omitempty"`        // Avisos de caducidad
+}
+
+// WithProfile devuelve la configuración con los valores del perfil indicado aplicados encima.
+func (c Config) WithProfile(name string) (Config, error) {
+	if name == "" {
+		return c, nil
+	}
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return c, fmt.Errorf("profile %q not found in configuration", name)
+	}
+	merged := c
+	mergeConfig(&merged, profile)
+	return merged, nil
+}
+
+// mergeConfig copia en dst los campos no vacíos de src.
+func mergeConfig(dst *Config, src Config) {
+	if src.DefaultDomain != "" {
+		dst.DefaultDomain = src.DefaultDomain
+	}
+	if src.DefaultCountry != "" {
+		dst.DefaultCountry = src.DefaultCountry
+	}
+	if src.DefaultLocality != "" {
+		dst.DefaultLocality = src.DefaultLocality
+	}
+	if src.DefaultOrganization != "" {
+		dst.DefaultOrganization = src.DefaultOrganization
+	}
+	if src.DefaultOrganizationalUnit != "" {
+		dst.DefaultOrganizationalUnit = src.DefaultOrganizationalUnit
+	}
+	if src.DefaultEmail != "" {
+		dst.DefaultEmail = src.DefaultEmail
+	}
+	if src.DefaultKeySize != 0 {
+		dst.DefaultKeySize = src.DefaultKeySize
+	}
+	if src.DefaultKeyAlgorithm != "" {
+		dst.DefaultKeyAlgorithm = src.DefaultKeyAlgorithm
+	}
+	if len(src.DefaultSANs) > 0 {
+		dst.DefaultSANs = src.DefaultSANs
+	}
 }
 
 // GenerateConfigTemplate genera un archivo de configuración YAML predeterminado.
@@ -28,6 +77,7 @@ func GenerateConfigTemplate(outputPath string) error {
 		DefaultOrganizationalUnit: "IT",
 		DefaultEmail:             "admin@example.com",
 		DefaultKeySize:           2048,
+		DefaultKeyAlgorithm:      "RSA",
 	}
 
 	return saveAsYAML(defaultTemplate, outputPath)
@@ -47,6 +97,7 @@ func saveAsYAML(data interface{}, path string) error {
 	return nil
 }
 
+// LoadConfig lee el archivo de configuración YAML indicado.
 func LoadConfig(path string) (Config, error) {
 	var cfg Config
 	data, err := os.ReadFile(path)
@@ -1,67 +1,158 @@
 package internal
 
 import (
+	"crypto"
 	"crypto/md5"
-	"crypto/sha256"
 	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
 	"encoding/hex"
 	"encoding/pem"
 	"fmt"
+	"net"
 	"os"
 	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
-// ExtractInfo extrae información de un CRT o CSR y la guarda en la estructura de configuración YAML.
-func ExtractInfo(filePath, outputPath string) error {
+// oidEmailAddress es el atributo emailAddress (PKCS#9) que OpenSSL incluye en el Subject.
+var oidEmailAddress = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 1}
+
+// ExtractConfig lee el primer bloque de un CRT o CSR y devuelve sus datos (sujeto, SANs y clave)
+// como valores de configuración.
+func ExtractConfig(filePath string) (Config, error) {
 	data, err := os.ReadFile(filePath)
 	if err != nil {
-		return fmt.Errorf("error reading file: %v", err)
+		return Config{}, fmt.Errorf("error reading file: %v", err)
 	}
 
 	block, _ := pem.Decode(data)
 	if block == nil {
-		return fmt.Errorf("failed to parse PEM block")
+		return Config{}, fmt.Errorf("failed to parse PEM block")
 	}
 
-	config := Config{}
-
 	switch block.Type {
 	case "CERTIFICATE":
 		// Procesar archivo de certificado (CRT)
 		cert, err := x509.ParseCertificate(block.Bytes)
 		if err != nil {
-			return fmt.Errorf("error parsing certificate: %v", err)
+			return Config{}, fmt.Errorf("error parsing certificate: %v", err)
 		}
-		config.DefaultDomain = cert.Subject.CommonName
-		config.DefaultCountry = firstOrEmpty(cert.Subject.Country)
-		config.DefaultLocality = firstOrEmpty(cert.Subject.Locality)
-		config.DefaultOrganization = firstOrEmpty(cert.Subject.Organization)
-		config.DefaultEmail = firstOrEmpty(cert.EmailAddresses)
-		config.DefaultKeySize = 2048 // Valor fijo.
+		return configFromSubject(cert.Subject, cert.DNSNames, cert.IPAddresses, cert.EmailAddresses, cert.PublicKey)
 
 	case "CERTIFICATE REQUEST":
 		// Procesar archivo CSR
 		csr, err := x509.ParseCertificateRequest(block.Bytes)
 		if err != nil {
-			return fmt.Errorf("error parsing CSR: %v", err)
+			return Config{}, fmt.Errorf("error parsing CSR: %v", err)
 		}
-		config.DefaultDomain = csr.Subject.CommonName
-		config.DefaultCountry = firstOrEmpty(csr.Subject.Country)
-		config.DefaultLocality = firstOrEmpty(csr.Subject.Locality)
-		config.DefaultOrganization = firstOrEmpty(csr.Subject.Organization)
-		config.DefaultKeySize = 2048 // Valor fijo.
+		return configFromSubject(csr.Subject, csr.DNSNames, csr.IPAddresses, csr.EmailAddresses, csr.PublicKey)
 
 	default:
-		return fmt.Errorf("unsupported PEM type: %s", block.Type)
+		return Config{}, fmt.Errorf("unsupported PEM type: %s", block.Type)
+	}
+}
+
+// MergeExtractedConfig combina los valores extraídos con el archivo de configuración YAML, creándolo
+// si no existe. Solo se sobrescriben los campos extraídos; si profile no está vacío se guardan en ese perfil.
+// La combinación se hace sobre el árbol YAML, así que se conservan los comentarios y las claves que
+// Config no conoce.
+func MergeExtractedConfig(outputPath, profile string, extracted Config) error {
+	// Partir del archivo existente para no perder el resto de valores
+	var doc yaml.Node
+	data, err := os.ReadFile(outputPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error reading config file: %v", err)
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("error parsing config file: %v", err)
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	target := doc.Content[0]
+	if target.Kind != yaml.MappingNode {
+		return fmt.Errorf("error parsing config file: %s is not a YAML mapping", outputPath)
+	}
+	if profile != "" {
+		target = yamlMapping(yamlMapping(target, "profiles"), profile)
+	}
+
+	// Con omitempty solo se codifican los campos extraídos
+	var fields yaml.Node
+	if err := fields.Encode(extracted); err != nil {
+		return fmt.Errorf("error marshalling to YAML: %v", err)
+	}
+	for i := 0; i+1 < len(fields.Content); i += 2 {
+		setYAMLValue(target, fields.Content[i], fields.Content[i+1])
 	}
 
 	// Guardar la información extraída en YAML
-	if err := saveAsYAML(config, outputPath); err != nil {
-		return err
+	return saveAsYAML(&doc, outputPath)
+}
+
+// yamlMapping devuelve el mapa de la clave key de mapping, creándolo si falta o está vacío.
+func yamlMapping(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			value := mapping.Content[i+1]
+			if value.Kind != yaml.MappingNode {
+				*value = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", LineComment: value.LineComment}
+			}
+			return value
+		}
+	}
+	value := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+	return value
+}
+
+// setYAMLValue fija el valor de una clave de mapping, conservando los comentarios del valor anterior.
+func setYAMLValue(mapping, key, value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key.Value {
+			old := mapping.Content[i+1]
+			value.HeadComment, value.LineComment, value.FootComment = old.HeadComment, old.LineComment, old.FootComment
+			mapping.Content[i+1] = value
+			return
+		}
+	}
+	mapping.Content = append(mapping.Content, key, value)
+}
+
+// configFromSubject construye los valores de configuración a partir del Subject, los SANs y la clave pública.
+func configFromSubject(subject pkix.Name, dnsNames []string, ips []net.IP, emails []string, pub crypto.PublicKey) (Config, error) {
+	keyInfo, err := DescribePublicKey(pub)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config := Config{
+		DefaultDomain:             subject.CommonName,
+		DefaultCountry:            firstOrEmpty(subject.Country),
+		DefaultLocality:           firstOrEmpty(subject.Locality),
+		DefaultOrganization:       firstOrEmpty(subject.Organization),
+		DefaultOrganizationalUnit: firstOrEmpty(subject.OrganizationalUnit),
+		DefaultEmail:              firstOrEmpty(emails),
+		DefaultKeySize:            keyInfo.Size,
+		DefaultKeyAlgorithm:       keyInfo.Algorithm,
+	}
+
+	if config.DefaultEmail == "" {
+		for _, name := range subject.Names {
+			if value, ok := name.Value.(string); ok && name.Type.Equal(oidEmailAddress) {
+				config.DefaultEmail = value
+				break
+			}
+		}
 	}
 
-	fmt.Printf("Information saved to %s\n", outputPath)
-	return nil
+	config.DefaultSANs = append(config.DefaultSANs, dnsNames...)
+	for _, ip := range ips {
+		config.DefaultSANs = append(config.DefaultSANs, ip.String())
+	}
+	return config, nil
 }
 
 // firstOrEmpty devuelve el primer elemento de un slice o una cadena vacía si está vacío
@@ -72,94 +163,86 @@ func firstOrEmpty(values []string) string {
 	return ""
 }
 
-func printCertificateInfo(cert *x509.Certificate) {
-	fmt.Println("Certificate Info:")
-	fmt.Printf("- Common Name: %s\n", cert.Subject.CommonName)
-	fmt.Printf("- Organization: %v\n", cert.Subject.Organization)
-	fmt.Printf("- Locality: %v\n", cert.Subject.Locality)
-	fmt.Printf("- Country: %v\n", cert.Subject.Country)
-	fmt.Printf("- Issuer: %s\n", cert.Issuer.CommonName)
-	fmt.Printf("- Valid From: %s\n", cert.NotBefore)
-	fmt.Printf("- Valid To: %s\n", cert.NotAfter)
+// X509Object es un certificado o un CSR leído de un archivo PEM; solo uno de los dos campos está presente.
+type X509Object struct {
+	Cert *x509.Certificate
+	CSR  *x509.CertificateRequest
 }
 
-func printCSRInfo(csr *x509.CertificateRequest) {
-	fmt.Println("CSR Info:")
-	fmt.Printf("- Common Name: %s\n", csr.Subject.CommonName)
-	fmt.Printf("- Organization: %v\n", csr.Subject.Organization)
-	fmt.Printf("- Locality: %v\n", csr.Subject.Locality)
-	fmt.Printf("- Country: %v\n", csr.Subject.Country)
-}
-
-func DaysUntilExpiration(certFile string) (int, error) {
-	data, err := os.ReadFile(certFile)
+// LoadX509Objects lee todos los certificados y CSRs de un archivo PEM, en orden; ver ParseX509Objects.
+func LoadX509Objects(path string) ([]X509Object, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return 0, fmt.Errorf("error reading certificate: %v", err)
+		return nil, fmt.Errorf("error reading file: %v", err)
 	}
-	block, _ := pem.Decode(data)
-	if block == nil || block.Type != "CERTIFICATE" {
-		return 0, fmt.Errorf("file is not a valid certificate")
-	}
-	cert, err := x509.ParseCertificate(block.Bytes)
-	if err != nil {
-		return 0, fmt.Errorf("error parsing certificate: %v", err)
-	}
-	days := int(time.Until(cert.NotAfter).Hours() / 24)
-	return days, nil
+	return ParseX509Objects(data)
 }
 
-func CertificateFingerprint(certFile string) (string, error) {
-	data, err := os.ReadFile(certFile)
-	if err != nil {
-		return "", fmt.Errorf("error reading certificate: %v", err)
-	}
-	block, _ := pem.Decode(data)
-	if block == nil || block.Type != "CERTIFICATE" {
-		return "", fmt.Errorf("file is not a valid certificate")
-	}
-	cert, err := x509.ParseCertificate(block.Bytes)
-	if err != nil {
-		return "", fmt.Errorf("error parsing certificate: %v", err)
+// ParseX509Objects decodifica los bloques CERTIFICATE y CERTIFICATE REQUEST de un contenido PEM,
+// ignorando el resto. Devuelve una lista vacía si no hay ninguno.
+func ParseX509Objects(data []byte) ([]X509Object, error) {
+	var objects []X509Object
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		switch block.Type {
+		case "CERTIFICATE":
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing certificate: %v", err)
+			}
+			objects = append(objects, X509Object{Cert: cert})
+		case "CERTIFICATE REQUEST", "NEW CERTIFICATE REQUEST":
+			csr, err := x509.ParseCertificateRequest(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing CSR: %v", err)
+			}
+			objects = append(objects, X509Object{CSR: csr})
+		}
 	}
-	hash := sha256.Sum256(cert.Raw)
-	return hex.EncodeToString(hash[:]), nil
+	return objects, nil
 }
 
-// VerifyHashes asume que está implementada según el código original
-func VerifyHashes(keyFile, csrFile, certFile string) error {
-	keyHash, err := calculateModulusHash(keyFile, "RSA PRIVATE KEY")
-	if err != nil {
-		return err
-	}
-	csrHash, err := calculateModulusHash(csrFile, "CERTIFICATE REQUEST")
-	if err != nil {
-		return err
-	}
-	certHash, err := calculateModulusHash(certFile, "CERTIFICATE")
-	if err != nil {
-		return err
+// SubjectAltNames junta los SANs de tipo DNS, IP y email en una sola lista.
+func SubjectAltNames(dnsNames []string, ips []net.IP, emails []string) []string {
+	names := append([]string{}, dnsNames...)
+	for _, ip := range ips {
+		names = append(names, ip.String())
 	}
+	return append(names, emails...)
+}
 
-	if keyHash == csrHash && csrHash == certHash {
-		fmt.Println("Hashes match! The private key, CSR, and certificate are consistent.")
-	} else {
-		fmt.Printf("Hashes do not match:\n- Key: %s\n- CSR: %s\n- Certificate: %s\n", keyHash, csrHash, certHash)
+// DaysUntilExpiration devuelve los días completos que faltan para la caducidad (negativos si ya
+// ha caducado). CheckExpiryFile ofrece el estado completo con precisión de horas.
+func DaysUntilExpiration(certFile string) (int, error) {
+	status, err := CheckExpiryFile(certFile, time.Time{}, 0)
+	if err != nil {
+		return 0, err
 	}
+	return status.Days(), nil
+}
 
-	return nil
+// CertificateFingerprint devuelve el SHA256 en hexadecimal de un certificado.
+func CertificateFingerprint(certFile string) (string, error) {
+	return Fingerprint(certFile, FingerprintOptions{Algorithm: "sha256", Format: FingerprintHex})
 }
 
-func calculateModulusHash(filePath, pemType string) (string, error) {
-	data, err := os.ReadFile(filePath)
+// PublicKeyHash calcula el MD5 de la clave pública (SPKI) de una clave, CSR o certificado, de modo
+// que el resultado no depende del formato (PKCS#1, PKCS#8 o SEC1) de la clave privada. verify-hashes
+// compara el de los tres archivos.
+func PublicKeyHash(filePath string, passphrase []byte) (string, error) {
+	pub, err := LoadPublicKey(filePath, passphrase)
 	if err != nil {
-		return "", fmt.Errorf("error reading file: %v", err)
+		return "", err
 	}
-
-	block, _ := pem.Decode(data)
-	if block == nil || block.Type != pemType {
-		return "", fmt.Errorf("file is not a valid %s", pemType)
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", fmt.Errorf("error encoding public key: %v", err)
 	}
 
-	hash := md5.Sum(block.Bytes)
+	hash := md5.Sum(der)
 	return hex.EncodeToString(hash[:]), nil
-}
\ No newline at end of file
+}
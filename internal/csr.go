@@ -1,11 +1,11 @@
 package internal
 
 import (
+	"crypto"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
-	"encoding/pem"
 	"errors"
 	"fmt"
 	"os"
@@ -25,6 +25,9 @@ func ValidateCSRParams(domain, country, locality, organization string) error {
 	if !matched {
 		return errors.New("country must be alphabetic 2-letter code")
 	}
+	if !IsCountryCode(country) {
+		return fmt.Errorf("country %s is not an ISO 3166-1 alpha-2 code", country)
+	}
 	if strings.TrimSpace(locality) == "" {
 		return errors.New("locality cannot be empty")
 	}
@@ -34,56 +37,71 @@ func ValidateCSRParams(domain, country, locality, organization string) error {
 	return nil
 }
 
-func GenerateCSR(domain, country, locality, organization string) error {
-	dirName := strings.ReplaceAll(domain, ".", "_")
-	if err := os.MkdirAll(dirName, 0755); err != nil {
-		return fmt.Errorf("error creating directory: %v", err)
-	}
+// CSRDirName devuelve el nombre del directorio y de los archivos de la clave y el CSR de un
+// dominio (example.com -> example_com), como los guarda generate-csr.
+func CSRDirName(domain string) string {
+	return strings.ReplaceAll(domain, ".", "_")
+}
 
-	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
-	if err != nil {
-		return fmt.Errorf("error generating private key: %v", err)
-	}
+// CSRFiles son las rutas de la clave privada y el CSR guardados por WriteCSRFiles.
+type CSRFiles struct {
+	KeyPath string
+	CSRPath string
+}
 
-	keyFilePath := filepath.Join(dirName, fmt.Sprintf("%s.key", dirName))
-	keyFile, err := os.Create(keyFilePath)
-	if err != nil {
-		return fmt.Errorf("error creating key file: %v", err)
+// WriteCSRFiles guarda la clave y el CSR, ya en PEM, como dir/name.key (solo legible por el
+// propietario) y dir/name.csr, creando dir si no existe. Los errores envuelven el *fs.PathError
+// de la ruta que no se ha podido crear o escribir.
+func WriteCSRFiles(dir, name string, keyPEM, csrPEM []byte) (CSRFiles, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return CSRFiles{}, fmt.Errorf("error creating directory: %w", err)
 	}
-	defer keyFile.Close()
-
-	if err := pem.Encode(keyFile, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)}); err != nil {
-		return fmt.Errorf("error writing private key: %v", err)
+	files := CSRFiles{
+		KeyPath: filepath.Join(dir, name+".key"),
+		CSRPath: filepath.Join(dir, name+".csr"),
+	}
+	if err := os.WriteFile(files.KeyPath, keyPEM, 0600); err != nil {
+		return CSRFiles{}, fmt.Errorf("error writing private key: %w", err)
 	}
+	if err := os.WriteFile(files.CSRPath, csrPEM, 0644); err != nil {
+		return CSRFiles{}, fmt.Errorf("error writing CSR: %w", err)
+	}
+	return files, nil
+}
 
-	subject := pkix.Name{
-		CommonName:   domain,
-		Country:      []string{country},
-		Locality:     []string{locality},
-		Organization: []string{organization},
+// CSRRequest son los datos del sujeto y los nombres alternativos de un CSR.
+type CSRRequest struct {
+	Domain       string   // Common Name
+	DNSNames     []string // SANs de tipo DNS
+	Country      string
+	Locality     string
+	Organization string
+}
+
+// CreateCSR crea un CSR firmado con key y lo devuelve en DER. Los campos vacíos del sujeto se omiten.
+func CreateCSR(req CSRRequest, key crypto.Signer) ([]byte, error) {
+	subject := pkix.Name{CommonName: req.Domain}
+	if req.Country != "" {
+		subject.Country = []string{req.Country}
+	}
+	if req.Locality != "" {
+		subject.Locality = []string{req.Locality}
+	}
+	if req.Organization != "" {
+		subject.Organization = []string{req.Organization}
 	}
 
 	csrTemplate := &x509.CertificateRequest{
-		Subject:            subject,
-		SignatureAlgorithm: x509.SHA256WithRSA,
+		Subject:  subject,
+		DNSNames: req.DNSNames,
 	}
-
-	csrBytes, err := x509.CreateCertificateRequest(rand.Reader, csrTemplate, privateKey)
-	if err != nil {
-		return fmt.Errorf("error creating CSR: %v", err)
+	if _, ok := key.(*rsa.PrivateKey); ok {
+		csrTemplate.SignatureAlgorithm = x509.SHA256WithRSA
 	}
 
-	csrFilePath := filepath.Join(dirName, fmt.Sprintf("%s.csr", dirName))
-	csrFile, err := os.Create(csrFilePath)
+	csrBytes, err := x509.CreateCertificateRequest(rand.Reader, csrTemplate, key)
 	if err != nil {
-		return fmt.Errorf("error creating CSR file: %v", err)
+		return nil, fmt.Errorf("error creating CSR: %v", err)
 	}
-	defer csrFile.Close()
-
-	if err := pem.Encode(csrFile, &pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrBytes}); err != nil {
-		return fmt.Errorf("error writing CSR: %v", err)
-	}
-
-	fmt.Printf("Files generated successfully:\n- Private Key: %s\n- CSR: %s\n", keyFilePath, csrFilePath)
-	return nil
-}
\ No newline at end of file
+	return csrBytes, nil
+}
@@ -0,0 +1,255 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Valores por defecto de los proveedores DNS-01.
+const (
+	DNSProviderRFC2136           = "rfc2136"
+	DNSProviderExec              = "exec"
+	DefaultTSIGAlgorithm         = "hmac-sha256"
+	DefaultDNSTTL                = 60
+	DefaultDNSPropagationTimeout = 2 * time.Minute
+	DefaultDNSPollingInterval    = 2 * time.Second
+	acmeChallengeLabel           = "_acme-challenge."
+	dnsExchangeTimeout           = 10 * time.Second
+)
+
+// DNSProviders son los proveedores DNS-01 disponibles.
+var DNSProviders = []string{DNSProviderRFC2136, DNSProviderExec}
+
+// DNSProvider publica y retira el registro TXT de un reto DNS-01. fqdn es el nombre completo
+// del registro (_acme-challenge.<dominio>.) y value su contenido.
+type DNSProvider interface {
+	Present(ctx context.Context, fqdn, value string) error
+	CleanUp(ctx context.Context, fqdn, value string) error
+}
+
+// DNS01RecordName devuelve el nombre del registro TXT del reto para un dominio, también comodín.
+func DNS01RecordName(domain string) string {
+	return dns.Fqdn(acmeChallengeLabel + strings.TrimPrefix(domain, "*."))
+}
+
+// RFC2136Provider publica los registros con actualizaciones dinámicas (RFC 2136) firmadas con TSIG.
+type RFC2136Provider struct {
+	Nameserver         string // host[:puerto] del servidor autoritativo que acepta las actualizaciones
+	Zone               string // si está vacía se descubre preguntando por el SOA
+	TSIGKey            string // nombre de la clave; vacío para actualizaciones sin firmar
+	TSIGSecret         string // secreto en base64
+	TSIGAlgorithm      string // hmac-sha1, hmac-sha256 o hmac-sha512
+	TTL                uint32
+	PropagationTimeout time.Duration // 0 usa el valor por defecto; negativo no espera
+	PollingInterval    time.Duration
+}
+
+// Present añade el registro TXT y espera a que el servidor lo devuelva.
+func (p *RFC2136Provider) Present(ctx context.Context, fqdn, value string) error {
+	if err := p.update(ctx, fqdn, value, true); err != nil {
+		return err
+	}
+	return p.waitPropagation(ctx, fqdn, value)
+}
+
+// CleanUp elimina el registro TXT con ese valor, dejando el resto del conjunto.
+func (p *RFC2136Provider) CleanUp(ctx context.Context, fqdn, value string) error {
+	return p.update(ctx, fqdn, value, false)
+}
+
+func (p *RFC2136Provider) update(ctx context.Context, fqdn, value string, insert bool) error {
+	nameserver, err := p.nameserver()
+	if err != nil {
+		return err
+	}
+	zone, err := p.zone(ctx, fqdn)
+	if err != nil {
+		return err
+	}
+	ttl := p.TTL
+	if ttl == 0 {
+		ttl = DefaultDNSTTL
+	}
+	rr := &dns.TXT{
+		Hdr: dns.RR_Header{Name: dns.Fqdn(fqdn), Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: ttl},
+		Txt: []string{value},
+	}
+
+	msg := new(dns.Msg)
+	msg.SetUpdate(zone)
+	if insert {
+		msg.Insert([]dns.RR{rr})
+	} else {
+		msg.Remove([]dns.RR{rr})
+	}
+	client := &dns.Client{Net: "tcp", Timeout: dnsExchangeTimeout}
+	if p.TSIGKey != "" {
+		algorithm, err := tsigAlgorithm(p.TSIGAlgorithm)
+		if err != nil {
+			return err
+		}
+		key := dns.Fqdn(strings.ToLower(p.TSIGKey))
+		client.TsigSecret = map[string]string{key: p.TSIGSecret}
+		msg.SetTsig(key, algorithm, 300, time.Now().Unix())
+	}
+
+	reply, _, err := client.ExchangeContext(ctx, msg, nameserver)
+	if err != nil {
+		return fmt.Errorf("error sending DNS update to %s: %v", nameserver, err)
+	}
+	if reply.Rcode != dns.RcodeSuccess {
+		return fmt.Errorf("DNS update for %s rejected by %s: %s", fqdn, nameserver, dns.RcodeToString[reply.Rcode])
+	}
+	return nil
+}
+
+// zone devuelve la zona configurada o la descubre subiendo por las etiquetas de fqdn hasta
+// encontrar un SOA.
+func (p *RFC2136Provider) zone(ctx context.Context, fqdn string) (string, error) {
+	if p.Zone != "" {
+		return dns.Fqdn(p.Zone), nil
+	}
+	nameserver, err := p.nameserver()
+	if err != nil {
+		return "", err
+	}
+	labels := dns.SplitDomainName(fqdn)
+	for i := range labels {
+		candidate := dns.Fqdn(strings.Join(labels[i:], "."))
+		reply, err := dnsQuery(ctx, nameserver, candidate, dns.TypeSOA)
+		if err != nil {
+			return "", fmt.Errorf("error looking up the zone of %s: %v", fqdn, err)
+		}
+		for _, rr := range append(reply.Answer, reply.Ns...) {
+			if soa, ok := rr.(*dns.SOA); ok {
+				return soa.Hdr.Name, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("could not find the zone of %s (set the zone explicitly)", fqdn)
+}
+
+// waitPropagation consulta el registro en el servidor hasta que devuelve el valor publicado.
+func (p *RFC2136Provider) waitPropagation(ctx context.Context, fqdn, value string) error {
+	timeout := p.PropagationTimeout
+	if timeout < 0 {
+		return nil
+	}
+	if timeout == 0 {
+		timeout = DefaultDNSPropagationTimeout
+	}
+	interval := p.PollingInterval
+	if interval <= 0 {
+		interval = DefaultDNSPollingInterval
+	}
+	nameserver, err := p.nameserver()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for {
+		if found, err := hasTXT(ctx, nameserver, fqdn, value); err == nil && found {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("the TXT record %s was not visible on %s after %s", fqdn, nameserver, timeout)
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (p *RFC2136Provider) nameserver() (string, error) {
+	if p.Nameserver == "" {
+		return "", errors.New("a nameserver is required for RFC 2136 updates")
+	}
+	if _, _, err := net.SplitHostPort(p.Nameserver); err != nil {
+		return net.JoinHostPort(p.Nameserver, "53"), nil
+	}
+	return p.Nameserver, nil
+}
+
+// tsigAlgorithm traduce el nombre corto del algoritmo al de la librería DNS.
+func tsigAlgorithm(name string) (string, error) {
+	switch strings.TrimSuffix(strings.ToLower(name), ".") {
+	case "", "hmac-sha256":
+		return dns.HmacSHA256, nil
+	case "hmac-sha1":
+		return dns.HmacSHA1, nil
+	case "hmac-sha512":
+		return dns.HmacSHA512, nil
+	}
+	return "", fmt.Errorf("unsupported TSIG algorithm: %s (expected hmac-sha1, hmac-sha256 or hmac-sha512)", name)
+}
+
+func dnsQuery(ctx context.Context, nameserver, name string, qtype uint16) (*dns.Msg, error) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(name), qtype)
+	msg.RecursionDesired = false
+	client := &dns.Client{Timeout: dnsExchangeTimeout}
+	reply, _, err := client.ExchangeContext(ctx, msg, nameserver)
+	return reply, err
+}
+
+func hasTXT(ctx context.Context, nameserver, fqdn, value string) (bool, error) {
+	reply, err := dnsQuery(ctx, nameserver, fqdn, dns.TypeTXT)
+	if err != nil {
+		return false, err
+	}
+	for _, rr := range reply.Answer {
+		if txt, ok := rr.(*dns.TXT); ok && strings.Join(txt.Txt, "") == value {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ExecProvider delega en un script propio, llamado como "<command> present|cleanup <fqdn> <value>".
+// Los mismos datos se pasan en SSL_TOOL_DNS_ACTION, SSL_TOOL_DNS_FQDN, SSL_TOOL_DNS_DOMAIN y
+// SSL_TOOL_DNS_VALUE. El script debe terminar cuando el registro sea visible.
+type ExecProvider struct {
+	Command string
+}
+
+// Present ejecuta el script con la acción present.
+func (p *ExecProvider) Present(ctx context.Context, fqdn, value string) error {
+	return p.run(ctx, "present", fqdn, value)
+}
+
+// CleanUp ejecuta el script con la acción cleanup.
+func (p *ExecProvider) CleanUp(ctx context.Context, fqdn, value string) error {
+	return p.run(ctx, "cleanup", fqdn, value)
+}
+
+func (p *ExecProvider) run(ctx context.Context, action, fqdn, value string) error {
+	if p.Command == "" {
+		return errors.New("a command is required for the exec DNS provider")
+	}
+	domain := strings.TrimSuffix(strings.TrimPrefix(fqdn, acmeChallengeLabel), ".")
+	cmd := exec.CommandContext(ctx, p.Command, action, fqdn, value)
+	cmd.Env = append(os.Environ(),
+		"SSL_TOOL_DNS_ACTION="+action,
+		"SSL_TOOL_DNS_FQDN="+fqdn,
+		"SSL_TOOL_DNS_DOMAIN="+domain,
+		"SSL_TOOL_DNS_VALUE="+value,
+	)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		message := strings.TrimSpace(string(output))
+		if message == "" {
+			return fmt.Errorf("error running %s %s: %v", p.Command, action, err)
+		}
+		return fmt.Errorf("error running %s %s: %v: %s", p.Command, action, err, message)
+	}
+	return nil
+}
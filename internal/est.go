@@ -0,0 +1,235 @@
+package internal
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/smallstep/pkcs7"
+)
+
+// Valores por defecto del cliente EST.
+const (
+	DefaultESTTimeout = 30 * time.Second
+	estPathPrefix     = "/.well-known/est"
+	estMaxResponse    = 1 << 20
+)
+
+// ESTOptions describe el servidor EST (RFC 7030) y la autenticación del cliente.
+type ESTOptions struct {
+	ServerURL  string // https://host[:puerto]; se le añade /.well-known/est
+	Label      string // etiqueta de la CA en el servidor (RFC 7030, 3.2.2), opcional
+	Roots      string // CA en PEM para verificar el servidor; vacío usa las del sistema
+	Username   string // autenticación HTTP basic
+	Password   string
+	ClientCert string // certificado y clave para autenticarse con TLS
+	ClientKey  string
+	Timeout    time.Duration
+}
+
+// ESTPendingError indica que el servidor aceptó la petición pero la emisión está pendiente de
+// aprobación (HTTP 202); hay que repetirla pasado RetryAfter.
+type ESTPendingError struct {
+	RetryAfter string
+}
+
+func (e *ESTPendingError) Error() string {
+	if e.RetryAfter == "" {
+		return "the EST server accepted the request but the certificate is pending approval; retry later"
+	}
+	return fmt.Sprintf("the EST server accepted the request but the certificate is pending approval; retry after %s seconds", e.RetryAfter)
+}
+
+// ESTCACerts descarga los certificados de CA que publica el servidor (/cacerts).
+func ESTCACerts(opts ESTOptions) ([]*x509.Certificate, error) {
+	client, err := newESTClient(opts)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodGet, estURL(opts, "cacerts"), nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid EST server URL: %v", err)
+	}
+	certs, err := doESTRequest(client, opts, req)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching CA certificates: %v", err)
+	}
+	return certs, nil
+}
+
+// ESTEnroll envía un CSR en DER a /simpleenroll, o a /simplereenroll si reenroll, y devuelve el
+// certificado emitido seguido del resto de certificados de la respuesta.
+func ESTEnroll(opts ESTOptions, csrDER []byte, reenroll bool) ([]*x509.Certificate, error) {
+	csr, err := x509.ParseCertificateRequest(csrDER)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing CSR: %v", err)
+	}
+	client, err := newESTClient(opts)
+	if err != nil {
+		return nil, err
+	}
+	operation := "simpleenroll"
+	if reenroll {
+		operation = "simplereenroll"
+	}
+
+	body := base64.StdEncoding.EncodeToString(csrDER)
+	req, err := http.NewRequest(http.MethodPost, estURL(opts, operation), strings.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("invalid EST server URL: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/pkcs10")
+	req.Header.Set("Content-Transfer-Encoding", "base64")
+	certs, err := doESTRequest(client, opts, req)
+	if err != nil {
+		var pending *ESTPendingError
+		if errors.As(err, &pending) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("error enrolling: %v", err)
+	}
+
+	// El certificado emitido es el que lleva la clave del CSR; se coloca el primero
+	for i, cert := range certs {
+		if bytes.Equal(cert.RawSubjectPublicKeyInfo, csr.RawSubjectPublicKeyInfo) {
+			ordered := append([]*x509.Certificate{cert}, certs[:i]...)
+			return append(ordered, certs[i+1:]...), nil
+		}
+	}
+	return nil, errors.New("the EST response does not contain a certificate for the CSR key")
+}
+
+// ESTReenrollCSR crea el CSR de renovación: mismo sujeto y SANs que cert, firmado con key.
+func ESTReenrollCSR(cert *x509.Certificate, key crypto.Signer) ([]byte, error) {
+	template := &x509.CertificateRequest{
+		Subject:        cert.Subject,
+		DNSNames:       cert.DNSNames,
+		EmailAddresses: cert.EmailAddresses,
+		IPAddresses:    cert.IPAddresses,
+		URIs:           cert.URIs,
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	if err != nil {
+		return nil, fmt.Errorf("error creating CSR: %v", err)
+	}
+	return csr, nil
+}
+
+func estURL(opts ESTOptions, operation string) string {
+	url := strings.TrimRight(opts.ServerURL, "/")
+	if !strings.HasSuffix(url, estPathPrefix) {
+		url += estPathPrefix
+	}
+	if opts.Label != "" {
+		url += "/" + opts.Label
+	}
+	return url + "/" + operation
+}
+
+func newESTClient(opts ESTOptions) (*http.Client, error) {
+	if opts.ServerURL == "" {
+		return nil, errors.New("an EST server URL is required")
+	}
+	tlsConfig := &tls.Config{}
+	if opts.Roots != "" {
+		roots, err := LoadCertificates(opts.Roots)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		for _, root := range roots {
+			tlsConfig.RootCAs.AddCert(root)
+		}
+	}
+	if opts.ClientCert != "" || opts.ClientKey != "" {
+		if opts.ClientCert == "" || opts.ClientKey == "" {
+			return nil, errors.New("TLS client authentication needs both the certificate and the key")
+		}
+		certs, err := LoadCertificates(opts.ClientCert)
+		if err != nil {
+			return nil, err
+		}
+		if len(certs) == 0 {
+			return nil, fmt.Errorf("no certificates found in %s", opts.ClientCert)
+		}
+		key, err := LoadPrivateKey(opts.ClientKey, nil)
+		if err != nil {
+			return nil, err
+		}
+		clientCert := tls.Certificate{PrivateKey: key, Leaf: certs[0]}
+		for _, cert := range certs {
+			clientCert.Certificate = append(clientCert.Certificate, cert.Raw)
+		}
+		tlsConfig.Certificates = []tls.Certificate{clientCert}
+	}
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultESTTimeout
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		},
+	}, nil
+}
+
+// doESTRequest envía la petición y decodifica la respuesta PKCS#7 (certs-only) en base64.
+func doESTRequest(client *http.Client, opts ESTOptions, req *http.Request) ([]*x509.Certificate, error) {
+	if opts.Username != "" {
+		req.SetBasicAuth(opts.Username, opts.Password)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, estMaxResponse))
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %v", err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusAccepted:
+		return nil, &ESTPendingError{RetryAfter: resp.Header.Get("Retry-After")}
+	case http.StatusUnauthorized:
+		return nil, errors.New("the EST server requires authentication (401 Unauthorized)")
+	default:
+		message := strings.TrimSpace(string(body))
+		if len(message) > 200 {
+			message = message[:200] + "..."
+		}
+		return nil, fmt.Errorf("unexpected status %s: %s", resp.Status, message)
+	}
+	return ParsePKCS7Certificates(body)
+}
+
+// ParsePKCS7Certificates decodifica un PKCS#7 certs-only en DER o en base64, como los que
+// devuelven EST y SCEP.
+func ParsePKCS7Certificates(data []byte) ([]*x509.Certificate, error) {
+	der := data
+	compact := strings.Join(strings.Fields(string(data)), "")
+	if decoded, err := base64.StdEncoding.DecodeString(compact); err == nil {
+		der = decoded
+	}
+	p7, err := pkcs7.Parse(der)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing PKCS#7 response: %v", err)
+	}
+	if len(p7.Certificates) == 0 {
+		return nil, errors.New("the PKCS#7 response contains no certificates")
+	}
+	return p7.Certificates, nil
+}
@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"crypto/x509"
+	"fmt"
+	"time"
+)
+
+// Estados de validez de un certificado.
+const (
+	ExpiryValid       = "valid"
+	ExpiryExpiring    = "expiring"
+	ExpiryExpired     = "expired"
+	ExpiryNotYetValid = "not-yet-valid"
+)
+
+// ExpiryStatus describe la validez de un certificado en un instante dado.
+type ExpiryStatus struct {
+	State     string
+	At        time.Time // instante en el que se evalúa
+	NotBefore time.Time
+	NotAfter  time.Time
+	Remaining time.Duration // hasta NotAfter; negativo si ya ha caducado
+}
+
+// CheckExpiry evalúa un certificado en el instante at (ahora si está vacío). Se considera
+// "expiring" si le queda warn o menos.
+func CheckExpiry(cert *x509.Certificate, at time.Time, warn time.Duration) ExpiryStatus {
+	if at.IsZero() {
+		at = time.Now()
+	}
+	status := ExpiryStatus{
+		At:        at,
+		NotBefore: cert.NotBefore,
+		NotAfter:  cert.NotAfter,
+		Remaining: cert.NotAfter.Sub(at),
+	}
+
+	switch {
+	case at.Before(cert.NotBefore):
+		status.State = ExpiryNotYetValid
+	case !at.Before(cert.NotAfter):
+		status.State = ExpiryExpired
+	case status.Remaining <= warn:
+		status.State = ExpiryExpiring
+	default:
+		status.State = ExpiryValid
+	}
+	return status
+}
+
+// CheckExpiryFile lee un certificado y evalúa su validez (ver CheckExpiry).
+func CheckExpiryFile(certFile string, at time.Time, warn time.Duration) (ExpiryStatus, error) {
+	certs, err := LoadCertificates(certFile)
+	if err != nil {
+		return ExpiryStatus{}, fmt.Errorf("file is not a valid certificate: %v", err)
+	}
+	return CheckExpiry(certs[0], at, warn), nil
+}
+
+// Usable indica si el certificado es válido en el instante evaluado.
+func (s ExpiryStatus) Usable() bool {
+	return s.State == ExpiryValid || s.State == ExpiryExpiring
+}
+
+// Days devuelve los días completos restantes (negativos si ya ha caducado).
+func (s ExpiryStatus) Days() int {
+	return int(s.Remaining / (24 * time.Hour))
+}
+
+// Describe devuelve una frase que deja claro el estado, por ejemplo "expires in 19h30m".
+func (s ExpiryStatus) Describe() string {
+	switch s.State {
+	case ExpiryNotYetValid:
+		return fmt.Sprintf("is not yet valid: valid from %s (in %s)", s.NotBefore.UTC().Format(time.RFC3339), HumanDuration(s.NotBefore.Sub(s.At)))
+	case ExpiryExpired:
+		return fmt.Sprintf("has EXPIRED: expired %s ago on %s", HumanDuration(-s.Remaining), s.NotAfter.UTC().Format(time.RFC3339))
+	default:
+		return fmt.Sprintf("expires in %s on %s", HumanDuration(s.Remaining), s.NotAfter.UTC().Format(time.RFC3339))
+	}
+}
+
+// HumanDuration formatea una duración positiva en días y horas, u horas y minutos si es menor de un día.
+func HumanDuration(d time.Duration) string {
+	if d < 0 {
+		d = -d
+	}
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+
+	switch {
+	case days >= 2:
+		return fmt.Sprintf("%d days", days)
+	case days == 1:
+		return fmt.Sprintf("1 day %dh", hours)
+	default:
+		return fmt.Sprintf("%dh%02dm", hours, minutes)
+	}
+}
@@ -0,0 +1,109 @@
+package internal
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Formatos de salida de una huella digital.
+const (
+	FingerprintHex    = "hex"
+	FingerprintColon  = "colon"
+	FingerprintBase64 = "base64"
+)
+
+// FingerprintOptions controla el algoritmo, el formato y el contenido sobre el que se calcula la huella.
+type FingerprintOptions struct {
+	Algorithm  string // sha1, sha256, sha384 o sha512
+	Format     string // hex, colon o base64
+	SPKI       bool   // huella de la SubjectPublicKeyInfo en lugar del certificado completo
+	Passphrase []byte // para claves privadas cifradas
+}
+
+// Fingerprint calcula la huella de un certificado o, con SPKI, la de la clave pública de un
+// certificado, CSR o clave.
+func Fingerprint(path string, opts FingerprintOptions) (string, error) {
+	var data []byte
+	if opts.SPKI {
+		pub, err := LoadPublicKey(path, opts.Passphrase)
+		if err != nil {
+			return "", err
+		}
+		data, err = x509.MarshalPKIXPublicKey(pub)
+		if err != nil {
+			return "", fmt.Errorf("error encoding public key: %v", err)
+		}
+	} else {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("error reading certificate: %v", err)
+		}
+		block, _ := pem.Decode(raw)
+		if block == nil {
+			return "", fmt.Errorf("failed to parse PEM block")
+		}
+		if block.Type != "CERTIFICATE" {
+			return "", fmt.Errorf("%s is a %s: only SPKI fingerprints are available, use --spki", path, strings.ToLower(block.Type))
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return "", fmt.Errorf("error parsing certificate: %v", err)
+		}
+		return FingerprintCertificate(cert, opts)
+	}
+
+	return FormatDigest(data, opts.Algorithm, opts.Format)
+}
+
+// FingerprintCertificate calcula la huella de un certificado ya leído o, con SPKI, la de su clave pública.
+func FingerprintCertificate(cert *x509.Certificate, opts FingerprintOptions) (string, error) {
+	data := cert.Raw
+	if opts.SPKI {
+		data = cert.RawSubjectPublicKeyInfo
+	}
+	return FormatDigest(data, opts.Algorithm, opts.Format)
+}
+
+// FormatDigest calcula el resumen de data con el algoritmo dado y lo representa en el formato pedido.
+func FormatDigest(data []byte, algorithm, format string) (string, error) {
+	var sum []byte
+	switch strings.ToLower(algorithm) {
+	case "sha1":
+		s := sha1.Sum(data)
+		sum = s[:]
+	case "", "sha256":
+		s := sha256.Sum256(data)
+		sum = s[:]
+	case "sha384":
+		s := sha512.Sum384(data)
+		sum = s[:]
+	case "sha512":
+		s := sha512.Sum512(data)
+		sum = s[:]
+	default:
+		return "", fmt.Errorf("unsupported fingerprint algorithm: %s", algorithm)
+	}
+
+	switch format {
+	case "", FingerprintHex:
+		return hex.EncodeToString(sum), nil
+	case FingerprintColon:
+		parts := make([]string, len(sum))
+		for i, b := range sum {
+			parts[i] = fmt.Sprintf("%02X", b)
+		}
+		return strings.Join(parts, ":"), nil
+	case FingerprintBase64:
+		return base64.StdEncoding.EncodeToString(sum), nil
+	default:
+		return "", fmt.Errorf("unsupported fingerprint format: %s", format)
+	}
+}
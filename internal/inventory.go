@@ -0,0 +1,251 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// DefaultInventoryPath es la base de datos del inventario si no se indica otra.
+const DefaultInventoryPath = "ssl-tool-inventory.db"
+
+// Tipos de objeto que guarda el inventario.
+const (
+	InventoryCertificate = "certificate"
+	InventoryCSR         = "csr"
+)
+
+var inventoryBucket = []byte("certificates")
+
+// InventoryEntry es un certificado (o CSR) registrado en el inventario.
+type InventoryEntry struct {
+	Fingerprint string    `json:"fingerprint"` // SHA256 del DER en hexadecimal; es la clave del registro
+	Kind        string    `json:"kind"`
+	Subject     string    `json:"subject"`
+	CommonName  string    `json:"common_name"`
+	SANs        []string  `json:"sans,omitempty"`
+	Issuer      string    `json:"issuer,omitempty"`
+	Serial      string    `json:"serial,omitempty"`
+	NotBefore   time.Time `json:"not_before,omitempty"`
+	NotAfter    time.Time `json:"not_after,omitempty"`
+	Sources     []string  `json:"sources"` // rutas o endpoints (tls://host:port) donde se ha visto
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+	Owner       string    `json:"owner,omitempty"`
+	DER         []byte    `json:"der,omitempty"` // para reconstruir el certificado en las consultas (--where)
+}
+
+// Inventory es la base de datos bbolt del inventario.
+type Inventory struct {
+	db *bolt.DB
+}
+
+// OpenInventory abre (o crea) la base de datos del inventario.
+func OpenInventory(path string) (*Inventory, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("error opening inventory %s: %v", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(inventoryBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error initialising inventory: %v", err)
+	}
+	return &Inventory{db: db}, nil
+}
+
+// Close cierra la base de datos.
+func (inv *Inventory) Close() error {
+	return inv.db.Close()
+}
+
+// AddCertificate registra un certificado visto en source. Devuelve el registro y si es nuevo.
+// Si owner no está vacío, sustituye al propietario anterior.
+func (inv *Inventory) AddCertificate(cert *x509.Certificate, source, owner string) (InventoryEntry, bool, error) {
+	entry := InventoryEntry{
+		Kind:       InventoryCertificate,
+		Subject:    cert.Subject.String(),
+		CommonName: cert.Subject.CommonName,
+		SANs:       SubjectAltNames(cert.DNSNames, cert.IPAddresses, cert.EmailAddresses),
+		Issuer:     cert.Issuer.String(),
+		Serial:     cert.SerialNumber.Text(16),
+		NotBefore:  cert.NotBefore,
+		NotAfter:   cert.NotAfter,
+		DER:        cert.Raw,
+	}
+	return inv.upsert(cert.Raw, entry, source, owner)
+}
+
+// AddCSR registra un CSR generado o encontrado en source.
+func (inv *Inventory) AddCSR(csr *x509.CertificateRequest, source, owner string) (InventoryEntry, bool, error) {
+	entry := InventoryEntry{
+		Kind:       InventoryCSR,
+		Subject:    csr.Subject.String(),
+		CommonName: csr.Subject.CommonName,
+		SANs:       SubjectAltNames(csr.DNSNames, csr.IPAddresses, csr.EmailAddresses),
+		DER:        csr.Raw,
+	}
+	return inv.upsert(csr.Raw, entry, source, owner)
+}
+
+// AddFile registra todos los certificados o el CSR de un archivo PEM.
+func (inv *Inventory) AddFile(path, owner string) ([]InventoryEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %v", err)
+	}
+	if block, _ := pem.Decode(data); block != nil && block.Type == "CERTIFICATE REQUEST" {
+		csr, err := x509.ParseCertificateRequest(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing CSR: %v", err)
+		}
+		entry, _, err := inv.AddCSR(csr, path, owner)
+		return []InventoryEntry{entry}, err
+	}
+
+	certs, err := ParseCertificates(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	var entries []InventoryEntry
+	for _, cert := range certs {
+		entry, _, err := inv.AddCertificate(cert, path, owner)
+		if err != nil {
+			return entries, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// upsert crea el registro o actualiza su última aparición y sus orígenes.
+func (inv *Inventory) upsert(der []byte, entry InventoryEntry, source, owner string) (InventoryEntry, bool, error) {
+	sum := sha256.Sum256(der)
+	entry.Fingerprint = hex.EncodeToString(sum[:])
+	now := time.Now().UTC()
+	created := false
+
+	err := inv.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(inventoryBucket)
+		if data := bucket.Get([]byte(entry.Fingerprint)); data != nil {
+			var existing InventoryEntry
+			if err := json.Unmarshal(data, &existing); err != nil {
+				return fmt.Errorf("corrupt inventory entry %s: %v", entry.Fingerprint, err)
+			}
+			entry.FirstSeen = existing.FirstSeen
+			entry.Sources = existing.Sources
+			entry.Owner = existing.Owner
+		} else {
+			entry.FirstSeen = now
+			created = true
+		}
+		entry.LastSeen = now
+		if source != "" && !strings.Contains(source, "://") {
+			if abs, err := filepath.Abs(source); err == nil {
+				source = abs
+			}
+		}
+		if source != "" && !slices.Contains(entry.Sources, source) {
+			entry.Sources = append(entry.Sources, source)
+		}
+		if owner != "" {
+			entry.Owner = owner
+		}
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(entry.Fingerprint), data)
+	})
+	if err != nil {
+		return entry, false, fmt.Errorf("error updating inventory: %v", err)
+	}
+	return entry, created, nil
+}
+
+// List devuelve todos los registros ordenados por fecha de caducidad (los CSR al final).
+func (inv *Inventory) List() ([]InventoryEntry, error) {
+	var entries []InventoryEntry
+	err := inv.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(inventoryBucket).ForEach(func(_, data []byte) error {
+			var entry InventoryEntry
+			if err := json.Unmarshal(data, &entry); err != nil {
+				return err
+			}
+			entries = append(entries, entry)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading inventory: %v", err)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.NotAfter.IsZero() != b.NotAfter.IsZero() {
+			return !a.NotAfter.IsZero()
+		}
+		return a.NotAfter.Before(b.NotAfter)
+	})
+	return entries, nil
+}
+
+// Get busca un registro por su huella o por un prefijo único de ella (admite separadores ":").
+func (inv *Inventory) Get(id string) (InventoryEntry, error) {
+	prefix := strings.ToLower(strings.ReplaceAll(id, ":", ""))
+	if prefix == "" {
+		return InventoryEntry{}, errors.New("fingerprint cannot be empty")
+	}
+	var matches []InventoryEntry
+	err := inv.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(inventoryBucket).Cursor()
+		for key, data := cursor.Seek([]byte(prefix)); key != nil && strings.HasPrefix(string(key), prefix); key, data = cursor.Next() {
+			var entry InventoryEntry
+			if err := json.Unmarshal(data, &entry); err != nil {
+				return err
+			}
+			matches = append(matches, entry)
+		}
+		return nil
+	})
+	if err != nil {
+		return InventoryEntry{}, fmt.Errorf("error reading inventory: %v", err)
+	}
+	switch len(matches) {
+	case 0:
+		return InventoryEntry{}, fmt.Errorf("no inventory entry matches %s", id)
+	case 1:
+		return matches[0], nil
+	default:
+		return InventoryEntry{}, fmt.Errorf("%s matches %d inventory entries; use a longer fingerprint", id, len(matches))
+	}
+}
+
+// Remove borra el registro indicado por su huella o un prefijo único.
+func (inv *Inventory) Remove(id string) (InventoryEntry, error) {
+	entry, err := inv.Get(id)
+	if err != nil {
+		return entry, err
+	}
+	err = inv.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(inventoryBucket).Delete([]byte(entry.Fingerprint))
+	})
+	if err != nil {
+		return entry, fmt.Errorf("error updating inventory: %v", err)
+	}
+	return entry, nil
+}
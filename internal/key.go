@@ -0,0 +1,536 @@
+package internal
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+	"os"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/ssh"
+)
+
+// Formatos de clave privada soportados.
+const (
+	KeyFormatPKCS1 = "pkcs1"
+	KeyFormatPKCS8 = "pkcs8"
+	KeyFormatSEC1  = "sec1"
+)
+
+// Formatos de clave pública soportados.
+const (
+	PublicKeyFormatPEM     = "pem"
+	PublicKeyFormatOpenSSH = "openssh"
+	PublicKeyFormatJWK     = "jwk"
+)
+
+// Tipos de clave que se pueden generar.
+const (
+	KeyTypeRSA2048 = "rsa2048"
+	KeyTypeRSA4096 = "rsa4096"
+	KeyTypeEC256   = "ec256"
+	KeyTypeEC384   = "ec384"
+)
+
+// KeyTypes enumera los tipos de clave que acepta GeneratePrivateKey.
+var KeyTypes = []string{KeyTypeRSA2048, KeyTypeRSA4096, KeyTypeEC256, KeyTypeEC384}
+
+// pbkdf2Iterations es el número de iteraciones usado al cifrar claves PKCS#8.
+const pbkdf2Iterations = 600000
+
+var (
+	oidPBES2          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidAES128CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	oidDESEDE3CBC     = asn1.ObjectIdentifier{1, 2, 840, 113549, 3, 7}
+)
+
+// KeyInfo resume el algoritmo, tamaño y huella SPKI de una clave.
+type KeyInfo struct {
+	Algorithm       string
+	Size            int
+	Curve           string
+	SPKIFingerprint string
+}
+
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// LoadPrivateKey lee una clave privada PEM en cualquiera de los formatos soportados.
+func LoadPrivateKey(path string, passphrase []byte) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading key file: %v", err)
+	}
+	return ParsePrivateKeyPEM(data, passphrase)
+}
+
+// ParsePrivateKeyPEM decodifica una clave PKCS#1, PKCS#8 o SEC1, cifrada o no.
+func ParsePrivateKeyPEM(data, passphrase []byte) (crypto.Signer, error) {
+	block := firstKeyBlock(data)
+	if block == nil {
+		return nil, fmt.Errorf("failed to parse PEM block")
+	}
+
+	der := block.Bytes
+	switch {
+	case block.Type == "ENCRYPTED PRIVATE KEY":
+		if len(passphrase) == 0 {
+			return nil, errors.New("key is encrypted: a passphrase is required")
+		}
+		decrypted, err := decryptPKCS8(der, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		der = decrypted
+	case x509.IsEncryptedPEMBlock(block):
+		// Cifrado PEM heredado ("Proc-Type: 4,ENCRYPTED"), aún habitual en claves de OpenSSL.
+		if len(passphrase) == 0 {
+			return nil, errors.New("key is encrypted: a passphrase is required")
+		}
+		decrypted, err := x509.DecryptPEMBlock(block, passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("error decrypting key: %v", err)
+		}
+		der = decrypted
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(der)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(der)
+	case "PRIVATE KEY", "ENCRYPTED PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(der)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing PKCS#8 key: %v", err)
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported private key type %T", key)
+		}
+		return signer, nil
+	default:
+		return nil, fmt.Errorf("unsupported PEM type: %s", block.Type)
+	}
+}
+
+// firstKeyBlock devuelve el primer bloque PEM ignorando los "EC PARAMETERS" que añade OpenSSL.
+func firstKeyBlock(data []byte) *pem.Block {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil || block.Type != "EC PARAMETERS" {
+			return block
+		}
+	}
+}
+
+// GeneratePrivateKey genera una clave nueva del tipo indicado (rsa2048, rsa4096, ec256 o ec384).
+func GeneratePrivateKey(keyType string) (crypto.Signer, error) {
+	var key crypto.Signer
+	var err error
+	switch keyType {
+	case KeyTypeRSA2048:
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	case KeyTypeRSA4096:
+		key, err = rsa.GenerateKey(rand.Reader, 4096)
+	case KeyTypeEC256:
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case KeyTypeEC384:
+		key, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported key type: %s (expected rsa2048, rsa4096, ec256 or ec384)", keyType)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error generating private key: %v", err)
+	}
+	return key, nil
+}
+
+// EncodePrivateKeyPEM serializa una clave en el formato indicado, cifrándola si hay passphrase.
+func EncodePrivateKeyPEM(key crypto.Signer, format string, passphrase []byte) ([]byte, error) {
+	var block *pem.Block
+	switch format {
+	case KeyFormatPKCS1:
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("PKCS#1 only supports RSA keys")
+		}
+		block = &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}
+	case KeyFormatSEC1:
+		ecKey, ok := key.(*ecdsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("SEC1 only supports EC keys")
+		}
+		der, err := x509.MarshalECPrivateKey(ecKey)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding EC key: %v", err)
+		}
+		block = &pem.Block{Type: "EC PRIVATE KEY", Bytes: der}
+	case KeyFormatPKCS8:
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding PKCS#8 key: %v", err)
+		}
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	default:
+		return nil, fmt.Errorf("unsupported key format: %s", format)
+	}
+
+	if len(passphrase) > 0 {
+		if format != KeyFormatPKCS8 {
+			return nil, errors.New("encryption is only supported for PKCS#8 output")
+		}
+		der, err := encryptPKCS8(block.Bytes, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		block = &pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: der}
+	}
+	return pem.EncodeToMemory(block), nil
+}
+
+// ConvertPrivateKey convierte una clave privada entre PKCS#1, PKCS#8 y SEC1.
+func ConvertPrivateKey(inPath, outPath, format string, passin, passout []byte) error {
+	key, err := LoadPrivateKey(inPath, passin)
+	if err != nil {
+		return err
+	}
+	data, err := EncodePrivateKeyPEM(key, format, passout)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(outPath, data, 0600); err != nil {
+		return fmt.Errorf("error writing key file: %v", err)
+	}
+	return nil
+}
+
+// LoadPublicKey obtiene la clave pública de un certificado, CSR, clave pública o clave privada.
+func LoadPublicKey(path string, passphrase []byte) (crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %v", err)
+	}
+	return ParsePublicKeyPEM(data, passphrase)
+}
+
+// ParsePublicKeyPEM obtiene la clave pública del primer bloque PEM de un certificado, CSR, clave
+// pública o clave privada.
+func ParsePublicKeyPEM(data, passphrase []byte) (crypto.PublicKey, error) {
+	block := firstKeyBlock(data)
+	if block == nil {
+		return nil, fmt.Errorf("failed to parse PEM block")
+	}
+
+	switch block.Type {
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing certificate: %v", err)
+		}
+		return cert.PublicKey, nil
+	case "CERTIFICATE REQUEST":
+		csr, err := x509.ParseCertificateRequest(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing CSR: %v", err)
+		}
+		return csr.PublicKey, nil
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		key, err := ParsePrivateKeyPEM(data, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		return key.Public(), nil
+	}
+}
+
+// EncodePublicKey serializa una clave pública como PEM PKIX, authorized_keys de OpenSSH o JWK.
+func EncodePublicKey(pub crypto.PublicKey, format string) ([]byte, error) {
+	switch format {
+	case PublicKeyFormatPEM:
+		der, err := x509.MarshalPKIXPublicKey(pub)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding public key: %v", err)
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+	case PublicKeyFormatOpenSSH:
+		sshKey, err := ssh.NewPublicKey(pub)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding OpenSSH key: %v", err)
+		}
+		return ssh.MarshalAuthorizedKey(sshKey), nil
+	case PublicKeyFormatJWK:
+		jwk, err := publicKeyJWK(pub)
+		if err != nil {
+			return nil, err
+		}
+		thumbprint, err := JWKThumbprint(pub)
+		if err != nil {
+			return nil, err
+		}
+		jwk["kid"] = thumbprint
+		data, err := json.MarshalIndent(jwk, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("error encoding JWK: %v", err)
+		}
+		return append(data, '\n'), nil
+	default:
+		return nil, fmt.Errorf("unsupported public key format: %s", format)
+	}
+}
+
+// JWKThumbprint calcula la huella RFC 7638 de una clave pública.
+func JWKThumbprint(pub crypto.PublicKey) (string, error) {
+	jwk, err := publicKeyJWK(pub)
+	if err != nil {
+		return "", err
+	}
+	// encoding/json ordena las claves del mapa, que es justo la forma canónica del RFC.
+	data, err := json.Marshal(jwk)
+	if err != nil {
+		return "", fmt.Errorf("error encoding JWK: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// publicKeyJWK devuelve los miembros obligatorios del JWK de una clave pública.
+func publicKeyJWK(pub crypto.PublicKey) (map[string]string, error) {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return map[string]string{
+			"kty": "RSA",
+			"n":   base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+		}, nil
+	case *ecdsa.PublicKey:
+		ecdhKey, err := k.ECDH()
+		if err != nil {
+			return nil, fmt.Errorf("unsupported EC key: %v", err)
+		}
+		// Formato sin comprimir: 0x04 || X || Y
+		point := ecdhKey.Bytes()[1:]
+		size := len(point) / 2
+		return map[string]string{
+			"kty": "EC",
+			"crv": k.Curve.Params().Name,
+			"x":   base64.RawURLEncoding.EncodeToString(point[:size]),
+			"y":   base64.RawURLEncoding.EncodeToString(point[size:]),
+		}, nil
+	case ed25519.PublicKey:
+		return map[string]string{
+			"kty": "OKP",
+			"crv": "Ed25519",
+			"x":   base64.RawURLEncoding.EncodeToString(k),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", pub)
+	}
+}
+
+// DescribePublicKey devuelve el algoritmo, tamaño o curva y la huella SPKI de una clave.
+func DescribePublicKey(pub crypto.PublicKey) (KeyInfo, error) {
+	var info KeyInfo
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		info.Algorithm = "RSA"
+		info.Size = k.N.BitLen()
+	case *ecdsa.PublicKey:
+		info.Algorithm = "ECDSA"
+		info.Size = k.Curve.Params().BitSize
+		info.Curve = k.Curve.Params().Name
+	case ed25519.PublicKey:
+		info.Algorithm = "Ed25519"
+		info.Size = 256
+		info.Curve = "Ed25519"
+	default:
+		return info, fmt.Errorf("unsupported public key type %T", pub)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return info, fmt.Errorf("error encoding public key: %v", err)
+	}
+	sum := sha256.Sum256(der)
+	info.SPKIFingerprint = hex.EncodeToString(sum[:])
+	return info, nil
+}
+
+// encryptPKCS8 cifra una clave PKCS#8 con PBES2 (PBKDF2-HMAC-SHA256 y AES-256-CBC).
+func encryptPKCS8(der, passphrase []byte) ([]byte, error) {
+	salt := make([]byte, 16)
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("error generating salt: %v", err)
+	}
+	if _, err := rand.Read(iv); err != nil {
+		return nil, fmt.Errorf("error generating IV: %v", err)
+	}
+
+	key := pbkdf2.Key(passphrase, salt, pbkdf2Iterations, 32, sha256.New)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	padded := pkcs7Pad(der, aes.BlockSize)
+	encrypted := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(encrypted, padded)
+
+	kdfParams, err := asn1.Marshal(pbkdf2Params{
+		Salt:           salt,
+		IterationCount: pbkdf2Iterations,
+		PRF:            pkix.AlgorithmIdentifier{Algorithm: oidHMACWithSHA256, Parameters: asn1.NullRawValue},
+	})
+	if err != nil {
+		return nil, err
+	}
+	ivParams, err := asn1.Marshal(iv)
+	if err != nil {
+		return nil, err
+	}
+	schemeParams, err := asn1.Marshal(pbes2Params{
+		KeyDerivationFunc: pkix.AlgorithmIdentifier{Algorithm: oidPBKDF2, Parameters: asn1.RawValue{FullBytes: kdfParams}},
+		EncryptionScheme:  pkix.AlgorithmIdentifier{Algorithm: oidAES256CBC, Parameters: asn1.RawValue{FullBytes: ivParams}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(encryptedPrivateKeyInfo{
+		Algorithm:     pkix.AlgorithmIdentifier{Algorithm: oidPBES2, Parameters: asn1.RawValue{FullBytes: schemeParams}},
+		EncryptedData: encrypted,
+	})
+}
+
+// decryptPKCS8 descifra un EncryptedPrivateKeyInfo protegido con PBES2.
+func decryptPKCS8(der, passphrase []byte) ([]byte, error) {
+	var info encryptedPrivateKeyInfo
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, fmt.Errorf("error parsing encrypted key: %v", err)
+	}
+	if !info.Algorithm.Algorithm.Equal(oidPBES2) {
+		return nil, fmt.Errorf("unsupported key encryption algorithm: %v", info.Algorithm.Algorithm)
+	}
+
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, fmt.Errorf("error parsing PBES2 parameters: %v", err)
+	}
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, fmt.Errorf("unsupported key derivation function: %v", params.KeyDerivationFunc.Algorithm)
+	}
+	var kdf pbkdf2Params
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdf); err != nil {
+		return nil, fmt.Errorf("error parsing PBKDF2 parameters: %v", err)
+	}
+
+	var prf func() hash.Hash
+	switch {
+	case len(kdf.PRF.Algorithm) == 0, kdf.PRF.Algorithm.Equal(oidHMACWithSHA1):
+		prf = sha1.New
+	case kdf.PRF.Algorithm.Equal(oidHMACWithSHA256):
+		prf = sha256.New
+	default:
+		return nil, fmt.Errorf("unsupported PBKDF2 PRF: %v", kdf.PRF.Algorithm)
+	}
+
+	var keyLen int
+	var newCipher func([]byte) (cipher.Block, error)
+	switch scheme := params.EncryptionScheme.Algorithm; {
+	case scheme.Equal(oidAES128CBC):
+		keyLen, newCipher = 16, aes.NewCipher
+	case scheme.Equal(oidAES192CBC):
+		keyLen, newCipher = 24, aes.NewCipher
+	case scheme.Equal(oidAES256CBC):
+		keyLen, newCipher = 32, aes.NewCipher
+	case scheme.Equal(oidDESEDE3CBC):
+		keyLen, newCipher = 24, des.NewTripleDESCipher
+	default:
+		return nil, fmt.Errorf("unsupported encryption scheme: %v", scheme)
+	}
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, fmt.Errorf("error parsing IV: %v", err)
+	}
+
+	key := pbkdf2.Key(passphrase, kdf.Salt, kdf.IterationCount, keyLen, prf)
+	block, err := newCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != block.BlockSize() || len(info.EncryptedData)%block.BlockSize() != 0 {
+		return nil, errors.New("malformed encrypted key")
+	}
+	decrypted := make([]byte, len(info.EncryptedData))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(decrypted, info.EncryptedData)
+	decrypted, err = pkcs7Unpad(decrypted, block.BlockSize())
+	if err != nil {
+		return nil, errors.New("error decrypting key: incorrect passphrase")
+	}
+	return decrypted, nil
+}
+
+func pkcs7Pad(data []byte, blockSize int) []byte {
+	padding := blockSize - len(data)%blockSize
+	return append(append([]byte{}, data...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+}
+
+func pkcs7Unpad(data []byte, blockSize int) ([]byte, error) {
+	if len(data) == 0 || len(data)%blockSize != 0 {
+		return nil, errors.New("invalid padding")
+	}
+	padding := int(data[len(data)-1])
+	if padding == 0 || padding > blockSize {
+		return nil, errors.New("invalid padding")
+	}
+	for _, b := range data[len(data)-padding:] {
+		if int(b) != padding {
+			return nil, errors.New("invalid padding")
+		}
+	}
+	return data[:len(data)-padding], nil
+}
@@ -0,0 +1,324 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Gravedad de los hallazgos de lint, de mayor a menor.
+const (
+	LintError  = "error"  // incumple un MUST de RFC 5280 o de los Baseline Requirements del CA/Browser Forum
+	LintWarn   = "warn"   // incumple un SHOULD o lo rechazarán muchos clientes o CAs
+	LintNotice = "notice" // informativo
+)
+
+// LintSeverities enumera las gravedades de mayor a menor.
+var LintSeverities = []string{LintError, LintWarn, LintNotice}
+
+// lintMaxValidity es la validez máxima de un certificado TLS de servidor según los Baseline Requirements.
+const lintMaxValidity = 398 * 24 * time.Hour
+
+// iso3166Countries son los códigos ISO 3166-1 alfa-2 asignados, más XX, que los Baseline
+// Requirements permiten cuando el país no tiene código oficial.
+var iso3166Countries = strings.Fields(`
+	AD AE AF AG AI AL AM AO AQ AR AS AT AU AW AX AZ BA BB BD BE BF BG BH BI BJ BL BM BN BO BQ BR BS BT BV BW BY BZ
+	CA CC CD CF CG CH CI CK CL CM CN CO CR CU CV CW CX CY CZ DE DJ DK DM DO DZ EC EE EG EH ER ES ET FI FJ FK FM FO
+	FR GA GB GD GE GF GG GH GI GL GM GN GP GQ GR GS GT GU GW GY HK HM HN HR HT HU ID IE IL IM IN IO IQ IR IS IT JE
+	JM JO JP KE KG KH KI KM KN KP KR KW KY KZ LA LB LC LI LK LR LS LT LU LV LY MA MC MD ME MF MG MH MK ML MM MN MO
+	MP MQ MR MS MT MU MV MW MX MY MZ NA NC NE NF NG NI NL NO NP NR NU NZ OM PA PE PF PG PH PK PL PM PN PR PS PT PW
+	PY QA RE RO RS RU RW SA SB SC SD SE SG SH SI SJ SK SL SM SN SO SR SS ST SV SX SY SZ TC TD TF TG TH TJ TK TL TM
+	TN TO TR TT TV TW TZ UA UG UM US UY UZ VA VC VE VG VI VN VU WF WS YE YT ZA ZM ZW XX`)
+
+var oidBasicConstraints = asn1.ObjectIdentifier{2, 5, 29, 19}
+
+// LintFinding es un problema encontrado por LintCertificate o LintCSR.
+type LintFinding struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// LintResult son los hallazgos de un certificado o CSR de un archivo.
+type LintResult struct {
+	Source   string        `json:"source"`
+	Index    int           `json:"index"` // posición dentro del archivo, desde 0
+	Kind     string        `json:"kind"`  // InventoryCertificate o InventoryCSR
+	Subject  string        `json:"subject"`
+	Findings []LintFinding `json:"findings"`
+}
+
+// IsCountryCode indica si code es un código de país ISO 3166-1 alfa-2 (en mayúsculas o minúsculas).
+func IsCountryCode(code string) bool {
+	code = strings.ToUpper(code)
+	for _, c := range iso3166Countries {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// LintFile revisa todos los certificados y CSRs de un archivo PEM o de un único objeto DER.
+func LintFile(path string) ([]LintResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %v", err)
+	}
+	return LintData(data, path)
+}
+
+// LintData revisa los certificados y CSRs de data; source solo se usa para identificar los resultados.
+func LintData(data []byte, source string) ([]LintResult, error) {
+	var objects []X509Object
+	if bytes.Contains(data, []byte("-----BEGIN ")) {
+		var err error
+		if objects, err = ParseX509Objects(data); err != nil {
+			return nil, err
+		}
+	} else if cert, err := x509.ParseCertificate(data); err == nil {
+		// DER: un certificado o, si no lo es, un CSR
+		objects = []X509Object{{Cert: cert}}
+	} else if csr, err := x509.ParseCertificateRequest(data); err == nil {
+		objects = []X509Object{{CSR: csr}}
+	} else {
+		return nil, fmt.Errorf("not a PEM file or a DER certificate or CSR: %v", err)
+	}
+	if len(objects) == 0 {
+		return nil, errors.New("no certificates or CSRs found")
+	}
+
+	results := make([]LintResult, len(objects))
+	for i, object := range objects {
+		results[i] = LintResult{Source: source, Index: i}
+		if object.Cert != nil {
+			results[i].Kind, results[i].Subject, results[i].Findings = InventoryCertificate, object.Cert.Subject.String(), LintCertificate(object.Cert)
+		} else {
+			results[i].Kind, results[i].Subject, results[i].Findings = InventoryCSR, object.CSR.Subject.String(), LintCSR(object.CSR)
+		}
+	}
+	return results, nil
+}
+
+// LintCertificate comprueba cert con un subconjunto de las reglas de RFC 5280 y de los Baseline
+// Requirements. Las reglas de certificados finales se aplican a los que no son CA.
+func LintCertificate(cert *x509.Certificate) []LintFinding {
+	var findings []LintFinding
+	add := func(rule, severity, format string, args ...interface{}) {
+		findings = append(findings, LintFinding{Rule: rule, Severity: severity, Message: fmt.Sprintf(format, args...)})
+	}
+	selfSigned := isSelfSigned(cert)
+
+	// Una raíz firmada con SHA-1 no es un problema: la firma de las raíces no se comprueba
+	if weakSignatureAlgorithm(cert.SignatureAlgorithm) {
+		if selfSigned && cert.IsCA {
+			add("weak_signature", LintNotice, "self-signed with %s; clients do not check the signature of a trusted root", cert.SignatureAlgorithm)
+		} else {
+			add("weak_signature", LintError, "signed with %s; SHA-1 and older digests are not accepted", cert.SignatureAlgorithm)
+		}
+	}
+	findings = append(findings, lintPublicKey(cert.PublicKey)...)
+	findings = append(findings, lintSubject(cert.Subject, cert.DNSNames, !cert.IsCA)...)
+
+	if !cert.IsCA {
+		if validity := cert.NotAfter.Sub(cert.NotBefore) + time.Second; validity > lintMaxValidity && usableForServerAuth(cert) {
+			add("validity_too_long", LintError, "validity of %d days exceeds the 398 days allowed for TLS server certificates", int(validity.Hours()/24))
+		}
+		if len(cert.DNSNames)+len(cert.IPAddresses)+len(cert.EmailAddresses)+len(cert.URIs) == 0 {
+			add("missing_san", LintError, "no subjectAltName extension; clients ignore the Common Name")
+		} else if finding, ok := lintCommonNameInSAN(cert.Subject.CommonName, cert.DNSNames, cert.IPAddresses, LintError); ok {
+			findings = append(findings, finding)
+		}
+	}
+
+	// RFC 5280 4.2.1.1 y 4.2.1.2
+	if len(cert.AuthorityKeyId) == 0 && !selfSigned {
+		add("missing_aki", LintError, "no authorityKeyIdentifier extension; it is required in every certificate that is not self-signed")
+	}
+	if len(cert.SubjectKeyId) == 0 {
+		if cert.IsCA {
+			add("missing_ski", LintError, "no subjectKeyIdentifier extension; it is required in CA certificates")
+		} else {
+			add("missing_ski", LintNotice, "no subjectKeyIdentifier extension (RFC 5280 recommends it for end-entity certificates)")
+		}
+	}
+
+	// RFC 5280 4.2.1.3 y 4.2.1.9
+	switch {
+	case cert.IsCA:
+		for _, ext := range cert.Extensions {
+			if ext.Id.Equal(oidBasicConstraints) && !ext.Critical {
+				add("basic_constraints_not_critical", LintError, "basicConstraints must be critical in CA certificates")
+			}
+		}
+		if cert.KeyUsage&x509.KeyUsageCertSign == 0 {
+			add("ca_without_cert_sign", LintError, "CA certificate without the keyCertSign key usage")
+		}
+		if len(cert.DNSNames) > 0 && hasExtKeyUsage(cert, x509.ExtKeyUsageServerAuth) {
+			add("ca_as_leaf", LintWarn, "server certificate with basicConstraints cA=TRUE; many clients reject a CA as an end-entity certificate")
+		}
+	case cert.KeyUsage&x509.KeyUsageCertSign != 0:
+		add("leaf_cert_sign", LintError, "keyCertSign key usage without basicConstraints cA=TRUE")
+	case cert.BasicConstraintsValid && (cert.MaxPathLen > 0 || cert.MaxPathLenZero):
+		add("leaf_path_length", LintError, "basicConstraints pathLenConstraint in a certificate with cA=FALSE")
+	}
+	return sortLintFindings(findings)
+}
+
+// LintCSR comprueba un CSR antes de enviarlo a una CA. Las CAs suelen copiar el Common Name a los
+// SANs, así que su ausencia solo es un aviso.
+func LintCSR(csr *x509.CertificateRequest) []LintFinding {
+	var findings []LintFinding
+	if err := csr.CheckSignature(); err != nil {
+		findings = append(findings, LintFinding{"invalid_signature", LintError, fmt.Sprintf("invalid CSR signature: %v", err)})
+	}
+	if weakSignatureAlgorithm(csr.SignatureAlgorithm) {
+		findings = append(findings, LintFinding{"weak_signature", LintError, fmt.Sprintf("signed with %s; most CAs reject it", csr.SignatureAlgorithm)})
+	}
+	findings = append(findings, lintPublicKey(csr.PublicKey)...)
+	findings = append(findings, lintSubject(csr.Subject, csr.DNSNames, true)...)
+	if len(csr.DNSNames)+len(csr.IPAddresses)+len(csr.EmailAddresses)+len(csr.URIs) == 0 {
+		findings = append(findings, LintFinding{"missing_san", LintWarn, "no subjectAltName; the CA has to add the Common Name as a SAN"})
+	} else if finding, ok := lintCommonNameInSAN(csr.Subject.CommonName, csr.DNSNames, csr.IPAddresses, LintWarn); ok {
+		findings = append(findings, finding)
+	}
+	return sortLintFindings(findings)
+}
+
+// LintCount cuenta los hallazgos de cada gravedad.
+func LintCount(results []LintResult) map[string]int {
+	counts := map[string]int{}
+	for _, r := range results {
+		for _, f := range r.Findings {
+			counts[f.Severity]++
+		}
+	}
+	return counts
+}
+
+// LintSeverityAtLeast indica si severity es igual o más grave que min.
+func LintSeverityAtLeast(severity, min string) bool {
+	for _, s := range LintSeverities {
+		if s == severity {
+			return true
+		}
+		if s == min {
+			return false
+		}
+	}
+	return false
+}
+
+// lintPublicKey señala claves RSA de menos de 2048 bits.
+func lintPublicKey(pub interface{}) []LintFinding {
+	if key, ok := pub.(*rsa.PublicKey); ok && key.N.BitLen() < 2048 {
+		return []LintFinding{{"weak_key", LintError, fmt.Sprintf("RSA key of %d bits (minimum 2048)", key.N.BitLen())}}
+	}
+	return nil
+}
+
+// lintSubject revisa el país del sujeto y los guiones bajos en los nombres DNS y, si hostCN, en el
+// Common Name.
+func lintSubject(subject pkix.Name, dnsNames []string, hostCN bool) []LintFinding {
+	var findings []LintFinding
+	for _, country := range subject.Country {
+		switch {
+		case !IsCountryCode(country):
+			findings = append(findings, LintFinding{"invalid_country", LintError, fmt.Sprintf("country %q is not an ISO 3166-1 alpha-2 code", country)})
+		case country != strings.ToUpper(country):
+			findings = append(findings, LintFinding{"invalid_country", LintWarn, fmt.Sprintf("country %q should be upper case", country)})
+		}
+	}
+	names := dnsNames
+	if cn := subject.CommonName; hostCN && cn != "" && net.ParseIP(cn) == nil && !strings.Contains(cn, " ") {
+		names = append([]string{cn}, names...)
+	}
+	seen := map[string]bool{}
+	for _, dnsName := range names {
+		if strings.Contains(dnsName, "_") && !seen[dnsName] {
+			seen[dnsName] = true
+			findings = append(findings, LintFinding{"underscore_in_dns_name", LintError, fmt.Sprintf("DNS name %q contains an underscore", dnsName)})
+		}
+	}
+	return findings
+}
+
+// lintCommonNameInSAN comprueba que el Common Name, si lo hay, figure entre los SANs.
+func lintCommonNameInSAN(cn string, dnsNames []string, ips []net.IP, severity string) (LintFinding, bool) {
+	if cn == "" {
+		return LintFinding{}, false
+	}
+	for _, name := range dnsNames {
+		if strings.EqualFold(name, cn) {
+			return LintFinding{}, false
+		}
+	}
+	if ip := net.ParseIP(cn); ip != nil {
+		for _, candidate := range ips {
+			if candidate.Equal(ip) {
+				return LintFinding{}, false
+			}
+		}
+	}
+	return LintFinding{"cn_not_in_san", severity, fmt.Sprintf("Common Name %q is not among the subjectAltNames", cn)}, true
+}
+
+// isSelfSigned indica si cert está firmado con su propia clave. Las firmas SHA-1, que Go ya no
+// comprueba, se dan por buenas si el emisor coincide con el sujeto.
+func isSelfSigned(cert *x509.Certificate) bool {
+	if !bytes.Equal(cert.RawSubject, cert.RawIssuer) {
+		return false
+	}
+	err := cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature)
+	var insecure x509.InsecureAlgorithmError
+	return err == nil || errors.As(err, &insecure)
+}
+
+// weakSignatureAlgorithm indica si el algoritmo usa SHA-1 o un resumen anterior.
+func weakSignatureAlgorithm(algorithm x509.SignatureAlgorithm) bool {
+	switch algorithm {
+	case x509.MD2WithRSA, x509.MD5WithRSA, x509.SHA1WithRSA, x509.DSAWithSHA1, x509.ECDSAWithSHA1:
+		return true
+	}
+	return false
+}
+
+// usableForServerAuth indica si el certificado sirve para autenticar servidores TLS: con
+// serverAuth o anyExtendedKeyUsage, o sin extendedKeyUsage.
+func usableForServerAuth(cert *x509.Certificate) bool {
+	return len(cert.ExtKeyUsage) == 0 && len(cert.UnknownExtKeyUsage) == 0 ||
+		hasExtKeyUsage(cert, x509.ExtKeyUsageServerAuth) || hasExtKeyUsage(cert, x509.ExtKeyUsageAny)
+}
+
+func hasExtKeyUsage(cert *x509.Certificate, usage x509.ExtKeyUsage) bool {
+	for _, u := range cert.ExtKeyUsage {
+		if u == usage {
+			return true
+		}
+	}
+	return false
+}
+
+// sortLintFindings ordena por gravedad y después por regla.
+func sortLintFindings(findings []LintFinding) []LintFinding {
+	rank := map[string]int{}
+	for i, s := range LintSeverities {
+		rank[s] = i
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Severity != findings[j].Severity {
+			return rank[findings[i].Severity] < rank[findings[j].Severity]
+		}
+		return findings[i].Rule < findings[j].Rule
+	})
+	return findings
+}
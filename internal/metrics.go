@@ -0,0 +1,318 @@
+package internal
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultMetricsInterval es cada cuánto se vuelven a revisar los objetivos si el archivo no lo indica.
+const DefaultMetricsInterval = 5 * time.Minute
+
+// MetricsTargets es el contenido del archivo de objetivos de serve-metrics.
+type MetricsTargets struct {
+	Interval time.Duration   `yaml:"interval,omitempty"` // cada cuánto se repite la revisión
+	Roots    string          `yaml:"roots,omitempty"`    // PEM con raíces de confianza adicionales para ssl_verify_ok
+	Targets  []MetricsTarget `yaml:"targets"`
+}
+
+// MetricsTarget es un archivo, un directorio o un endpoint remoto.
+type MetricsTarget struct {
+	File      string        `yaml:"file,omitempty"`
+	Dir       string        `yaml:"dir,omitempty"`
+	Recursive bool          `yaml:"recursive,omitempty"`
+	Include   []string      `yaml:"include,omitempty"`
+	Remote    string        `yaml:"remote,omitempty"`
+	SNI       string        `yaml:"sni,omitempty"`
+	StartTLS  string        `yaml:"starttls,omitempty"`
+	Timeout   time.Duration `yaml:"timeout,omitempty"`
+	Roots     string        `yaml:"roots,omitempty"` // sustituye a las raíces globales para este objetivo
+}
+
+// Name devuelve el identificador del objetivo usado en las métricas de errores.
+func (t MetricsTarget) Name() string {
+	switch {
+	case t.Remote != "":
+		return t.Remote
+	case t.Dir != "":
+		return t.Dir
+	default:
+		return t.File
+	}
+}
+
+// LoadMetricsTargets lee y valida el archivo de objetivos.
+func LoadMetricsTargets(path string) (MetricsTargets, error) {
+	var targets MetricsTargets
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return targets, fmt.Errorf("error reading targets file: %v", err)
+	}
+	if err := yaml.Unmarshal(data, &targets); err != nil {
+		return targets, fmt.Errorf("error parsing targets file: %v", err)
+	}
+	if len(targets.Targets) == 0 {
+		return targets, errors.New("targets file does not define any target")
+	}
+	for i, target := range targets.Targets {
+		set := 0
+		for _, value := range []string{target.File, target.Dir, target.Remote} {
+			if value != "" {
+				set++
+			}
+		}
+		if set != 1 {
+			return targets, fmt.Errorf("target %d must define exactly one of file, dir or remote", i+1)
+		}
+	}
+	if targets.Interval <= 0 {
+		targets.Interval = DefaultMetricsInterval
+	}
+	return targets, nil
+}
+
+// certMetric es una serie por certificado encontrado en la última revisión.
+type certMetric struct {
+	path, endpoint string
+	index          int
+	cert           *x509.Certificate
+	verified       *bool // solo para el primer certificado de cada archivo o endpoint
+}
+
+// MetricsExporter mantiene el resultado de la última revisión y lo expone en el formato de texto de
+// Prometheus. Los contadores de errores se acumulan entre revisiones.
+type MetricsExporter struct {
+	targets MetricsTargets
+
+	mu       sync.RWMutex
+	certs    []certMetric
+	errors   map[string]float64
+	lastScan time.Time
+}
+
+// NewMetricsExporter crea un exportador para los objetivos indicados.
+func NewMetricsExporter(targets MetricsTargets) *MetricsExporter {
+	e := &MetricsExporter{targets: targets, errors: map[string]float64{}}
+	for _, target := range targets.Targets {
+		e.errors[target.Name()] = 0
+	}
+	return e
+}
+
+// Collect revisa todos los objetivos y sustituye las series de la revisión anterior. Devuelve los
+// errores encontrados para que quien lo llame pueda registrarlos.
+func (e *MetricsExporter) Collect() []error {
+	var certs []certMetric
+	var errs []error
+	failed := map[string]float64{}
+
+	for _, target := range e.targets.Targets {
+		roots, err := e.targetRoots(target)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", target.Name(), err))
+			failed[target.Name()]++
+			continue
+		}
+
+		var found []certMetric
+		if target.Remote != "" {
+			found, err = collectRemote(target, roots)
+		} else {
+			found, err = collectFiles(target, roots)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", target.Name(), err))
+			failed[target.Name()]++
+		}
+		certs = append(certs, found...)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.certs = certs
+	for name, count := range failed {
+		e.errors[name] += count
+	}
+	e.lastScan = time.Now()
+	return errs
+}
+
+// targetRoots carga las raíces de confianza del objetivo o, si no tiene, las globales.
+func (e *MetricsExporter) targetRoots(target MetricsTarget) ([]*x509.Certificate, error) {
+	path := target.Roots
+	if path == "" {
+		path = e.targets.Roots
+	}
+	if path == "" {
+		return nil, nil
+	}
+	return LoadCertificates(path)
+}
+
+// collectFiles lee los certificados de un archivo o directorio con el mismo código que check-expiration.
+func collectFiles(target MetricsTarget, roots []*x509.Certificate) ([]certMetric, error) {
+	root := target.File
+	if target.Dir != "" {
+		root = target.Dir
+	}
+	results, err := ScanCertificates(ScanOptions{Root: root, Recursive: target.Recursive, Include: target.Include})
+	if err != nil {
+		return nil, err
+	}
+
+	// Agrupa por archivo para verificar el primer certificado con el resto como intermedios
+	byPath := map[string][]*x509.Certificate{}
+	var failures []string
+	for _, result := range results {
+		if result.Err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", result.Path, result.Err))
+			continue
+		}
+		byPath[result.Path] = append(byPath[result.Path], result.Cert)
+	}
+
+	var certs []certMetric
+	for _, result := range results {
+		if result.Err != nil {
+			continue
+		}
+		metric := certMetric{path: result.Path, index: result.Index, cert: result.Cert}
+		if result.Index == 0 {
+			chain := byPath[result.Path]
+			ok := verifyForMetrics(chain, roots, "", PurposeAny)
+			metric.verified = &ok
+		}
+		certs = append(certs, metric)
+	}
+	if len(failures) > 0 {
+		return certs, errors.New(strings.Join(failures, "; "))
+	}
+	return certs, nil
+}
+
+// collectRemote obtiene la cadena de un endpoint y verifica la hoja contra el nombre del servidor.
+func collectRemote(target MetricsTarget, roots []*x509.Certificate) ([]certMetric, error) {
+	chain, err := FetchRemoteChain(RemoteOptions{Address: target.Remote, SNI: target.SNI, Timeout: target.Timeout, StartTLS: target.StartTLS})
+	if err != nil {
+		return nil, err
+	}
+	hostname := target.SNI
+	if hostname == "" {
+		hostname, _ = RemoteHost(target.Remote, "")
+	}
+
+	certs := make([]certMetric, len(chain))
+	for i, cert := range chain {
+		certs[i] = certMetric{endpoint: target.Remote, index: i, cert: cert}
+	}
+	ok := verifyForMetrics(chain, roots, hostname, PurposeServer)
+	certs[0].verified = &ok
+	return certs, nil
+}
+
+// verifyForMetrics indica si el primer certificado de la cadena llega a una raíz de confianza.
+func verifyForMetrics(chain, roots []*x509.Certificate, hostname, purpose string) bool {
+	_, err := VerifyChain(chain[0], ChainOptions{
+		Intermediates: chain[1:],
+		Roots:         roots,
+		SystemRoots:   true,
+		Hostname:      hostname,
+		Purpose:       purpose,
+	})
+	return err == nil
+}
+
+// Results devuelve los certificados de la última revisión, con el endpoint como ruta de los remotos.
+func (e *MetricsExporter) Results() []ScanResult {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	results := make([]ScanResult, len(e.certs))
+	for i, metric := range e.certs {
+		path := metric.path
+		if metric.endpoint != "" {
+			path = metric.endpoint
+		}
+		results[i] = ScanResult{Path: path, Index: metric.index, Cert: metric.cert}
+	}
+	return results
+}
+
+// WriteMetrics escribe las métricas en el formato de texto de Prometheus (versión 0.0.4).
+func (e *MetricsExporter) WriteMetrics(w io.Writer) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	var b strings.Builder
+	writeHeader(&b, "ssl_cert_not_after_seconds", "gauge", "Expiration time of the certificate as a Unix timestamp.")
+	for _, metric := range e.certs {
+		fmt.Fprintf(&b, "ssl_cert_not_after_seconds{%s} %d\n", certLabels(metric), metric.cert.NotAfter.Unix())
+	}
+	writeHeader(&b, "ssl_cert_not_before_seconds", "gauge", "Start of the validity period of the certificate as a Unix timestamp.")
+	for _, metric := range e.certs {
+		fmt.Fprintf(&b, "ssl_cert_not_before_seconds{%s} %d\n", certLabels(metric), metric.cert.NotBefore.Unix())
+	}
+	writeHeader(&b, "ssl_verify_ok", "gauge", "Whether the first certificate of each file or endpoint chains to a trusted root (1) or not (0).")
+	for _, metric := range e.certs {
+		if metric.verified == nil {
+			continue
+		}
+		value := 0
+		if *metric.verified {
+			value = 1
+		}
+		fmt.Fprintf(&b, "ssl_verify_ok{%s} %d\n", certLabels(metric), value)
+	}
+
+	writeHeader(&b, "ssl_probe_errors_total", "counter", "Number of scans in which reading a target failed.")
+	names := make([]string, 0, len(e.errors))
+	for name := range e.errors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&b, "ssl_probe_errors_total{target=\"%s\"} %g\n", escapeLabel(name), e.errors[name])
+	}
+
+	if !e.lastScan.IsZero() {
+		writeHeader(&b, "ssl_last_scan_timestamp_seconds", "gauge", "Time of the last scan of the targets as a Unix timestamp.")
+		fmt.Fprintf(&b, "ssl_last_scan_timestamp_seconds %d\n", e.lastScan.Unix())
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ServeHTTP sirve las métricas para que el exportador se pueda registrar en /metrics.
+func (e *MetricsExporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := e.WriteMetrics(w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func writeHeader(b *strings.Builder, name, kind, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func certLabels(metric certMetric) string {
+	return fmt.Sprintf(`path="%s",endpoint="%s",subject="%s",issuer="%s",serial="%s"`,
+		escapeLabel(metric.path),
+		escapeLabel(metric.endpoint),
+		escapeLabel(metric.cert.Subject.String()),
+		escapeLabel(metric.cert.Issuer.String()),
+		metric.cert.SerialNumber.Text(16))
+}
+
+// escapeLabel escapa un valor de etiqueta según el formato de texto de Prometheus.
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
@@ -0,0 +1,367 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Tipos de webhook soportados en la sección notify.
+const (
+	WebhookGeneric = "generic"
+	WebhookSlack   = "slack"
+	WebhookTeams   = "teams"
+)
+
+// DefaultNotifyThresholds son los días restantes en los que se avisa si la configuración no indica otros.
+var DefaultNotifyThresholds = []int{30, 14, 7, 1}
+
+// DefaultNotifyStateFile es el archivo donde se recuerdan los avisos ya enviados.
+const DefaultNotifyStateFile = "ssl-tool-notify-state.json"
+
+// NotifyConfig es la sección notify del archivo de configuración.
+type NotifyConfig struct {
+	Thresholds []int           `yaml:"thresholds,omitempty"` // días restantes en los que se avisa
+	StateFile  string          `yaml:"state_file,omitempty"` // estado para no repetir avisos
+	Webhooks   []WebhookConfig `yaml:"webhooks,omitempty"`
+	SMTP       *SMTPConfig     `yaml:"smtp,omitempty"`
+}
+
+// WebhookConfig describe un webhook al que se envían los avisos.
+type WebhookConfig struct {
+	URL      string            `yaml:"url"`
+	Type     string            `yaml:"type,omitempty"`     // generic, slack o teams
+	Template string            `yaml:"template,omitempty"` // cuerpo JSON para generic (text/template sobre Alert)
+	Headers  map[string]string `yaml:"headers,omitempty"`
+}
+
+// SMTPConfig describe el servidor de correo para los avisos por email.
+type SMTPConfig struct {
+	Host        string   `yaml:"host"`
+	Port        int      `yaml:"port,omitempty"`
+	Username    string   `yaml:"username,omitempty"`
+	Password    string   `yaml:"password,omitempty"`
+	PasswordEnv string   `yaml:"password_env,omitempty"` // variable de entorno con la contraseña
+	From        string   `yaml:"from"`
+	To          []string `yaml:"to"`
+}
+
+// Alert es un aviso de caducidad. Sus campos están disponibles en las plantillas de webhook.
+type Alert struct {
+	Source     string    `json:"source"`
+	Subject    string    `json:"subject"`
+	CommonName string    `json:"common_name"`
+	Issuer     string    `json:"issuer"`
+	Serial     string    `json:"serial"`
+	NotAfter   time.Time `json:"not_after"`
+	Days       int       `json:"days"`
+	State      string    `json:"state"`
+	Threshold  int       `json:"threshold"` // umbral alcanzado en días; -1 si ya ha caducado
+	Message    string    `json:"message"`
+}
+
+// notifyRecord es lo que se guarda de cada certificado ya avisado.
+type notifyRecord struct {
+	Source     string    `json:"source"`
+	Serial     string    `json:"serial"`
+	Threshold  int       `json:"threshold"`
+	NotifiedAt time.Time `json:"notified_at"`
+}
+
+// Notifier envía los avisos de caducidad por los canales configurados.
+type Notifier struct {
+	cfg    NotifyConfig
+	client *http.Client
+}
+
+// NewNotifier valida la configuración y crea el notificador.
+func NewNotifier(cfg NotifyConfig) (*Notifier, error) {
+	if len(cfg.Webhooks) == 0 && cfg.SMTP == nil {
+		return nil, errors.New("notify section does not define any webhook or smtp target")
+	}
+	for i, webhook := range cfg.Webhooks {
+		if webhook.URL == "" {
+			return nil, fmt.Errorf("webhook %d has no url", i+1)
+		}
+		switch webhook.Type {
+		case "", WebhookGeneric, WebhookSlack, WebhookTeams:
+		default:
+			return nil, fmt.Errorf("unsupported webhook type: %s (use %s, %s or %s)", webhook.Type, WebhookGeneric, WebhookSlack, WebhookTeams)
+		}
+		if webhook.Template != "" {
+			if _, err := parseWebhookTemplate(webhook.Template); err != nil {
+				return nil, fmt.Errorf("invalid template for webhook %d: %v", i+1, err)
+			}
+		}
+	}
+	if cfg.SMTP != nil && (cfg.SMTP.Host == "" || cfg.SMTP.From == "" || len(cfg.SMTP.To) == 0) {
+		return nil, errors.New("smtp requires host, from and to")
+	}
+	if len(cfg.Thresholds) == 0 {
+		cfg.Thresholds = DefaultNotifyThresholds
+	}
+	if cfg.StateFile == "" {
+		cfg.StateFile = DefaultNotifyStateFile
+	}
+	return &Notifier{cfg: cfg, client: &http.Client{Timeout: 10 * time.Second}}, nil
+}
+
+// Notify avisa de los certificados que han alcanzado un umbral nuevo desde el último aviso. Cada
+// certificado (origen y número de serie) solo se vuelve a avisar al pasar a un umbral menor. Devuelve
+// los avisos enviados; los que fallan se reintentan en la siguiente ejecución.
+func (n *Notifier) Notify(results []ScanResult, at time.Time) ([]Alert, error) {
+	state, err := loadNotifyState(n.cfg.StateFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var sent []Alert
+	var errs []string
+	for _, result := range results {
+		if result.Err != nil {
+			continue
+		}
+		source := result.Path
+		if result.Index > 0 {
+			source = fmt.Sprintf("%s#%d", result.Path, result.Index)
+		}
+		serial := result.Cert.SerialNumber.Text(16)
+		key := source + "|" + serial
+
+		// Un certificado renovado en el mismo origen sustituye al anterior
+		for other, record := range state {
+			if record.Source == source && record.Serial != serial {
+				delete(state, other)
+			}
+		}
+
+		status := CheckExpiry(result.Cert, at, time.Duration(slices.Max(n.cfg.Thresholds))*24*time.Hour)
+		threshold, ok := alertThreshold(status, n.cfg.Thresholds)
+		if !ok {
+			continue
+		}
+		if record, done := state[key]; done && record.Threshold <= threshold {
+			continue
+		}
+
+		alert := newAlert(source, result, status, threshold)
+		if err := n.send(alert); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", source, err))
+			continue
+		}
+		state[key] = notifyRecord{Source: source, Serial: serial, Threshold: threshold, NotifiedAt: time.Now()}
+		sent = append(sent, alert)
+	}
+
+	if err := saveNotifyState(n.cfg.StateFile, state); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if len(errs) > 0 {
+		return sent, fmt.Errorf("error sending notifications: %s", strings.Join(errs, "; "))
+	}
+	return sent, nil
+}
+
+// alertThreshold devuelve el umbral más estricto alcanzado (-1 si ya ha caducado). Los certificados
+// que todavía no son válidos no generan avisos.
+func alertThreshold(status ExpiryStatus, thresholds []int) (int, bool) {
+	switch status.State {
+	case ExpiryExpired:
+		return -1, true
+	case ExpiryNotYetValid:
+		return 0, false
+	}
+	sorted := append([]int{}, thresholds...)
+	sort.Ints(sorted)
+	for _, days := range sorted {
+		if status.Remaining <= time.Duration(days)*24*time.Hour {
+			return days, true
+		}
+	}
+	return 0, false
+}
+
+func newAlert(source string, result ScanResult, status ExpiryStatus, threshold int) Alert {
+	cert := result.Cert
+	name := cert.Subject.CommonName
+	if name == "" {
+		name = cert.Subject.String()
+	}
+	return Alert{
+		Source:     source,
+		Subject:    cert.Subject.String(),
+		CommonName: cert.Subject.CommonName,
+		Issuer:     cert.Issuer.String(),
+		Serial:     cert.SerialNumber.Text(16),
+		NotAfter:   cert.NotAfter,
+		Days:       status.Days(),
+		State:      status.State,
+		Threshold:  threshold,
+		Message:    fmt.Sprintf("Certificate %s (%s) %s.", name, source, status.Describe()),
+	}
+}
+
+// send envía un aviso por todos los canales; falla si falla alguno.
+func (n *Notifier) send(alert Alert) error {
+	var errs []string
+	for _, webhook := range n.cfg.Webhooks {
+		if err := n.postWebhook(webhook, alert); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if n.cfg.SMTP != nil {
+		if err := sendAlertEmail(*n.cfg.SMTP, alert); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (n *Notifier) postWebhook(webhook WebhookConfig, alert Alert) error {
+	body, err := webhookBody(webhook, alert)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating webhook request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range webhook.Headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error posting webhook: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s returned %s", webhook.URL, resp.Status)
+	}
+	return nil
+}
+
+// webhookBody construye el JSON de cada tipo de webhook.
+func webhookBody(webhook WebhookConfig, alert Alert) ([]byte, error) {
+	switch webhook.Type {
+	case WebhookSlack:
+		return json.Marshal(map[string]string{"text": alert.Message})
+	case WebhookTeams:
+		color := "FFA500"
+		if alert.Threshold <= 7 {
+			color = "D70000"
+		}
+		return json.Marshal(map[string]interface{}{
+			"@type":      "MessageCard",
+			"@context":   "http://schema.org/extensions",
+			"summary":    alert.Message,
+			"themeColor": color,
+			"title":      "Certificate expiry alert",
+			"text":       alert.Message,
+		})
+	default:
+		if webhook.Template == "" {
+			return json.Marshal(alert)
+		}
+		tmpl, err := parseWebhookTemplate(webhook.Template)
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, alert); err != nil {
+			return nil, fmt.Errorf("error rendering webhook template: %v", err)
+		}
+		return buf.Bytes(), nil
+	}
+}
+
+// parseWebhookTemplate añade la función json para escapar valores dentro de la plantilla.
+func parseWebhookTemplate(text string) (*template.Template, error) {
+	return template.New("webhook").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+	}).Parse(text)
+}
+
+// sendAlertEmail envía el aviso por SMTP. net/smtp usa STARTTLS si el servidor lo anuncia.
+func sendAlertEmail(cfg SMTPConfig, alert Alert) error {
+	port := cfg.Port
+	if port == 0 {
+		port = 587
+	}
+	address := net.JoinHostPort(cfg.Host, strconv.Itoa(port))
+
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		password := cfg.Password
+		if cfg.PasswordEnv != "" {
+			password = os.Getenv(cfg.PasswordEnv)
+		}
+		auth = smtp.PlainAuth("", cfg.Username, password, cfg.Host)
+	}
+
+	name := alert.CommonName
+	if name == "" {
+		name = alert.Subject
+	}
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: [ssl-tool] Certificate %s: %s\r\n", alert.State, name)
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	fmt.Fprintf(&msg, "%s\r\n\r\n", alert.Message)
+	fmt.Fprintf(&msg, "Source:    %s\r\n", alert.Source)
+	fmt.Fprintf(&msg, "Subject:   %s\r\n", alert.Subject)
+	fmt.Fprintf(&msg, "Issuer:    %s\r\n", alert.Issuer)
+	fmt.Fprintf(&msg, "Serial:    %s\r\n", alert.Serial)
+	fmt.Fprintf(&msg, "Not after: %s\r\n", alert.NotAfter.UTC().Format(time.RFC3339))
+
+	if err := smtp.SendMail(address, auth, cfg.From, cfg.To, []byte(msg.String())); err != nil {
+		return fmt.Errorf("error sending email via %s: %v", address, err)
+	}
+	return nil
+}
+
+// loadNotifyState lee los avisos enviados; si el archivo no existe, el estado está vacío.
+func loadNotifyState(path string) (map[string]notifyRecord, error) {
+	state := map[string]notifyRecord{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading notify state: %v", err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("error parsing notify state %s: %v", path, err)
+	}
+	return state, nil
+}
+
+func saveNotifyState(path string, state map[string]notifyRecord) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding notify state: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("error writing notify state: %v", err)
+	}
+	return nil
+}
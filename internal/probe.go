@@ -0,0 +1,401 @@
+package internal
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// Perfiles de configuración TLS de Mozilla (https://wiki.mozilla.org/Security/Server_Side_TLS).
+const (
+	ProfileModern       = "modern"
+	ProfileIntermediate = "intermediate"
+	ProfileOld          = "old"
+)
+
+// DefaultProbeALPN son los protocolos ALPN que se prueban si no se indica otra lista.
+var DefaultProbeALPN = []string{"h2", "http/1.1"}
+
+// probeVersions son las versiones que puede negociar crypto/tls (SSLv3 no está soportado).
+var probeVersions = []uint16{tls.VersionTLS10, tls.VersionTLS11, tls.VersionTLS12, tls.VersionTLS13}
+
+// probeCurves son las curvas que se prueban para el intercambio de claves.
+var probeCurves = []tls.CurveID{tls.X25519, tls.CurveP256, tls.CurveP384, tls.CurveP521}
+
+// probeTicketWait es lo que se espera tras el handshake a que llegue un ticket de sesión de TLS 1.3.
+const probeTicketWait = 500 * time.Millisecond
+
+// ProbeOptions describe el endpoint a analizar.
+type ProbeOptions struct {
+	Remote RemoteOptions
+	ALPN   []string // protocolos ALPN a probar; por defecto, DefaultProbeALPN
+}
+
+// ProbeResult recoge lo que acepta un endpoint TLS.
+type ProbeResult struct {
+	Address      string
+	Chain        []*x509.Certificate
+	Versions     []uint16            // versiones aceptadas
+	CipherSuites map[uint16][]uint16 // suites aceptadas para cada versión anterior a TLS 1.3
+	TLS13Suite   uint16              // suite negociada en TLS 1.3 (crypto/tls no permite elegirla)
+	Curves       []tls.CurveID
+	ALPN         []string
+	OCSPStapled  bool
+	OCSPStatus   string // good, revoked, unknown o el error al interpretar la respuesta
+	OCSPNext     time.Time
+	Resumption   bool
+	Profiles     []ProfileCheck
+}
+
+// ProfileCheck es el resultado de comparar el endpoint con un perfil de Mozilla.
+type ProfileCheck struct {
+	Name   string
+	Issues []string
+}
+
+// Compliant indica si el endpoint cumple el perfil.
+func (p ProfileCheck) Compliant() bool {
+	return len(p.Issues) == 0
+}
+
+// Grade devuelve el perfil más estricto que cumple el endpoint, o "none".
+func (r *ProbeResult) Grade() string {
+	for _, profile := range r.Profiles {
+		if profile.Compliant() {
+			return profile.Name
+		}
+	}
+	return "none"
+}
+
+// Profile devuelve la comprobación del perfil indicado.
+func (r *ProbeResult) Profile(name string) (ProfileCheck, error) {
+	for _, profile := range r.Profiles {
+		if profile.Name == name {
+			return profile, nil
+		}
+	}
+	return ProfileCheck{}, fmt.Errorf("unknown Mozilla profile: %s (supported: %s, %s, %s)", name, ProfileModern, ProfileIntermediate, ProfileOld)
+}
+
+// ProbeTLS analiza un endpoint con una serie de handshakes restringidos: versiones, suites, curvas,
+// ALPN, OCSP stapling y reanudación de sesión. Un handshake rechazado significa que la opción no
+// está soportada; solo los fallos de conexión se devuelven como error.
+func ProbeTLS(opts ProbeOptions) (*ProbeResult, error) {
+	alpn := opts.ALPN
+	if len(alpn) == 0 {
+		alpn = DefaultProbeALPN
+	}
+	result := &ProbeResult{CipherSuites: map[uint16][]uint16{}}
+
+	// Handshake inicial lo más permisivo posible: cadena, OCSP y comprobación de que el endpoint responde
+	state, address, err := probeHandshake(opts.Remote, &tls.Config{MinVersion: tls.VersionTLS10, CipherSuites: allCipherSuites()})
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		return nil, fmt.Errorf("TLS handshake with %s failed with every protocol version", address)
+	}
+	result.Address = address
+	result.Chain = state.PeerCertificates
+	if len(state.OCSPResponse) > 0 {
+		result.OCSPStapled = true
+		result.OCSPStatus, result.OCSPNext = describeOCSP(state.OCSPResponse, result.Chain)
+	}
+
+	for _, version := range probeVersions {
+		config := &tls.Config{MinVersion: version, MaxVersion: version}
+		if version < tls.VersionTLS13 {
+			config.CipherSuites = allCipherSuites()
+		}
+		state, _, err := probeHandshake(opts.Remote, config)
+		if err != nil {
+			return nil, err
+		}
+		if state == nil {
+			continue
+		}
+		result.Versions = append(result.Versions, version)
+		if version == tls.VersionTLS13 {
+			result.TLS13Suite = state.CipherSuite
+		}
+	}
+
+	var accepted, ecdhe []uint16
+	for _, version := range result.Versions {
+		if version == tls.VersionTLS13 {
+			continue
+		}
+		for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+			if !slices.Contains(suite.SupportedVersions, version) {
+				continue
+			}
+			state, _, err := probeHandshake(opts.Remote, &tls.Config{MinVersion: version, MaxVersion: version, CipherSuites: []uint16{suite.ID}})
+			if err != nil {
+				return nil, err
+			}
+			if state == nil {
+				continue
+			}
+			result.CipherSuites[version] = append(result.CipherSuites[version], suite.ID)
+			if !slices.Contains(accepted, suite.ID) {
+				accepted = append(accepted, suite.ID)
+				if strings.HasPrefix(suite.Name, "TLS_ECDHE_") {
+					ecdhe = append(ecdhe, suite.ID)
+				}
+			}
+		}
+	}
+
+	// Las curvas solo se pueden comprobar con TLS 1.3 o con suites ECDHE
+	if len(ecdhe) > 0 || slices.Contains(result.Versions, tls.VersionTLS13) {
+		for _, curve := range probeCurves {
+			state, _, err := probeHandshake(opts.Remote, &tls.Config{MinVersion: tls.VersionTLS10, CipherSuites: ecdhe, CurvePreferences: []tls.CurveID{curve}})
+			if err != nil {
+				return nil, err
+			}
+			if state != nil {
+				result.Curves = append(result.Curves, curve)
+			}
+		}
+	}
+
+	base := &tls.Config{MinVersion: tls.VersionTLS10, CipherSuites: accepted}
+	for _, protocol := range alpn {
+		config := base.Clone()
+		config.NextProtos = []string{protocol}
+		state, _, err := probeHandshake(opts.Remote, config)
+		if err != nil {
+			return nil, err
+		}
+		if state != nil && state.NegotiatedProtocol == protocol {
+			result.ALPN = append(result.ALPN, protocol)
+		}
+	}
+
+	result.Resumption, err = probeResumption(opts.Remote, base)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Profiles = checkProfiles(result)
+	return result, nil
+}
+
+// probeHandshake conecta y realiza un handshake con la configuración indicada. Devuelve nil como
+// estado si el servidor rechaza el handshake.
+func probeHandshake(remote RemoteOptions, config *tls.Config) (*tls.ConnectionState, string, error) {
+	conn, address, sni, err := dialRemote(context.Background(), remote)
+	if err != nil {
+		return nil, address, err
+	}
+	defer conn.Close()
+
+	config = config.Clone()
+	config.ServerName = sni
+	config.InsecureSkipVerify = true
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.Handshake(); err != nil {
+		return nil, address, nil
+	}
+	state := tlsConn.ConnectionState()
+	return &state, address, nil
+}
+
+// probeResumption comprueba si el servidor acepta reanudar una sesión con un segundo handshake.
+func probeResumption(remote RemoteOptions, base *tls.Config) (bool, error) {
+	config := base.Clone()
+	config.InsecureSkipVerify = true
+	config.ClientSessionCache = tls.NewLRUClientSessionCache(1)
+
+	for attempt := 0; attempt < 2; attempt++ {
+		conn, _, sni, err := dialRemote(context.Background(), remote)
+		if err != nil {
+			return false, err
+		}
+		config.ServerName = sni
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return false, nil
+		}
+		if attempt == 1 {
+			resumed := tlsConn.ConnectionState().DidResume
+			conn.Close()
+			return resumed, nil
+		}
+		// En TLS 1.3 el ticket llega después del handshake y se procesa al leer
+		if tlsConn.ConnectionState().Version == tls.VersionTLS13 {
+			_ = tlsConn.SetReadDeadline(time.Now().Add(probeTicketWait))
+			_, _ = tlsConn.Read(make([]byte, 1))
+		}
+		conn.Close()
+	}
+	return false, nil
+}
+
+// describeOCSP interpreta la respuesta OCSP grapada en el handshake.
+func describeOCSP(raw []byte, chain []*x509.Certificate) (string, time.Time) {
+	var issuer *x509.Certificate
+	if len(chain) > 1 {
+		issuer = chain[1]
+	}
+	response, err := ocsp.ParseResponse(raw, issuer)
+	if err != nil {
+		return fmt.Sprintf("invalid response: %v", err), time.Time{}
+	}
+	switch response.Status {
+	case ocsp.Good:
+		return "good", response.NextUpdate
+	case ocsp.Revoked:
+		return "revoked", response.NextUpdate
+	default:
+		return "unknown", response.NextUpdate
+	}
+}
+
+// mozillaProfile describe lo que permite cada perfil de Mozilla (versión 5.7 de las guías).
+// Las suites DHE no aparecen porque crypto/tls no las implementa y no se pueden probar.
+type mozillaProfile struct {
+	name     string
+	versions []uint16
+	suites   []uint16
+	curves   []tls.CurveID
+	rsa      bool // admite certificados RSA de 2048 bits o más
+}
+
+var intermediateSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+}
+
+var mozillaProfiles = []mozillaProfile{
+	{
+		name:     ProfileModern,
+		versions: []uint16{tls.VersionTLS13},
+		curves:   []tls.CurveID{tls.X25519, tls.CurveP256, tls.CurveP384},
+	},
+	{
+		name:     ProfileIntermediate,
+		versions: []uint16{tls.VersionTLS12, tls.VersionTLS13},
+		suites:   intermediateSuites,
+		curves:   []tls.CurveID{tls.X25519, tls.CurveP256, tls.CurveP384},
+		rsa:      true,
+	},
+	{
+		name:     ProfileOld,
+		versions: []uint16{tls.VersionTLS10, tls.VersionTLS11, tls.VersionTLS12, tls.VersionTLS13},
+		suites: append(append([]uint16{}, intermediateSuites...),
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+			tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_RSA_WITH_AES_128_CBC_SHA256,
+			tls.TLS_RSA_WITH_AES_128_CBC_SHA,
+			tls.TLS_RSA_WITH_AES_256_CBC_SHA,
+			tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA,
+		),
+		curves: []tls.CurveID{tls.X25519, tls.CurveP256, tls.CurveP384},
+		rsa:    true,
+	},
+}
+
+// checkProfiles compara el resultado con los perfiles de Mozilla, del más estricto al más permisivo.
+func checkProfiles(result *ProbeResult) []ProfileCheck {
+	var checks []ProfileCheck
+	for _, profile := range mozillaProfiles {
+		check := ProfileCheck{Name: profile.name}
+		for _, version := range result.Versions {
+			if !slices.Contains(profile.versions, version) {
+				check.Issues = append(check.Issues, fmt.Sprintf("%s is not allowed", tls.VersionName(version)))
+			}
+		}
+		var reported []uint16
+		for _, version := range result.Versions {
+			for _, suite := range result.CipherSuites[version] {
+				if !slices.Contains(profile.suites, suite) && !slices.Contains(reported, suite) {
+					reported = append(reported, suite)
+					check.Issues = append(check.Issues, fmt.Sprintf("cipher suite %s is not allowed", tls.CipherSuiteName(suite)))
+				}
+			}
+		}
+		for _, curve := range result.Curves {
+			if !slices.Contains(profile.curves, curve) {
+				check.Issues = append(check.Issues, fmt.Sprintf("curve %s is not allowed", curve))
+			}
+		}
+		if len(result.Chain) > 0 {
+			if issue := checkProfileKey(result.Chain[0], profile.rsa); issue != "" {
+				check.Issues = append(check.Issues, issue)
+			}
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// checkProfileKey comprueba el tipo de clave del certificado hoja: ECDSA P-256/P-384 en todos los
+// perfiles y RSA de al menos 2048 bits salvo en modern.
+func checkProfileKey(leaf *x509.Certificate, allowRSA bool) string {
+	switch pub := leaf.PublicKey.(type) {
+	case *ecdsa.PublicKey:
+		if name := pub.Curve.Params().Name; name != "P-256" && name != "P-384" {
+			return fmt.Sprintf("certificate curve %s is not allowed", name)
+		}
+		return ""
+	case *rsa.PublicKey:
+		if !allowRSA {
+			return "certificate must use an ECDSA key"
+		}
+		if pub.N.BitLen() < 2048 {
+			return fmt.Sprintf("certificate RSA key is too small (%d bits)", pub.N.BitLen())
+		}
+		return ""
+	default:
+		return fmt.Sprintf("certificate key type %T is not allowed", leaf.PublicKey)
+	}
+}
+
+// allCipherSuites devuelve los identificadores de todas las suites que implementa crypto/tls,
+// incluidas las inseguras, para poder detectar si el servidor las acepta.
+func allCipherSuites() []uint16 {
+	var ids []uint16
+	for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		ids = append(ids, suite.ID)
+	}
+	return ids
+}
+
+// InsecureCipherSuite indica si crypto/tls considera insegura la suite indicada.
+func InsecureCipherSuite(id uint16) bool {
+	for _, suite := range tls.InsecureCipherSuites() {
+		if suite.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// SortedVersions devuelve las versiones de mayor a menor, para mostrar primero la más reciente.
+func SortedVersions(versions []uint16) []uint16 {
+	sorted := append([]uint16{}, versions...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] > sorted[j] })
+	return sorted
+}
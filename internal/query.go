@@ -0,0 +1,612 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// QueryFields es el modelo de un certificado sobre el que se evalúan las expresiones de --where.
+// Los valores son float64, string, bool, []string o time.Time; un campo ausente no cumple ninguna
+// comparación.
+type QueryFields map[string]interface{}
+
+// QueryField describe un campo del modelo.
+type QueryField struct {
+	Name        string
+	Type        string
+	Description string
+}
+
+// QueryFieldList enumera los campos disponibles en el orden en que se muestran.
+var QueryFieldList = []QueryField{
+	{"kind", "string", "certificate or csr"},
+	{"subject", "string", "full subject DN"},
+	{"subject.cn", "string", "subject common name"},
+	{"subject.o", "string", "subject organization"},
+	{"subject.ou", "string", "subject organizational unit"},
+	{"subject.c", "string", "subject country"},
+	{"subject.l", "string", "subject locality"},
+	{"issuer", "string", "full issuer DN"},
+	{"issuer.cn", "string", "issuer common name"},
+	{"issuer.o", "string", "issuer organization"},
+	{"issuer.ou", "string", "issuer organizational unit"},
+	{"issuer.c", "string", "issuer country"},
+	{"san", "list", "DNS names, IP addresses and emails"},
+	{"key.type", "string", "RSA, ECDSA or Ed25519"},
+	{"key.size", "number", "key size in bits"},
+	{"key.curve", "string", "curve name for ECDSA and Ed25519 keys"},
+	{"signature", "string", "signature algorithm, e.g. SHA256-RSA"},
+	{"serial", "string", "serial number in hex"},
+	{"fingerprint", "string", "SHA256 of the DER in hex"},
+	{"not_before", "time", "start of validity"},
+	{"not_after", "time", "end of validity"},
+	{"days", "number", "whole days until expiry (negative once expired)"},
+	{"expired", "bool", "whether the certificate has expired"},
+	{"ca", "bool", "whether the certificate is a CA"},
+	{"self_signed", "bool", "whether subject and issuer are the same"},
+	{"source", "list", "paths or endpoints where it was found"},
+	{"owner", "string", "inventory owner annotation"},
+	{"first_seen", "time", "first time the inventory saw it"},
+	{"last_seen", "time", "last time the inventory saw it"},
+}
+
+// CertificateFields construye el modelo de un certificado encontrado en source, evaluado en now.
+func CertificateFields(cert *x509.Certificate, source string, now time.Time) QueryFields {
+	if now.IsZero() {
+		now = time.Now()
+	}
+	sum := sha256.Sum256(cert.Raw)
+	fields := QueryFields{
+		"kind":        InventoryCertificate,
+		"san":         SubjectAltNames(cert.DNSNames, cert.IPAddresses, cert.EmailAddresses),
+		"signature":   cert.SignatureAlgorithm.String(),
+		"serial":      cert.SerialNumber.Text(16),
+		"fingerprint": hex.EncodeToString(sum[:]),
+		"not_before":  cert.NotBefore,
+		"not_after":   cert.NotAfter,
+		"days":        float64(int(cert.NotAfter.Sub(now) / (24 * time.Hour))),
+		"expired":     now.After(cert.NotAfter),
+		"ca":          cert.IsCA,
+		"self_signed": string(cert.RawSubject) == string(cert.RawIssuer),
+		"source":      []string{},
+	}
+	if source != "" {
+		fields["source"] = []string{source}
+	}
+	addNameFields(fields, "subject", cert.Subject)
+	addNameFields(fields, "issuer", cert.Issuer)
+	addKeyFields(fields, cert.PublicKey)
+	return fields
+}
+
+// CSRFields construye el modelo de un CSR; no tiene emisor ni validez.
+func CSRFields(csr *x509.CertificateRequest, source string) QueryFields {
+	sum := sha256.Sum256(csr.Raw)
+	fields := QueryFields{
+		"kind":        InventoryCSR,
+		"san":         SubjectAltNames(csr.DNSNames, csr.IPAddresses, csr.EmailAddresses),
+		"signature":   csr.SignatureAlgorithm.String(),
+		"fingerprint": hex.EncodeToString(sum[:]),
+		"source":      []string{},
+	}
+	if source != "" {
+		fields["source"] = []string{source}
+	}
+	addNameFields(fields, "subject", csr.Subject)
+	addKeyFields(fields, csr.PublicKey)
+	return fields
+}
+
+// InventoryFields construye el modelo de un registro del inventario a partir del DER guardado y
+// añade los datos propios del inventario.
+func InventoryFields(entry InventoryEntry, now time.Time) QueryFields {
+	var fields QueryFields
+	if entry.Kind == InventoryCSR {
+		if csr, err := x509.ParseCertificateRequest(entry.DER); err == nil {
+			fields = CSRFields(csr, "")
+		}
+	} else if cert, err := x509.ParseCertificate(entry.DER); err == nil {
+		fields = CertificateFields(cert, "", now)
+	}
+	if fields == nil {
+		// Registros sin DER: solo los campos guardados
+		fields = QueryFields{
+			"kind":        entry.Kind,
+			"subject":     entry.Subject,
+			"subject.cn":  entry.CommonName,
+			"issuer":      entry.Issuer,
+			"san":         entry.SANs,
+			"serial":      entry.Serial,
+			"fingerprint": entry.Fingerprint,
+		}
+		if !entry.NotAfter.IsZero() {
+			if now.IsZero() {
+				now = time.Now()
+			}
+			fields["not_before"] = entry.NotBefore
+			fields["not_after"] = entry.NotAfter
+			fields["days"] = float64(int(entry.NotAfter.Sub(now) / (24 * time.Hour)))
+			fields["expired"] = now.After(entry.NotAfter)
+		}
+	}
+	fields["source"] = append([]string{}, entry.Sources...)
+	fields["owner"] = entry.Owner
+	fields["first_seen"] = entry.FirstSeen
+	fields["last_seen"] = entry.LastSeen
+	return fields
+}
+
+func addNameFields(fields QueryFields, prefix string, name pkix.Name) {
+	fields[prefix] = name.String()
+	fields[prefix+".cn"] = name.CommonName
+	fields[prefix+".o"] = strings.Join(name.Organization, ", ")
+	fields[prefix+".ou"] = strings.Join(name.OrganizationalUnit, ", ")
+	fields[prefix+".c"] = strings.Join(name.Country, ", ")
+	fields[prefix+".l"] = strings.Join(name.Locality, ", ")
+}
+
+func addKeyFields(fields QueryFields, pub interface{}) {
+	info, err := DescribePublicKey(pub)
+	if err != nil {
+		return
+	}
+	fields["key.type"] = info.Algorithm
+	fields["key.size"] = float64(info.Size)
+	fields["key.curve"] = info.Curve
+}
+
+// Query es una expresión de filtrado ya analizada. La sintaxis admite comparaciones campo-valor
+// (==, !=, <, <=, >, >=, ~ y !~ con expresiones regulares, contains), combinadas con &&, || y ! y
+// agrupadas con paréntesis. Un campo booleano puede usarse solo, por ejemplo "expired && !ca".
+type Query struct {
+	source string
+	root   queryNode
+}
+
+// ParseQuery analiza una expresión de --where.
+func ParseQuery(expr string) (*Query, error) {
+	tokens, err := lexQuery(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %v", err)
+	}
+	p := &queryParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err == nil && !p.done() {
+		err = fmt.Errorf("unexpected %s", p.peek())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %v", err)
+	}
+	return &Query{source: expr, root: root}, nil
+}
+
+// String devuelve la expresión original.
+func (q *Query) String() string {
+	return q.source
+}
+
+// Match evalúa la expresión sobre el modelo de un certificado.
+func (q *Query) Match(fields QueryFields) (bool, error) {
+	return q.root.eval(fields)
+}
+
+// Tokens del lenguaje de consulta.
+type queryToken struct {
+	kind  string // ident, string, number, op
+	value string
+}
+
+func (t queryToken) String() string {
+	if t.kind == "string" {
+		return strconv.Quote(t.value)
+	}
+	return fmt.Sprintf("%q", t.value)
+}
+
+var queryOperators = []string{"&&", "||", "==", "!=", "<=", ">=", "!~", "<", ">", "~", "!", "(", ")"}
+
+func lexQuery(expr string) ([]queryToken, error) {
+	var tokens []queryToken
+	for i := 0; i < len(expr); {
+		c := rune(expr[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"' || c == '\'':
+			var value strings.Builder
+			j := i + 1
+			for ; j < len(expr) && rune(expr[j]) != c; j++ {
+				if expr[j] == '\\' && j+1 < len(expr) {
+					j++
+				}
+				value.WriteByte(expr[j])
+			}
+			if j >= len(expr) {
+				return nil, errors.New("unterminated string")
+			}
+			tokens = append(tokens, queryToken{"string", value.String()})
+			i = j + 1
+		case unicode.IsDigit(c) || (c == '-' && i+1 < len(expr) && unicode.IsDigit(rune(expr[i+1]))):
+			j := i + 1
+			for j < len(expr) && (unicode.IsDigit(rune(expr[j])) || expr[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, queryToken{"number", expr[i:j]})
+			i = j
+		case unicode.IsLetter(c) || c == '_':
+			j := i + 1
+			for j < len(expr) && (unicode.IsLetter(rune(expr[j])) || unicode.IsDigit(rune(expr[j])) || expr[j] == '_' || expr[j] == '.') {
+				j++
+			}
+			word := expr[i:j]
+			switch strings.ToLower(word) {
+			case "and":
+				tokens = append(tokens, queryToken{"op", "&&"})
+			case "or":
+				tokens = append(tokens, queryToken{"op", "||"})
+			case "not":
+				tokens = append(tokens, queryToken{"op", "!"})
+			case "contains":
+				tokens = append(tokens, queryToken{"op", "contains"})
+			default:
+				tokens = append(tokens, queryToken{"ident", word})
+			}
+			i = j
+		default:
+			matched := false
+			for _, op := range queryOperators {
+				if strings.HasPrefix(expr[i:], op) {
+					tokens = append(tokens, queryToken{"op", op})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, i+1)
+			}
+		}
+	}
+	return tokens, nil
+}
+
+// queryParser es un analizador descendente recursivo: || < && < ! < comparación.
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+}
+
+func (p *queryParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *queryParser) peek() queryToken {
+	if p.done() {
+		return queryToken{"op", "end of expression"}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *queryParser) accept(op string) bool {
+	if !p.done() && p.tokens[p.pos].kind == "op" && p.tokens[p.pos].value == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) parseOr() (queryNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = logicNode{and: false, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseAnd() (queryNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = logicNode{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseUnary() (queryNode, error) {
+	if p.accept("!") {
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{inner}, nil
+	}
+	if p.accept("(") {
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, fmt.Errorf("expected \")\" but found %s", p.peek())
+		}
+		return inner, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *queryParser) parseComparison() (queryNode, error) {
+	field := p.peek()
+	if field.kind != "ident" {
+		return nil, fmt.Errorf("expected a field name but found %s", field)
+	}
+	p.pos++
+	fieldType, ok := queryFieldType(field.value)
+	if !ok {
+		return nil, fmt.Errorf("unknown field %q", field.value)
+	}
+
+	op := p.peek()
+	if op.kind != "op" || !isComparison(op.value) {
+		// Un campo booleano solo equivale a "campo == true"
+		if fieldType != "bool" {
+			return nil, fmt.Errorf("expected an operator after %s", field.value)
+		}
+		return compareNode{field: field.value, fieldType: fieldType, op: "==", value: true}, nil
+	}
+	p.pos++
+
+	literal := p.peek()
+	if p.done() || literal.kind == "op" {
+		return nil, fmt.Errorf("expected a value after %s %s", field.value, op.value)
+	}
+	p.pos++
+	node := compareNode{field: field.value, fieldType: fieldType, op: op.value}
+	if err := node.setValue(literal); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+func isComparison(op string) bool {
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=", "~", "!~", "contains":
+		return true
+	}
+	return false
+}
+
+func queryFieldType(name string) (string, bool) {
+	for _, field := range QueryFieldList {
+		if field.Name == name {
+			return field.Type, true
+		}
+	}
+	return "", false
+}
+
+// Nodos del árbol de la expresión.
+type queryNode interface {
+	eval(fields QueryFields) (bool, error)
+}
+
+type logicNode struct {
+	and         bool
+	left, right queryNode
+}
+
+func (n logicNode) eval(fields QueryFields) (bool, error) {
+	left, err := n.left.eval(fields)
+	if err != nil {
+		return false, err
+	}
+	if left != n.and {
+		return left, nil
+	}
+	return n.right.eval(fields)
+}
+
+type notNode struct {
+	inner queryNode
+}
+
+func (n notNode) eval(fields QueryFields) (bool, error) {
+	value, err := n.inner.eval(fields)
+	return !value, err
+}
+
+type compareNode struct {
+	field     string
+	fieldType string
+	op        string
+	value     interface{} // float64, string, bool o time.Time según el tipo del campo
+	pattern   *regexp.Regexp
+}
+
+// setValue convierte el literal al tipo del campo y comprueba que el operador tiene sentido.
+func (n *compareNode) setValue(literal queryToken) error {
+	invalid := fmt.Errorf("operator %s is not valid for %s field %s", n.op, n.fieldType, n.field)
+
+	if n.op == "~" || n.op == "!~" {
+		if n.fieldType != "string" && n.fieldType != "list" {
+			return invalid
+		}
+		pattern, err := regexp.Compile(literal.value)
+		if err != nil {
+			return fmt.Errorf("invalid regular expression %q: %v", literal.value, err)
+		}
+		n.pattern = pattern
+		return nil
+	}
+	if n.op == "contains" {
+		if n.fieldType != "string" && n.fieldType != "list" {
+			return invalid
+		}
+		if n.fieldType == "list" {
+			n.pattern = globPattern(literal.value)
+		}
+	}
+
+	switch n.fieldType {
+	case "number":
+		number, err := strconv.ParseFloat(literal.value, 64)
+		if err != nil || literal.kind == "string" {
+			return fmt.Errorf("%s expects a number, got %s", n.field, literal)
+		}
+		n.value = number
+	case "bool":
+		if n.op != "==" && n.op != "!=" {
+			return invalid
+		}
+		switch strings.ToLower(literal.value) {
+		case "true":
+			n.value = true
+		case "false":
+			n.value = false
+		default:
+			return fmt.Errorf("%s expects true or false, got %s", n.field, literal)
+		}
+	case "time":
+		if n.op == "contains" {
+			return invalid
+		}
+		at, err := parseQueryTime(literal.value)
+		if err != nil {
+			return fmt.Errorf("%s expects a date (YYYY-MM-DD or RFC3339), got %s", n.field, literal)
+		}
+		n.value = at
+	default:
+		if n.fieldType == "list" && n.op != "==" && n.op != "!=" && n.op != "contains" {
+			return invalid
+		}
+		n.value = literal.value
+	}
+	return nil
+}
+
+// globPattern convierte un comodín (* y ?) en una expresión regular que compara el elemento completo
+// sin distinguir mayúsculas. A diferencia de path.Match, * también abarca "/" y ".".
+func globPattern(glob string) *regexp.Regexp {
+	quoted := regexp.QuoteMeta(glob)
+	quoted = strings.ReplaceAll(quoted, `\*`, ".*")
+	quoted = strings.ReplaceAll(quoted, `\?`, ".")
+	return regexp.MustCompile("(?i)^" + quoted + "$")
+}
+
+func parseQueryTime(value string) (time.Time, error) {
+	if at, err := time.Parse(time.RFC3339, value); err == nil {
+		return at, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
+
+func (n compareNode) eval(fields QueryFields) (bool, error) {
+	raw, ok := fields[n.field]
+	if !ok || raw == nil {
+		return false, nil
+	}
+
+	switch actual := raw.(type) {
+	case float64:
+		return compareOrdered(n.op, actual, n.value.(float64)), nil
+	case bool:
+		return (actual == n.value.(bool)) == (n.op == "=="), nil
+	case time.Time:
+		expected := n.value.(time.Time)
+		return compareOrdered(n.op, float64(actual.Unix()), float64(expected.Unix())), nil
+	case string:
+		switch n.op {
+		case "~":
+			return n.pattern.MatchString(actual), nil
+		case "!~":
+			return !n.pattern.MatchString(actual), nil
+		case "contains":
+			return strings.Contains(strings.ToLower(actual), strings.ToLower(n.value.(string))), nil
+		case "==":
+			return actual == n.value.(string), nil
+		case "!=":
+			return actual != n.value.(string), nil
+		default:
+			return compareStrings(n.op, actual, n.value.(string)), nil
+		}
+	case []string:
+		return n.evalList(actual), nil
+	default:
+		return false, fmt.Errorf("unsupported value for field %s", n.field)
+	}
+}
+
+// evalList aplica el operador a una lista: ==, ~ y contains se cumplen si algún elemento coincide;
+// != y !~ si no coincide ninguno. contains compara el elemento completo y admite comodines (*.corp).
+func (n compareNode) evalList(values []string) bool {
+	matched := false
+	for _, value := range values {
+		switch n.op {
+		case "~", "!~":
+			matched = n.pattern.MatchString(value)
+		case "contains":
+			matched = n.pattern.MatchString(value)
+		default:
+			matched = value == n.value.(string)
+		}
+		if matched {
+			break
+		}
+	}
+	if n.op == "!=" || n.op == "!~" {
+		return !matched
+	}
+	return matched
+}
+
+func compareOrdered(op string, a, b float64) bool {
+	switch op {
+	case "==":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	return false
+}
+
+func compareStrings(op string, a, b string) bool {
+	switch op {
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package internal
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// DefaultRemoteTimeout es el tiempo máximo por defecto para conectar y completar el handshake.
+const DefaultRemoteTimeout = 10 * time.Second
+
+// RemoteOptions describe el endpoint TLS del que se obtiene la cadena.
+type RemoteOptions struct {
+	Address  string        // host:port; sin puerto se usa el 443 o el del protocolo STARTTLS
+	SNI      string        // nombre enviado en el ClientHello; por defecto, el host
+	Timeout  time.Duration // límite para la conexión y el handshake
+	StartTLS string        // protocolo a negociar antes del handshake (smtp, imap, ldap...)
+}
+
+// RemoteHost devuelve el host y el puerto de una dirección, añadiendo defaultPort si falta.
+func RemoteHost(address, defaultPort string) (string, string) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return address, defaultPort
+	}
+	return host, port
+}
+
+// FetchRemoteChain realiza un handshake TLS y devuelve la cadena tal y como la presenta el servidor,
+// sin validarla: la verificación corresponde a quien la consume.
+func FetchRemoteChain(opts RemoteOptions) ([]*x509.Certificate, error) {
+	return FetchRemoteChainContext(context.Background(), opts)
+}
+
+// FetchRemoteChainContext es FetchRemoteChain con un contexto que puede cancelar la conexión y el
+// handshake antes de que venza opts.Timeout.
+func FetchRemoteChainContext(ctx context.Context, opts RemoteOptions) ([]*x509.Certificate, error) {
+	conn, address, sni, err := dialRemote(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	tlsConn := tls.Client(conn, &tls.Config{
+		ServerName: sni,
+		// Solo se captura la cadena; no se confía en ella
+		InsecureSkipVerify: true,
+	})
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("TLS handshake with %s failed: %v", address, err)
+	}
+
+	chain := tlsConn.ConnectionState().PeerCertificates
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("%s did not present any certificate", address)
+	}
+	return chain, nil
+}
+
+// dialRemote abre la conexión TCP con el endpoint, fija el plazo máximo y negocia STARTTLS si se
+// ha pedido. Devuelve la conexión lista para el ClientHello, la dirección y el nombre para SNI.
+func dialRemote(ctx context.Context, opts RemoteOptions) (net.Conn, string, string, error) {
+	if opts.Address == "" {
+		return nil, "", "", errors.New("remote address cannot be empty")
+	}
+	defaultPort := "443"
+	if opts.StartTLS != "" {
+		port, ok := starttlsPorts[opts.StartTLS]
+		if !ok {
+			return nil, "", "", fmt.Errorf("unsupported STARTTLS protocol: %s (supported: %s)", opts.StartTLS, strings.Join(StartTLSProtocols(), ", "))
+		}
+		defaultPort = port
+	}
+	host, port := RemoteHost(opts.Address, defaultPort)
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultRemoteTimeout
+	}
+	sni := opts.SNI
+	if sni == "" {
+		sni = host
+	}
+
+	address := net.JoinHostPort(host, port)
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, "", "", ctx.Err()
+		}
+		return nil, "", "", fmt.Errorf("error connecting to %s: %v", address, err)
+	}
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		conn.Close()
+		return nil, "", "", err
+	}
+
+	if opts.StartTLS != "" {
+		// Cancelar el contexto interrumpe también la negociación de STARTTLS
+		stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Unix(1, 0)) })
+		err := startTLS(conn, opts.StartTLS, sni)
+		stop()
+		if err != nil {
+			conn.Close()
+			if ctx.Err() != nil {
+				return nil, "", "", ctx.Err()
+			}
+			return nil, "", "", err
+		}
+	}
+	return conn, address, sni, nil
+}
@@ -0,0 +1,414 @@
+package internal
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Formatos del informe.
+const (
+	ReportCSV      = "csv"
+	ReportHTML     = "html"
+	ReportMarkdown = "markdown"
+)
+
+// Tramos de caducidad del informe, en orden.
+var reportBuckets = []struct {
+	name string
+	days int // límite superior (exclusivo); -1 para el último tramo
+}{
+	{"expired", 0},
+	{"under 7 days", 7},
+	{"under 30 days", 30},
+	{"under 90 days", 90},
+	{"90 days or more", -1},
+}
+
+// ReportNoOwner agrupa los certificados sin propietario en el informe.
+const ReportNoOwner = "(no owner)"
+
+// ReportCertificate es una fila del informe.
+type ReportCertificate struct {
+	Name        string
+	Subject     string
+	Issuer      string
+	SANs        []string
+	Key         string // p. ej. "RSA 2048" o "ECDSA P-256"
+	Signature   string
+	Serial      string
+	Fingerprint string
+	NotAfter    time.Time
+	Days        int
+	Bucket      string
+	Sources     []string
+	Owner       string
+	Findings    []string
+}
+
+// ReportCount es una fila de un desglose (tramo de caducidad o emisor).
+type ReportCount struct {
+	Name  string
+	Count int
+}
+
+// ReportOwner agrupa los certificados de un propietario.
+type ReportOwner struct {
+	Owner        string
+	Certificates []ReportCertificate
+}
+
+// Report es el informe de certificados ya calculado.
+type Report struct {
+	GeneratedAt  time.Time
+	Source       string
+	Certificates []ReportCertificate
+	Buckets      []ReportCount
+	Issuers      []ReportCount
+	Findings     []ReportCertificate // certificados con algún problema de criptografía débil
+	Owners       []ReportOwner
+}
+
+// NewReportCertificate construye una fila a partir del modelo de campos de --where.
+func NewReportCertificate(fields QueryFields) ReportCertificate {
+	str := func(name string) string {
+		value, _ := fields[name].(string)
+		return value
+	}
+	list := func(name string) []string {
+		value, _ := fields[name].([]string)
+		return value
+	}
+
+	c := ReportCertificate{
+		Name:        str("subject.cn"),
+		Subject:     str("subject"),
+		Issuer:      str("issuer.cn"),
+		SANs:        list("san"),
+		Signature:   str("signature"),
+		Serial:      str("serial"),
+		Fingerprint: str("fingerprint"),
+		Sources:     list("source"),
+		Owner:       str("owner"),
+	}
+	if c.Name == "" {
+		c.Name = c.Subject
+	}
+	if c.Issuer == "" {
+		c.Issuer = str("issuer")
+	}
+	c.NotAfter, _ = fields["not_after"].(time.Time)
+	days, _ := fields["days"].(float64)
+	c.Days = int(days)
+	if expired, _ := fields["expired"].(bool); expired {
+		c.Bucket = reportBuckets[0].name
+	} else {
+		for _, bucket := range reportBuckets[1:] {
+			if bucket.days < 0 || c.Days < bucket.days {
+				c.Bucket = bucket.name
+				break
+			}
+		}
+	}
+
+	keyType, keySize := str("key.type"), 0
+	if size, ok := fields["key.size"].(float64); ok {
+		keySize = int(size)
+	}
+	switch {
+	case keyType == "":
+		c.Key = "unknown"
+	case str("key.curve") != "":
+		c.Key = keyType + " " + str("key.curve")
+	default:
+		c.Key = fmt.Sprintf("%s %d", keyType, keySize)
+	}
+	c.Findings = weakCryptoFindings(keyType, keySize, c.Signature)
+	return c
+}
+
+// weakCryptoFindings señala claves cortas y algoritmos de firma obsoletos.
+func weakCryptoFindings(keyType string, keySize int, signature string) []string {
+	var findings []string
+	switch {
+	case keyType == "RSA" && keySize < 2048:
+		findings = append(findings, fmt.Sprintf("RSA key of %d bits (minimum 2048)", keySize))
+	case keyType == "ECDSA" && keySize < 256:
+		findings = append(findings, fmt.Sprintf("ECDSA key of %d bits (minimum 256)", keySize))
+	}
+	upper := strings.ToUpper(signature)
+	switch {
+	case strings.HasPrefix(upper, "MD2"), strings.HasPrefix(upper, "MD5"), strings.HasPrefix(upper, "SHA1"), strings.HasPrefix(upper, "ECDSA-SHA1"):
+		findings = append(findings, "weak signature algorithm "+signature)
+	case strings.HasPrefix(upper, "DSA"):
+		findings = append(findings, "DSA signature "+signature)
+	}
+	return findings
+}
+
+// BuildReport agrupa los certificados por tramo de caducidad, emisor y propietario.
+func BuildReport(certs []ReportCertificate, source string, at time.Time) Report {
+	if at.IsZero() {
+		at = time.Now()
+	}
+	sort.SliceStable(certs, func(i, j int) bool { return certs[i].NotAfter.Before(certs[j].NotAfter) })
+	report := Report{GeneratedAt: at, Source: source, Certificates: certs}
+
+	bucketCounts := map[string]int{}
+	issuerCounts := map[string]int{}
+	owners := map[string][]ReportCertificate{}
+	for _, c := range certs {
+		bucketCounts[c.Bucket]++
+		issuerCounts[c.Issuer]++
+		owner := c.Owner
+		if owner == "" {
+			owner = ReportNoOwner
+		}
+		owners[owner] = append(owners[owner], c)
+		if len(c.Findings) > 0 {
+			report.Findings = append(report.Findings, c)
+		}
+	}
+
+	for _, bucket := range reportBuckets {
+		report.Buckets = append(report.Buckets, ReportCount{Name: bucket.name, Count: bucketCounts[bucket.name]})
+	}
+	for issuer, count := range issuerCounts {
+		report.Issuers = append(report.Issuers, ReportCount{Name: issuer, Count: count})
+	}
+	sort.Slice(report.Issuers, func(i, j int) bool {
+		a, b := report.Issuers[i], report.Issuers[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Name < b.Name
+	})
+	for owner, certs := range owners {
+		report.Owners = append(report.Owners, ReportOwner{Owner: owner, Certificates: certs})
+	}
+	// Los certificados sin propietario van al final
+	sort.Slice(report.Owners, func(i, j int) bool {
+		a, b := report.Owners[i].Owner, report.Owners[j].Owner
+		if (a == ReportNoOwner) != (b == ReportNoOwner) {
+			return b == ReportNoOwner
+		}
+		return a < b
+	})
+	return report
+}
+
+// WriteReport escribe el informe en el formato indicado.
+func WriteReport(w io.Writer, report Report, format string) error {
+	switch format {
+	case ReportCSV:
+		return writeReportCSV(w, report)
+	case ReportHTML:
+		return reportHTMLTemplate.Execute(w, report)
+	case ReportMarkdown:
+		return writeReportMarkdown(w, report)
+	default:
+		return fmt.Errorf("unsupported report format: %s (expected csv, html or markdown)", format)
+	}
+}
+
+// writeReportCSV escribe cada sección como una tabla precedida de su título y separada por una línea
+// en blanco, de modo que se abre directamente en una hoja de cálculo. La tabla de certificados lleva
+// la columna del propietario para poder filtrar por ella.
+func writeReportCSV(w io.Writer, report Report) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"Certificate report", report.GeneratedAt.UTC().Format(time.RFC3339), report.Source})
+	out.Write(nil)
+
+	out.Write([]string{"Expiry"})
+	out.Write([]string{"bucket", "certificates"})
+	for _, bucket := range report.Buckets {
+		out.Write([]string{bucket.Name, strconv.Itoa(bucket.Count)})
+	}
+	out.Write(nil)
+
+	out.Write([]string{"Issuers"})
+	out.Write([]string{"issuer", "certificates"})
+	for _, issuer := range report.Issuers {
+		out.Write([]string{csvCell(issuer.Name), strconv.Itoa(issuer.Count)})
+	}
+	out.Write(nil)
+
+	out.Write([]string{"Weak cryptography"})
+	out.Write([]string{"owner", "name", "fingerprint", "finding"})
+	for _, c := range report.Findings {
+		for _, finding := range c.Findings {
+			out.Write([]string{csvCell(c.Owner), csvCell(c.Name), c.Fingerprint, finding})
+		}
+	}
+	out.Write(nil)
+
+	out.Write([]string{"Certificates"})
+	out.Write([]string{"owner", "name", "not_after", "days", "bucket", "issuer", "key", "signature", "sans", "sources", "serial", "fingerprint"})
+	for _, owner := range report.Owners {
+		for _, c := range owner.Certificates {
+			out.Write([]string{
+				csvCell(c.Owner), csvCell(c.Name), c.NotAfter.UTC().Format(time.RFC3339), strconv.Itoa(c.Days), c.Bucket, csvCell(c.Issuer),
+				c.Key, c.Signature, csvCell(strings.Join(c.SANs, ";")), csvCell(strings.Join(c.Sources, ";")), c.Serial, c.Fingerprint,
+			})
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// csvCell neutraliza los valores que una hoja de cálculo interpretaría como fórmula (CSV injection):
+// los nombres vienen de los certificados, incluidos los de servidores remotos, así que un CN como
+// "=HYPERLINK(...)" se guarda con un apóstrofo delante.
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func writeReportMarkdown(w io.Writer, report Report) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Certificate report\n\n")
+	fmt.Fprintf(&b, "Generated %s from %s: %d certificate(s).\n\n", report.GeneratedAt.UTC().Format(time.RFC3339), report.Source, len(report.Certificates))
+
+	b.WriteString("## Expiry\n\n| Bucket | Certificates |\n|---|---:|\n")
+	for _, bucket := range report.Buckets {
+		fmt.Fprintf(&b, "| %s | %d |\n", bucket.Name, bucket.Count)
+	}
+
+	b.WriteString("\n## Issuers\n\n| Issuer | Certificates |\n|---|---:|\n")
+	for _, issuer := range report.Issuers {
+		fmt.Fprintf(&b, "| %s | %d |\n", markdownCell(issuer.Name), issuer.Count)
+	}
+
+	b.WriteString("\n## Weak cryptography\n\n")
+	if len(report.Findings) == 0 {
+		b.WriteString("No findings.\n")
+	} else {
+		b.WriteString("| Certificate | Owner | Finding |\n|---|---|---|\n")
+		for _, c := range report.Findings {
+			for _, finding := range c.Findings {
+				fmt.Fprintf(&b, "| %s | %s | %s |\n", markdownCell(c.Name), markdownCell(c.Owner), markdownCell(finding))
+			}
+		}
+	}
+
+	for _, owner := range report.Owners {
+		fmt.Fprintf(&b, "\n## Owner: %s\n\n", owner.Owner)
+		b.WriteString("| Certificate | Expires | Days | Issuer | Key | Sources |\n|---|---|---:|---|---|---|\n")
+		for _, c := range owner.Certificates {
+			fmt.Fprintf(&b, "| %s | %s | %d | %s | %s | %s |\n", markdownCell(c.Name), c.NotAfter.UTC().Format(time.DateOnly), c.Days,
+				markdownCell(c.Issuer), c.Key, markdownCell(strings.Join(c.Sources, ", ")))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// markdownCell escapa los caracteres que romperían una celda de una tabla Markdown.
+func markdownCell(value string) string {
+	value = strings.ReplaceAll(value, "|", `\|`)
+	return strings.ReplaceAll(value, "\n", " ")
+}
+
+// reportHTMLTemplate genera un único archivo HTML sin recursos externos. Las tablas se ordenan al
+// pulsar la cabecera; las celdas con data-sort se ordenan por ese valor numérico.
+var reportHTMLTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"date":  func(t time.Time) string { return t.UTC().Format(time.DateOnly) },
+	"stamp": func(t time.Time) string { return t.UTC().Format(time.RFC3339) },
+	"join":  strings.Join,
+	"unix":  func(t time.Time) int64 { return t.Unix() },
+	"slug":  func(s string) string { return strings.ReplaceAll(s, " ", "-") },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Certificate report</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+th { background: #f0f0f0; cursor: pointer; user-select: none; }
+th.asc::after { content: " \25B2"; }
+th.desc::after { content: " \25BC"; }
+td.num { text-align: right; }
+tr.expired td { background: #fdd; }
+tr.under-7-days td { background: #fec; }
+tr.under-30-days td { background: #ffd; }
+</style>
+</head>
+<body>
+<h1>Certificate report</h1>
+<p>Generated {{stamp .GeneratedAt}} from {{.Source}}: {{len .Certificates}} certificate(s).</p>
+
+<h2>Expiry</h2>
+<table class="sortable">
+<thead><tr><th>Bucket</th><th>Certificates</th></tr></thead>
+<tbody>
+{{- range .Buckets}}
+<tr><td>{{.Name}}</td><td class="num" data-sort="{{.Count}}">{{.Count}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+
+<h2>Issuers</h2>
+<table class="sortable">
+<thead><tr><th>Issuer</th><th>Certificates</th></tr></thead>
+<tbody>
+{{- range .Issuers}}
+<tr><td>{{.Name}}</td><td class="num" data-sort="{{.Count}}">{{.Count}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+
+<h2>Weak cryptography</h2>
+{{- if .Findings}}
+<table class="sortable">
+<thead><tr><th>Certificate</th><th>Owner</th><th>Key</th><th>Signature</th><th>Finding</th></tr></thead>
+<tbody>
+{{- range $c := .Findings}}{{range .Findings}}
+<tr><td>{{$c.Name}}</td><td>{{$c.Owner}}</td><td>{{$c.Key}}</td><td>{{$c.Signature}}</td><td>{{.}}</td></tr>
+{{- end}}{{end}}
+</tbody>
+</table>
+{{- else}}
+<p>No findings.</p>
+{{- end}}
+{{range .Owners}}
+<h2>Owner: {{.Owner}}</h2>
+<table class="sortable">
+<thead><tr><th>Certificate</th><th>Expires</th><th>Days</th><th>Issuer</th><th>Key</th><th>SANs</th><th>Sources</th><th>Fingerprint</th></tr></thead>
+<tbody>
+{{- range .Certificates}}
+<tr class="{{slug .Bucket}}"><td>{{.Name}}</td><td data-sort="{{unix .NotAfter}}">{{date .NotAfter}}</td><td class="num" data-sort="{{.Days}}">{{.Days}}</td><td>{{.Issuer}}</td><td>{{.Key}}</td><td>{{join .SANs ", "}}</td><td>{{join .Sources ", "}}</td><td><code>{{.Fingerprint}}</code></td></tr>
+{{- end}}
+</tbody>
+</table>
+{{- end}}
+<script>
+document.querySelectorAll("table.sortable th").forEach(function (th) {
+  th.addEventListener("click", function () {
+    var table = th.closest("table"), body = table.tBodies[0];
+    var index = Array.prototype.indexOf.call(th.parentNode.children, th);
+    var asc = !th.classList.contains("asc");
+    table.querySelectorAll("th").forEach(function (h) { h.classList.remove("asc", "desc"); });
+    th.classList.add(asc ? "asc" : "desc");
+    var key = function (row) {
+      var cell = row.children[index];
+      return cell.dataset.sort !== undefined ? parseFloat(cell.dataset.sort) : cell.textContent.toLowerCase();
+    };
+    Array.from(body.rows).sort(function (a, b) {
+      var x = key(a), y = key(b);
+      return (x < y ? -1 : x > y ? 1 : 0) * (asc ? 1 : -1);
+    }).forEach(function (row) { body.appendChild(row); });
+  });
+});
+</script>
+</body>
+</html>
+`))
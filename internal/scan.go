@@ -0,0 +1,163 @@
+package internal
+
+import (
+	"crypto/x509"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultScanInclude son los patrones de archivo que se revisan si no se indica otra cosa.
+var DefaultScanInclude = []string{"*.pem", "*.crt", "*.cer", "*.cert"}
+
+// ScanOptions controla el recorrido de un directorio en busca de certificados.
+type ScanOptions struct {
+	Root      string
+	Recursive bool
+	Include   []string // patrones glob sobre el nombre del archivo
+	Workers   int      // número de lectores en paralelo; 0 usa el número de CPUs
+}
+
+// ScanResult es un certificado encontrado (o el error al leer un archivo).
+type ScanResult struct {
+	Path  string
+	Index int // posición del certificado dentro del archivo
+	Cert  *x509.Certificate
+	Err   error
+}
+
+// ScanCertificates recorre un directorio y lee en paralelo todos los certificados de los archivos
+// que coinciden con los patrones. Los archivos sin certificados (por ejemplo, claves) se ignoran, y
+// los archivos y subdirectorios que no se pueden leer se devuelven como resultados con Err.
+func ScanCertificates(opts ScanOptions) ([]ScanResult, error) {
+	info, err := os.Stat(opts.Root)
+	if err != nil {
+		return nil, fmt.Errorf("error reading path: %v", err)
+	}
+
+	include := opts.Include
+	if len(include) == 0 {
+		include = DefaultScanInclude
+	}
+	for _, pattern := range include {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid include pattern %q: %v", pattern, err)
+		}
+	}
+
+	var files []string
+	var walkErrors []ScanResult
+	if !info.IsDir() {
+		files = []string{opts.Root}
+	} else {
+		// Un subdirectorio o archivo ilegible se anota como resultado con error y se sigue con el
+		// resto; solo se aborta si no se puede leer la raíz
+		err = filepath.WalkDir(opts.Root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if path == opts.Root {
+					return err
+				}
+				walkErrors = append(walkErrors, ScanResult{Path: path, Err: fmt.Errorf("error reading path: %v", err)})
+				if d != nil && d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() {
+				if path != opts.Root && !opts.Recursive {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if matchesAny(d.Name(), include) {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error scanning %s: %v", opts.Root, err)
+		}
+	}
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	paths := make(chan string)
+	perFile := make([][]ScanResult, len(files))
+	index := make(map[string]int, len(files))
+	for i, path := range files {
+		index[path] = i
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				perFile[index[path]] = readScanFile(path)
+			}
+		}()
+	}
+	for _, path := range files {
+		paths <- path
+	}
+	close(paths)
+	wg.Wait()
+
+	var results []ScanResult
+	for _, r := range perFile {
+		results = append(results, r...)
+	}
+	return append(results, walkErrors...), nil
+}
+
+// readScanFile lee todos los certificados de un archivo.
+func readScanFile(path string) []ScanResult {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return []ScanResult{{Path: path, Err: fmt.Errorf("error reading file: %v", err)}}
+	}
+	if !strings.Contains(string(data), "-----BEGIN CERTIFICATE-----") {
+		return nil
+	}
+	certs, err := ParseCertificates(data)
+	if err != nil {
+		return []ScanResult{{Path: path, Err: err}}
+	}
+	results := make([]ScanResult, len(certs))
+	for i, cert := range certs {
+		results[i] = ScanResult{Path: path, Index: i, Cert: cert}
+	}
+	return results
+}
+
+// SortByExpiration ordena los resultados por fecha de caducidad, dejando los errores al final.
+func SortByExpiration(results []ScanResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if (a.Err == nil) != (b.Err == nil) {
+			return a.Err == nil
+		}
+		if a.Err != nil {
+			return a.Path < b.Path
+		}
+		return a.Cert.NotAfter.Before(b.Cert.NotAfter)
+	})
+}
+
+func matchesAny(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(strings.TrimSpace(pattern), name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,512 @@
+package internal
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/smallstep/pkcs7"
+	"github.com/smallstep/scep"
+	"github.com/smallstep/scep/cryptoutil"
+	"github.com/smallstep/scep/x509util"
+)
+
+// Valores por defecto del cliente SCEP.
+const (
+	DefaultSCEPTimeout      = 30 * time.Second
+	DefaultSCEPPollInterval = 10 * time.Second
+	DefaultSCEPPollTimeout  = 10 * time.Minute
+	scepMaxResponse         = 1 << 20
+)
+
+// SCEPOptions describe el servidor SCEP (RFC 8894) y cómo esperar una emisión pendiente.
+type SCEPOptions struct {
+	ServerURL     string        // URL del servicio, p. ej. http://mdm.example.com/scep o .../pkiclient.exe
+	CAIdentifier  string        // parámetro message de GetCACert y GetCACaps, opcional
+	CAFingerprint string        // huella SHA-256 en hex de la CA esperada; vacío no la comprueba
+	Roots         string        // CA en PEM para verificar el servidor si es https
+	Timeout       time.Duration // tiempo máximo de cada petición
+	PollInterval  time.Duration
+	PollTimeout   time.Duration                            // tiempo máximo esperando una petición pendiente
+	Logf          func(format string, args ...interface{}) // progreso; nil no muestra nada
+	// Resume sondea con CertPoll una petición que quedó pendiente, sin volver a enviar el PKCSReq
+	Resume bool
+}
+
+// SCEPPendingError indica que la CA sigue sin aprobar la petición al agotarse PollTimeout. El
+// identificador de transacción se deriva de la clave, así que con Resume y la misma clave se puede
+// seguir sondeando la misma transacción.
+type SCEPPendingError struct {
+	TransactionID string
+}
+
+func (e *SCEPPendingError) Error() string {
+	return fmt.Sprintf("the SCEP request is still pending approval (transaction %s); resume it with the same key to keep polling", e.TransactionID)
+}
+
+// Atributos firmados de SCEP que hacen falta para construir CertPoll, que la librería no genera.
+var (
+	oidSCEPMessageType   = asn1.ObjectIdentifier{2, 16, 840, 1, 113733, 1, 9, 2}
+	oidSCEPSenderNonce   = asn1.ObjectIdentifier{2, 16, 840, 1, 113733, 1, 9, 5}
+	oidSCEPTransactionID = asn1.ObjectIdentifier{2, 16, 840, 1, 113733, 1, 9, 7}
+)
+
+// scepIssuerAndSubject es el contenido de CertPoll: el emisor esperado y el sujeto de la petición.
+type scepIssuerAndSubject struct {
+	Issuer  asn1.RawValue
+	Subject asn1.RawValue
+}
+
+var scepFailInfos = map[scep.FailInfo]string{
+	scep.BadAlg:          "badAlg",
+	scep.BadMessageCheck: "badMessageCheck",
+	scep.BadRequest:      "badRequest",
+	scep.BadTime:         "badTime",
+	scep.BadCertID:       "badCertID",
+}
+
+// Los algoritmos de pkcs7 son variables globales; se protegen mientras se construye cada mensaje.
+var scepAlgorithmsMu sync.Mutex
+
+// SCEPCACaps devuelve las capacidades que anuncia el servidor (GetCACaps). Un servidor que no
+// implementa la operación devuelve una lista vacía.
+func SCEPCACaps(opts SCEPOptions) ([]string, error) {
+	client, err := newSCEPClient(opts)
+	if err != nil {
+		return nil, err
+	}
+	return scepCACaps(client, opts), nil
+}
+
+// SCEPCACerts descarga los certificados de la CA y, si los hay, de la RA (GetCACert) y comprueba
+// CAFingerprint si se indica.
+func SCEPCACerts(opts SCEPOptions) ([]*x509.Certificate, error) {
+	client, err := newSCEPClient(opts)
+	if err != nil {
+		return nil, err
+	}
+	return scepCACerts(client, opts)
+}
+
+// SCEPEnroll pide un certificado para el CSR en DER. La petición se firma con un certificado
+// autofirmado de key, lleva challenge como challengePassword y se cifra para la CA o la RA. Si la
+// CA deja la petición pendiente, se sondea con CertPoll (RFC 8894, sección 3.3.3) y la misma
+// transacción cada PollInterval hasta PollTimeout; el challenge solo se envía en el PKCSReq.
+func SCEPEnroll(opts SCEPOptions, csrDER []byte, key crypto.Signer, challenge string) (*x509.Certificate, error) {
+	if _, ok := key.(*rsa.PrivateKey); !ok {
+		return nil, errors.New("SCEP requires an RSA key")
+	}
+	csr, err := x509.ParseCertificateRequest(csrDER)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing CSR: %v", err)
+	}
+	pub, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return nil, fmt.Errorf("error encoding public key: %v", err)
+	}
+	if !bytes.Equal(csr.RawSubjectPublicKeyInfo, pub) {
+		return nil, errors.New("the private key does not match the CSR")
+	}
+	logf := opts.Logf
+	if logf == nil {
+		logf = func(string, ...interface{}) {}
+	}
+	client, err := newSCEPClient(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	caps := scepCACaps(client, opts)
+	caCerts, err := scepCACerts(client, opts)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := scepSignerCertificate(csr, key)
+	if err != nil {
+		return nil, err
+	}
+	transactionID, err := scepTransactionID(csr)
+	if err != nil {
+		return nil, err
+	}
+	recipients := scepRecipients(caCerts)
+
+	interval, timeout := opts.PollInterval, opts.PollTimeout
+	if interval <= 0 {
+		interval = DefaultSCEPPollInterval
+	}
+	if timeout <= 0 {
+		timeout = DefaultSCEPPollTimeout
+	}
+	deadline := time.Now().Add(timeout)
+	poll := opts.Resume
+	for {
+		var raw, nonce []byte
+		if poll {
+			if raw, nonce, err = newSCEPCertPoll(transactionID, scepIssuer(caCerts), csr, signer, key, recipients, caps); err != nil {
+				return nil, err
+			}
+		} else {
+			request, err := scepRequestCSR(csr, key, challenge)
+			if err != nil {
+				return nil, err
+			}
+			msg, err := newSCEPRequest(request, signer, key, recipients, caps)
+			if err != nil {
+				return nil, err
+			}
+			if msg.TransactionID != transactionID {
+				return nil, errors.New("unexpected SCEP transaction ID for the CSR key")
+			}
+			raw, nonce = msg.Raw, msg.SenderNonce
+			// Las siguientes consultas de la misma transacción son CertPoll
+			poll = true
+		}
+		data, err := scepPKIOperation(client, opts, caps, raw)
+		if err != nil {
+			return nil, fmt.Errorf("error enrolling: %v", err)
+		}
+		reply, err := scep.ParsePKIMessage(data, scep.WithCACerts(caCerts))
+		if err != nil {
+			return nil, fmt.Errorf("error parsing SCEP response: %v", err)
+		}
+		if reply.MessageType != scep.CertRep || reply.CertRepMessage == nil {
+			return nil, fmt.Errorf("unexpected SCEP message type %s in the response", string(reply.MessageType))
+		}
+		if reply.TransactionID != transactionID || !bytes.Equal(reply.RecipientNonce, nonce) {
+			return nil, errors.New("the SCEP response does not belong to this request")
+		}
+
+		switch reply.PKIStatus {
+		case scep.SUCCESS:
+			if err := reply.DecryptPKIEnvelope(signer, key); err != nil {
+				return nil, fmt.Errorf("error decrypting SCEP response: %v", err)
+			}
+			if !bytes.Equal(reply.Certificate.RawSubjectPublicKeyInfo, csr.RawSubjectPublicKeyInfo) {
+				return nil, errors.New("the SCEP response does not contain a certificate for the CSR key")
+			}
+			return reply.Certificate, nil
+		case scep.FAILURE:
+			info, ok := scepFailInfos[reply.FailInfo]
+			if !ok {
+				info = string(reply.FailInfo)
+			}
+			return nil, fmt.Errorf("the SCEP server rejected the request: %s", info)
+		}
+
+		// PENDING: la CA aún no ha aprobado la petición
+		if time.Now().Add(interval).After(deadline) {
+			return nil, &SCEPPendingError{TransactionID: string(transactionID)}
+		}
+		logf("Request pending approval (transaction %s); polling again in %s", transactionID, interval)
+		time.Sleep(interval)
+	}
+}
+
+func newSCEPClient(opts SCEPOptions) (*http.Client, error) {
+	if opts.ServerURL == "" {
+		return nil, errors.New("a SCEP server URL is required")
+	}
+	if _, err := url.Parse(opts.ServerURL); err != nil {
+		return nil, fmt.Errorf("invalid SCEP server URL: %v", err)
+	}
+	transport := &http.Transport{Proxy: http.ProxyFromEnvironment}
+	if opts.Roots != "" {
+		roots, err := LoadCertificates(opts.Roots)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: x509.NewCertPool()}
+		for _, root := range roots {
+			transport.TLSClientConfig.RootCAs.AddCert(root)
+		}
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultSCEPTimeout
+	}
+	return &http.Client{Timeout: timeout, Transport: transport}, nil
+}
+
+// scepURL construye la URL de una operación con el parámetro message si no está vacío.
+func scepURL(opts SCEPOptions, operation, message string) string {
+	query := url.Values{"operation": {operation}}
+	if message != "" {
+		query.Set("message", message)
+	}
+	separator := "?"
+	if strings.Contains(opts.ServerURL, "?") {
+		separator = "&"
+	}
+	return opts.ServerURL + separator + query.Encode()
+}
+
+func scepGet(client *http.Client, target string) ([]byte, string, error) {
+	resp, err := client.Get(target)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, scepMaxResponse))
+	if err != nil {
+		return nil, "", fmt.Errorf("error reading response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", scepStatusError(resp, body)
+	}
+	return body, resp.Header.Get("Content-Type"), nil
+}
+
+func scepStatusError(resp *http.Response, body []byte) error {
+	message := strings.TrimSpace(string(body))
+	if len(message) > 200 {
+		message = message[:200] + "..."
+	}
+	return fmt.Errorf("unexpected status %s: %s", resp.Status, message)
+}
+
+func scepCACaps(client *http.Client, opts SCEPOptions) []string {
+	body, _, err := scepGet(client, scepURL(opts, "GetCACaps", opts.CAIdentifier))
+	if err != nil {
+		return nil
+	}
+	return strings.Fields(string(body))
+}
+
+func scepCACerts(client *http.Client, opts SCEPOptions) ([]*x509.Certificate, error) {
+	body, contentType, err := scepGet(client, scepURL(opts, "GetCACert", opts.CAIdentifier))
+	if err != nil {
+		return nil, fmt.Errorf("error fetching CA certificates: %v", err)
+	}
+	var certs []*x509.Certificate
+	if strings.HasPrefix(contentType, "application/x-x509-ca-cert") {
+		cert, err := x509.ParseCertificate(body)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing CA certificate: %v", err)
+		}
+		certs = []*x509.Certificate{cert}
+	} else if certs, err = ParsePKCS7Certificates(body); err != nil {
+		return nil, err
+	}
+
+	if opts.CAFingerprint != "" {
+		expected := strings.ToLower(strings.NewReplacer(":", "", " ", "").Replace(opts.CAFingerprint))
+		matched := false
+		for _, cert := range certs {
+			sum := sha256.Sum256(cert.Raw)
+			if hex.EncodeToString(sum[:]) == expected {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return nil, errors.New("no CA certificate from the SCEP server matches the expected fingerprint")
+		}
+	}
+	return certs, nil
+}
+
+// scepRecipients elige los certificados para los que se cifra la petición: los de cifrado de la
+// RA si el servidor publica CA y RA, o el de la CA si solo hay uno.
+func scepRecipients(certs []*x509.Certificate) []*x509.Certificate {
+	if len(certs) == 1 {
+		return certs
+	}
+	if selected := scep.EnciphermentCertsSelector().SelectCerts(certs); len(selected) > 0 {
+		return selected
+	}
+	return certs[:1]
+}
+
+func scepHasCap(caps []string, names ...string) bool {
+	for _, c := range caps {
+		for _, name := range names {
+			if strings.EqualFold(c, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// newSCEPRequest crea un PKCSReq con los algoritmos más fuertes que anuncia el servidor.
+func newSCEPRequest(csr *x509.CertificateRequest, signer *x509.Certificate, key crypto.Signer, recipients []*x509.Certificate, caps []string) (*scep.PKIMessage, error) {
+	scepAlgorithmsMu.Lock()
+	defer scepAlgorithmsMu.Unlock()
+	if err := setSCEPAlgorithms(caps); err != nil {
+		return nil, err
+	}
+
+	msg, err := scep.NewCSRRequest(csr, &scep.PKIMessage{
+		MessageType: scep.PKCSReq,
+		Recipients:  recipients,
+		SignerKey:   key,
+		SignerCert:  signer,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating SCEP request: %v", err)
+	}
+	return msg, nil
+}
+
+// newSCEPCertPoll crea un CertPoll (messageType 20) de la transacción indicada, con el
+// IssuerAndSubject de la petición cifrado para la CA o la RA. Devuelve el mensaje y su senderNonce.
+func newSCEPCertPoll(transactionID scep.TransactionID, issuer *x509.Certificate, csr *x509.CertificateRequest, signer *x509.Certificate, key crypto.Signer, recipients []*x509.Certificate, caps []string) ([]byte, []byte, error) {
+	content, err := asn1.Marshal(scepIssuerAndSubject{
+		Issuer:  asn1.RawValue{FullBytes: issuer.RawSubject},
+		Subject: asn1.RawValue{FullBytes: csr.RawSubject},
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("error encoding CertPoll: %v", err)
+	}
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, fmt.Errorf("error generating nonce: %v", err)
+	}
+
+	scepAlgorithmsMu.Lock()
+	defer scepAlgorithmsMu.Unlock()
+	if err := setSCEPAlgorithms(caps); err != nil {
+		return nil, nil, err
+	}
+	enveloped, err := pkcs7.Encrypt(content, recipients)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error encrypting CertPoll: %v", err)
+	}
+	signed, err := pkcs7.NewSignedData(enveloped)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creating CertPoll: %v", err)
+	}
+	err = signed.AddSigner(signer, key, pkcs7.SignerInfoConfig{ExtraSignedAttributes: []pkcs7.Attribute{
+		{Type: oidSCEPTransactionID, Value: transactionID},
+		{Type: oidSCEPMessageType, Value: scep.CertPoll},
+		{Type: oidSCEPSenderNonce, Value: scep.SenderNonce(nonce)},
+	}})
+	if err != nil {
+		return nil, nil, fmt.Errorf("error signing CertPoll: %v", err)
+	}
+	raw, err := signed.Finish()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creating CertPoll: %v", err)
+	}
+	return raw, nonce, nil
+}
+
+// setSCEPAlgorithms elige los algoritmos más fuertes que anuncia el servidor. Se llama con
+// scepAlgorithmsMu bloqueado.
+func setSCEPAlgorithms(caps []string) error {
+	// RFC 8894: SCEPStandard implica AES y SHA-256
+	standard := scepHasCap(caps, "SCEPStandard")
+	digest, encryption := pkcs7.OIDDigestAlgorithmSHA1, pkcs7.EncryptionAlgorithmDESCBC
+	if standard || scepHasCap(caps, "SHA-256") {
+		digest = pkcs7.OIDDigestAlgorithmSHA256
+	}
+	if standard || scepHasCap(caps, "AES") {
+		encryption = pkcs7.EncryptionAlgorithmAES128CBC
+	}
+	if err := pkcs7.SetDefaultDigestAlgorithm(digest); err != nil {
+		return err
+	}
+	pkcs7.ContentEncryptionAlgorithm = encryption
+	return nil
+}
+
+// scepIssuer devuelve el certificado de la CA entre los que publica el servidor (CA y RA).
+func scepIssuer(certs []*x509.Certificate) *x509.Certificate {
+	for _, cert := range certs {
+		if cert.IsCA {
+			return cert
+		}
+	}
+	return certs[0]
+}
+
+// scepTransactionID calcula el identificador de transacción como lo hace scep.NewCSRRequest: el
+// SubjectKeyIdentifier de la clave en base64, de modo que no cambia entre ejecuciones.
+func scepTransactionID(csr *x509.CertificateRequest) (scep.TransactionID, error) {
+	id, err := cryptoutil.GenerateSubjectKeyID(csr.PublicKey)
+	if err != nil {
+		return "", fmt.Errorf("error computing transaction ID: %v", err)
+	}
+	return scep.TransactionID(base64.StdEncoding.EncodeToString(id)), nil
+}
+
+// scepPKIOperation envía el mensaje por POST si el servidor lo admite o, si no, por GET en base64.
+func scepPKIOperation(client *http.Client, opts SCEPOptions, caps []string, message []byte) ([]byte, error) {
+	var resp *http.Response
+	var err error
+	if scepHasCap(caps, "POSTPKIOperation", "SCEPStandard") {
+		resp, err = client.Post(scepURL(opts, "PKIOperation", ""), "application/x-pki-message", bytes.NewReader(message))
+	} else {
+		resp, err = client.Get(scepURL(opts, "PKIOperation", base64.StdEncoding.EncodeToString(message)))
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, scepMaxResponse))
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, scepStatusError(resp, body)
+	}
+	return body, nil
+}
+
+// scepRequestCSR rehace el CSR con el mismo sujeto y SANs añadiendo el challengePassword, que
+// así no se guarda en el CSR del disco.
+func scepRequestCSR(csr *x509.CertificateRequest, key crypto.Signer, challenge string) (*x509.CertificateRequest, error) {
+	der, err := x509util.CreateCertificateRequest(rand.Reader, &x509util.CertificateRequest{
+		CertificateRequest: x509.CertificateRequest{
+			SignatureAlgorithm: x509.SHA256WithRSA,
+			Subject:            csr.Subject,
+			DNSNames:           csr.DNSNames,
+			EmailAddresses:     csr.EmailAddresses,
+			IPAddresses:        csr.IPAddresses,
+			URIs:               csr.URIs,
+		},
+		ChallengePassword: challenge,
+	}, key)
+	if err != nil {
+		return nil, fmt.Errorf("error creating CSR: %v", err)
+	}
+	return x509.ParseCertificateRequest(der)
+}
+
+// scepSignerCertificate crea el certificado autofirmado con el que el cliente firma la petición y
+// para el que la CA cifra la respuesta.
+func scepSignerCertificate(csr *x509.CertificateRequest, key crypto.Signer) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		return nil, fmt.Errorf("error generating serial number: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      csr.Subject,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, fmt.Errorf("error creating signer certificate: %v", err)
+	}
+	return x509.ParseCertificate(der)
+}
@@ -0,0 +1,336 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+)
+
+// starttlsPorts son los puertos por defecto de cada protocolo con STARTTLS.
+var starttlsPorts = map[string]string{
+	"smtp":     "25",
+	"imap":     "143",
+	"pop3":     "110",
+	"ldap":     "389",
+	"postgres": "5432",
+	"mysql":    "3306",
+	"ftp":      "21",
+	"xmpp":     "5222",
+}
+
+// StartTLSProtocols devuelve los protocolos soportados por --starttls.
+func StartTLSProtocols() []string {
+	return []string{"smtp", "imap", "pop3", "ldap", "postgres", "mysql", "ftp", "xmpp"}
+}
+
+// startTLS negocia el paso a TLS del protocolo indicado sobre una conexión en claro. Al volver sin
+// error, el siguiente mensaje que espera el servidor es el ClientHello.
+func startTLS(conn net.Conn, protocol, serverName string) error {
+	r := bufio.NewReader(conn)
+	var err error
+	switch protocol {
+	case "smtp":
+		err = startTLSSMTP(conn, r)
+	case "imap":
+		err = startTLSIMAP(conn, r)
+	case "pop3":
+		err = startTLSPOP3(conn, r)
+	case "ftp":
+		err = startTLSFTP(conn, r)
+	case "ldap":
+		err = startTLSLDAP(conn, r)
+	case "postgres":
+		err = startTLSPostgres(conn, r)
+	case "mysql":
+		err = startTLSMySQL(conn, r)
+	case "xmpp":
+		err = startTLSXMPP(conn, r, serverName)
+	default:
+		return fmt.Errorf("unsupported STARTTLS protocol: %s", protocol)
+	}
+	if err != nil {
+		return fmt.Errorf("%s STARTTLS failed: %v", protocol, err)
+	}
+	return nil
+}
+
+// readReply lee una respuesta de SMTP o FTP (posiblemente multilínea) y comprueba su código.
+func readReply(r *bufio.Reader, code string) (string, error) {
+	var lines []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		lines = append(lines, line)
+		if len(line) < 4 || line[3] != '-' {
+			break
+		}
+	}
+	reply := strings.Join(lines, "\n")
+	if !strings.HasPrefix(lines[len(lines)-1], code) {
+		return reply, fmt.Errorf("unexpected reply: %s", reply)
+	}
+	return reply, nil
+}
+
+func startTLSSMTP(conn net.Conn, r *bufio.Reader) error {
+	if _, err := readReply(r, "220"); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(conn, "EHLO ssl-tool\r\n"); err != nil {
+		return err
+	}
+	capabilities, err := readReply(r, "250")
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(strings.ToUpper(capabilities), "STARTTLS") {
+		return errors.New("server does not advertise STARTTLS")
+	}
+	if _, err := io.WriteString(conn, "STARTTLS\r\n"); err != nil {
+		return err
+	}
+	_, err = readReply(r, "220")
+	return err
+}
+
+func startTLSFTP(conn net.Conn, r *bufio.Reader) error {
+	if _, err := readReply(r, "220"); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(conn, "AUTH TLS\r\n"); err != nil {
+		return err
+	}
+	_, err := readReply(r, "234")
+	return err
+}
+
+func startTLSIMAP(conn net.Conn, r *bufio.Reader) error {
+	greeting, err := r.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(greeting, "* OK") {
+		return fmt.Errorf("unexpected greeting: %s", strings.TrimSpace(greeting))
+	}
+	if _, err := io.WriteString(conn, "a001 STARTTLS\r\n"); err != nil {
+		return err
+	}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(line, "a001 ") {
+			if !strings.HasPrefix(line, "a001 OK") {
+				return fmt.Errorf("unexpected reply: %s", strings.TrimSpace(line))
+			}
+			return nil
+		}
+	}
+}
+
+func startTLSPOP3(conn net.Conn, r *bufio.Reader) error {
+	greeting, err := r.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(greeting, "+OK") {
+		return fmt.Errorf("unexpected greeting: %s", strings.TrimSpace(greeting))
+	}
+	if _, err := io.WriteString(conn, "STLS\r\n"); err != nil {
+		return err
+	}
+	reply, err := r.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(reply, "+OK") {
+		return fmt.Errorf("unexpected reply: %s", strings.TrimSpace(reply))
+	}
+	return nil
+}
+
+// maxLDAPResponse limita el tamaño de la respuesta a StartTLS: un ExtendedResponse ocupa unos pocos
+// bytes, y la longitud la anuncia el servidor antes de enviar nada.
+const maxLDAPResponse = 64 << 10
+
+// ldapStartTLSRequest es un ExtendedRequest (messageID 1) con el OID de StartTLS (RFC 4511).
+var ldapStartTLSRequest = append([]byte{0x30, 0x1d, 0x02, 0x01, 0x01, 0x77, 0x18, 0x80, 0x16}, "1.3.6.1.4.1.1466.20037"...)
+
+func startTLSLDAP(conn net.Conn, r *bufio.Reader) error {
+	if _, err := conn.Write(ldapStartTLSRequest); err != nil {
+		return err
+	}
+	message, err := readBER(r, maxLDAPResponse)
+	if err != nil {
+		return err
+	}
+
+	var envelope struct {
+		MessageID  int
+		ProtocolOp asn1.RawValue
+	}
+	if _, err := asn1.UnmarshalWithParams(message, &envelope, ""); err != nil {
+		return fmt.Errorf("malformed LDAP response: %v", err)
+	}
+	// ExtendedResponse es [APPLICATION 24] y empieza por el resultCode
+	if envelope.ProtocolOp.Class != asn1.ClassApplication || envelope.ProtocolOp.Tag != 24 {
+		return fmt.Errorf("unexpected LDAP response tag %d", envelope.ProtocolOp.Tag)
+	}
+	var resultCode asn1.Enumerated
+	if _, err := asn1.Unmarshal(envelope.ProtocolOp.Bytes, &resultCode); err != nil {
+		return fmt.Errorf("malformed LDAP response: %v", err)
+	}
+	if resultCode != 0 {
+		return fmt.Errorf("server refused StartTLS (resultCode %d)", resultCode)
+	}
+	return nil
+}
+
+// readBER lee un elemento BER completo (etiqueta, longitud y contenido) de como mucho max bytes de
+// contenido; la longitud se comprueba antes de reservar memoria.
+func readBER(r *bufio.Reader, max int) ([]byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	length := int(header[1])
+	if length&0x80 != 0 {
+		n := length & 0x7f
+		if n == 0 || n > 4 {
+			return nil, errors.New("unsupported BER length")
+		}
+		lengthBytes := make([]byte, n)
+		if _, err := io.ReadFull(r, lengthBytes); err != nil {
+			return nil, err
+		}
+		header = append(header, lengthBytes...)
+		length = 0
+		for _, b := range lengthBytes {
+			length = length<<8 | int(b)
+		}
+	}
+	if length < 0 || length > max {
+		return nil, fmt.Errorf("BER element too large (%d bytes, maximum %d)", length, max)
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return append(header, body...), nil
+}
+
+func startTLSPostgres(conn net.Conn, r *bufio.Reader) error {
+	// SSLRequest: longitud 8 y el código 80877103
+	request := make([]byte, 8)
+	binary.BigEndian.PutUint32(request[0:4], 8)
+	binary.BigEndian.PutUint32(request[4:8], 80877103)
+	if _, err := conn.Write(request); err != nil {
+		return err
+	}
+	reply, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+	if reply != 'S' {
+		return errors.New("server does not accept SSL connections")
+	}
+	return nil
+}
+
+// Flags de capacidad del protocolo de MySQL usados en el SSLRequest.
+const (
+	mysqlClientProtocol41       = 0x00000200
+	mysqlClientSSL              = 0x00000800
+	mysqlClientSecureConnection = 0x00008000
+)
+
+func startTLSMySQL(conn net.Conn, r *bufio.Reader) error {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return err
+	}
+	length := int(header[0]) | int(header[1])<<8 | int(header[2])<<16
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return err
+	}
+	if len(payload) == 0 || payload[0] == 0xff {
+		return errors.New("server returned an error instead of a handshake")
+	}
+	if payload[0] != 10 {
+		return fmt.Errorf("unsupported handshake protocol version %d", payload[0])
+	}
+
+	// Protocolo 10: versión (terminada en 0), id de conexión (4), auth data (8), relleno (1), capacidades (2)
+	end := bytes.IndexByte(payload[1:], 0)
+	if end < 0 || len(payload) < 1+end+1+4+8+1+2 {
+		return errors.New("malformed handshake packet")
+	}
+	offset := 1 + end + 1 + 4 + 8 + 1
+	capabilities := binary.LittleEndian.Uint16(payload[offset : offset+2])
+	if capabilities&mysqlClientSSL == 0 {
+		return errors.New("server does not support SSL")
+	}
+
+	// SSLRequest: capacidades (4), tamaño máximo de paquete (4), charset (1) y 23 bytes reservados
+	request := make([]byte, 4+32)
+	request[0] = 32
+	request[3] = header[3] + 1
+	binary.LittleEndian.PutUint32(request[4:8], mysqlClientSSL|mysqlClientProtocol41|mysqlClientSecureConnection)
+	binary.LittleEndian.PutUint32(request[8:12], 1<<24)
+	request[12] = 33 // utf8_general_ci
+	_, err := conn.Write(request)
+	return err
+}
+
+func startTLSXMPP(conn net.Conn, r *bufio.Reader, serverName string) error {
+	open := fmt.Sprintf("<?xml version='1.0'?><stream:stream to='%s' xmlns='jabber:client' "+
+		"xmlns:stream='http://etherx.jabber.org/streams' version='1.0'>", serverName)
+	if _, err := io.WriteString(conn, open); err != nil {
+		return err
+	}
+	features, err := readUntil(r, "</stream:features>")
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(features, "urn:ietf:params:xml:ns:xmpp-tls") {
+		return errors.New("server does not advertise STARTTLS")
+	}
+	if _, err := io.WriteString(conn, "<starttls xmlns='urn:ietf:params:xml:ns:xmpp-tls'/>"); err != nil {
+		return err
+	}
+	reply, err := readUntil(r, ">")
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(reply, "<proceed") {
+		return fmt.Errorf("unexpected reply: %s", strings.TrimSpace(reply))
+	}
+	return nil
+}
+
+// readUntil lee hasta encontrar marker (incluido).
+func readUntil(r *bufio.Reader, marker string) (string, error) {
+	var buf strings.Builder
+	for !strings.HasSuffix(buf.String(), marker) {
+		b, err := r.ReadByte()
+		if err != nil {
+			return buf.String(), err
+		}
+		buf.WriteByte(b)
+		if buf.Len() > 64*1024 {
+			return "", errors.New("response too long")
+		}
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,247 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// trustBundleHeader encabeza los almacenes que escribe EncodeTrustBundle.
+const trustBundleHeader = "# CA bundle maintained with ssl-tool truststore.\n" +
+	"# Entries are sorted by name; each one lists its subject, expiry and SHA-256 fingerprint.\n"
+
+// TrustAnchor es un certificado de un almacén de confianza.
+type TrustAnchor struct {
+	Cert        *x509.Certificate
+	Fingerprint string // SHA256 del DER en hexadecimal
+	Label       string // comentario "# Label:" del bloque, si lo tiene
+	// Fecha a partir de la cual no se confía en lo que emita (certdata.txt); comentario "# Distrust After:"
+	DistrustAfter time.Time
+}
+
+// Name devuelve la etiqueta o, si no hay, el CN o el sujeto completo.
+func (a TrustAnchor) Name() string {
+	switch {
+	case a.Label != "":
+		return a.Label
+	case a.Cert.Subject.CommonName != "":
+		return a.Cert.Subject.CommonName
+	default:
+		return a.Cert.Subject.String()
+	}
+}
+
+// NewTrustAnchor calcula la huella de cert.
+func NewTrustAnchor(cert *x509.Certificate, label string) TrustAnchor {
+	sum := sha256.Sum256(cert.Raw)
+	return TrustAnchor{Cert: cert, Fingerprint: hex.EncodeToString(sum[:]), Label: label}
+}
+
+// LoadTrustBundle lee un almacén PEM; ver ParseTrustBundle.
+func LoadTrustBundle(path string) ([]TrustAnchor, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading bundle: %v", err)
+	}
+	return ParseTrustBundle(data)
+}
+
+// ParseTrustBundle decodifica todos los bloques PEM de un almacén. Admite CERTIFICATE y TRUSTED
+// CERTIFICATE (OpenSSL; los datos de confianza añadidos se descartan) y falla con cualquier otro
+// bloque o certificado ilegible, indicando su posición. Un almacén vacío no es un error.
+func ParseTrustBundle(data []byte) ([]TrustAnchor, error) {
+	var anchors []TrustAnchor
+	for n := 1; ; n++ {
+		start := bytes.Index(data, []byte("-----BEGIN "))
+		if start < 0 {
+			break
+		}
+		preamble := data[:start]
+		block, rest := pem.Decode(data[start:])
+		if block == nil {
+			return nil, fmt.Errorf("block %d: invalid PEM encoding", n)
+		}
+		data = rest
+
+		der := block.Bytes
+		switch block.Type {
+		case "CERTIFICATE":
+		case "TRUSTED CERTIFICATE":
+			var raw asn1.RawValue
+			tail, err := asn1.Unmarshal(der, &raw)
+			if err != nil {
+				return nil, fmt.Errorf("block %d: error parsing trusted certificate: %v", n, err)
+			}
+			der = der[:len(der)-len(tail)]
+		default:
+			return nil, fmt.Errorf("block %d: unexpected %s block in a CA bundle", n, block.Type)
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("block %d: error parsing certificate: %v", n, err)
+		}
+		anchor := NewTrustAnchor(cert, "")
+		anchor.Label, anchor.DistrustAfter = preambleComments(preamble)
+		anchors = append(anchors, anchor)
+	}
+	return anchors, nil
+}
+
+// preambleComments busca los comentarios "# Label:" (con o sin comillas, como en los almacenes de
+// certifi) y "# Distrust After:" del texto anterior a un bloque.
+func preambleComments(preamble []byte) (label string, distrustAfter time.Time) {
+	for _, line := range strings.Split(string(preamble), "\n") {
+		line = strings.TrimSpace(line)
+		if value, ok := strings.CutPrefix(line, "# Label:"); ok {
+			label = strings.Trim(strings.TrimSpace(value), `"`)
+		} else if value, ok := strings.CutPrefix(line, "# Distrust After:"); ok {
+			distrustAfter, _ = time.Parse(time.DateOnly, strings.TrimSpace(value))
+		}
+	}
+	return label, distrustAfter
+}
+
+// SortTrustBundle ordena por nombre, sin distinguir mayúsculas, y después por huella, de modo
+// que el mismo conjunto de certificados produce siempre el mismo archivo.
+func SortTrustBundle(anchors []TrustAnchor) {
+	sort.SliceStable(anchors, func(i, j int) bool {
+		a, b := strings.ToLower(anchors[i].Name()), strings.ToLower(anchors[j].Name())
+		if a != b {
+			return a < b
+		}
+		return anchors[i].Fingerprint < anchors[j].Fingerprint
+	})
+}
+
+// DedupeTrustBundle quita los certificados repetidos (se queda con el primero) y, si
+// removeExpired, los caducados en now. Devuelve los que se conservan y los eliminados.
+func DedupeTrustBundle(anchors []TrustAnchor, removeExpired bool, now time.Time) (kept, duplicates, expired []TrustAnchor) {
+	if now.IsZero() {
+		now = time.Now()
+	}
+	seen := map[string]bool{}
+	for _, anchor := range anchors {
+		switch {
+		case seen[anchor.Fingerprint]:
+			duplicates = append(duplicates, anchor)
+		case removeExpired && now.After(anchor.Cert.NotAfter):
+			expired = append(expired, anchor)
+		default:
+			seen[anchor.Fingerprint] = true
+			kept = append(kept, anchor)
+		}
+	}
+	return kept, duplicates, expired
+}
+
+// FindTrustAnchor busca un certificado por su huella o por un prefijo único de ella (admite
+// separadores ":") y devuelve su posición.
+func FindTrustAnchor(anchors []TrustAnchor, id string) (int, error) {
+	prefix := strings.ToLower(strings.ReplaceAll(id, ":", ""))
+	if prefix == "" {
+		return -1, errors.New("fingerprint cannot be empty")
+	}
+	found, matches := -1, map[string]bool{}
+	for i, anchor := range anchors {
+		if strings.HasPrefix(anchor.Fingerprint, prefix) {
+			if found < 0 {
+				found = i
+			}
+			matches[anchor.Fingerprint] = true
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return -1, fmt.Errorf("no certificate in the bundle matches %s", id)
+	case 1:
+		return found, nil
+	default:
+		return -1, fmt.Errorf("%s matches %d certificates in the bundle; use a longer fingerprint", id, len(matches))
+	}
+}
+
+// EncodeTrustBundle escribe el almacén en PEM con un comentario delante de cada certificado.
+// No incluye fechas de generación para que el archivo solo cambie cuando cambian los certificados.
+func EncodeTrustBundle(anchors []TrustAnchor) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(trustBundleHeader)
+	for _, anchor := range anchors {
+		buf.WriteString("\n")
+		if anchor.Label != "" {
+			fmt.Fprintf(&buf, "# Label: %s\n", anchor.Label)
+		}
+		fmt.Fprintf(&buf, "# Subject: %s\n", anchor.Cert.Subject)
+		fmt.Fprintf(&buf, "# Not After: %s\n", anchor.Cert.NotAfter.UTC().Format(time.DateOnly))
+		if !anchor.DistrustAfter.IsZero() {
+			fmt.Fprintf(&buf, "# Distrust After: %s\n", anchor.DistrustAfter.UTC().Format(time.DateOnly))
+		}
+		fmt.Fprintf(&buf, "# SHA-256: %s\n", anchor.Fingerprint)
+		pem.Encode(&buf, &pem.Block{Type: "CERTIFICATE", Bytes: anchor.Cert.Raw})
+	}
+	return buf.Bytes()
+}
+
+// TrustIssue es un problema de un certificado del almacén encontrado por VerifyTrustBundle.
+type TrustIssue struct {
+	Anchor  TrustAnchor
+	Problem string
+}
+
+// VerifyTrustBundle revisa que cada certificado sea una CA raíz válida en now: sin duplicados,
+// dentro de su validez, con la autofirma correcta y sin claves débiles.
+func VerifyTrustBundle(anchors []TrustAnchor, now time.Time) []TrustIssue {
+	if now.IsZero() {
+		now = time.Now()
+	}
+	var issues []TrustIssue
+	seen := map[string]bool{}
+	for _, anchor := range anchors {
+		cert := anchor.Cert
+		add := func(format string, args ...interface{}) {
+			issues = append(issues, TrustIssue{Anchor: anchor, Problem: fmt.Sprintf(format, args...)})
+		}
+		if seen[anchor.Fingerprint] {
+			add("duplicate")
+			continue
+		}
+		seen[anchor.Fingerprint] = true
+
+		switch {
+		case now.After(cert.NotAfter):
+			add("expired on %s", cert.NotAfter.UTC().Format(time.DateOnly))
+		case now.Before(cert.NotBefore):
+			add("not valid until %s", cert.NotBefore.UTC().Format(time.DateOnly))
+		}
+		if !anchor.DistrustAfter.IsZero() && !anchor.DistrustAfter.After(now) {
+			add("distrusted for certificates issued after %s", anchor.DistrustAfter.UTC().Format(time.DateOnly))
+		}
+		if !cert.BasicConstraintsValid || !cert.IsCA {
+			add("not a CA certificate")
+		}
+		if string(cert.RawSubject) != string(cert.RawIssuer) {
+			add("not self-signed (issued by %s)", cert.Issuer)
+		} else if err := cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature); err != nil {
+			// Muchas raíces vigentes están autofirmadas con SHA-1, que Go ya no comprueba
+			var insecure x509.InsecureAlgorithmError
+			if !errors.As(err, &insecure) {
+				add("invalid self-signature: %v", err)
+			}
+		}
+		// La firma de una raíz no se comprueba al validar cadenas; solo importa su clave
+		if info, err := DescribePublicKey(cert.PublicKey); err == nil {
+			for _, finding := range weakCryptoFindings(info.Algorithm, info.Size, "") {
+				add("%s", finding)
+			}
+		}
+	}
+	return issues
+}
@@ -0,0 +1,292 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultWatchDebounce es el tiempo que se espera a que un archivo deje de cambiar antes de leerlo.
+const DefaultWatchDebounce = 500 * time.Millisecond
+
+// Tipos de evento de WatchEvent.
+const (
+	WatchInitial = "initial" // lectura inicial al arrancar
+	WatchCreate  = "create"
+	WatchWrite   = "write"
+	WatchRename  = "rename" // el archivo ya no está en su ruta; el nombre nuevo llega como create
+	WatchRemove  = "remove"
+	WatchRescan  = "rescan" // relectura completa tras desbordarse la cola de eventos
+)
+
+// WatchOptions controla la vigilancia de un directorio.
+type WatchOptions struct {
+	Scan     ScanOptions
+	Debounce time.Duration
+	// OnError recibe los errores que no detienen la vigilancia (cola de eventos desbordada,
+	// subdirectorios que no se pueden vigilar, relecturas fallidas); si es nil se ignoran
+	OnError func(error)
+}
+
+// WatchEvent es un archivo que ha cambiado junto con los certificados leídos de él. Los eventos
+// rename y remove no llevan resultados.
+type WatchEvent struct {
+	Op      string
+	Path    string
+	Results []ScanResult
+}
+
+// WatchCertificates lee primero todos los certificados de opts.Scan.Root y después vigila el
+// directorio (y sus subdirectorios, con Recursive) hasta que se cancela ctx. Cada archivo que cambia
+// se vuelve a leer con el mismo lector que ScanCertificates y se entrega a handle. Si el kernel
+// descarta eventos, se vuelve a leer todo el directorio para no perder cambios.
+func WatchCertificates(ctx context.Context, opts WatchOptions, handle func(WatchEvent)) error {
+	include := opts.Scan.Include
+	if len(include) == 0 {
+		include = DefaultScanInclude
+	}
+	debounce := opts.Debounce
+	if debounce <= 0 {
+		debounce = DefaultWatchDebounce
+	}
+
+	reportError := func(err error) {
+		if opts.OnError != nil {
+			opts.OnError(err)
+		}
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("error creating watcher: %v", err)
+	}
+	defer watcher.Close()
+
+	// Se empieza a vigilar antes de la lectura inicial para no perder cambios entre ambas. Solo se
+	// aborta si no se puede vigilar la raíz; los subdirectorios ilegibles se notifican y se omiten
+	if err := watchTree(watcher, opts.Scan.Root, opts.Scan.Recursive, reportError); err != nil {
+		return err
+	}
+	initial, err := ScanCertificates(opts.Scan)
+	if err != nil {
+		return err
+	}
+	// Archivos con certificados ya entregados, para detectar los borrados al releer el directorio
+	known := map[string]bool{}
+	deliver := func(event WatchEvent) {
+		if len(event.Results) > 0 {
+			known[event.Path] = true
+		} else {
+			delete(known, event.Path)
+		}
+		handle(event)
+	}
+	for _, event := range scanEvents(initial, reportError) {
+		deliver(event)
+	}
+
+	// Los eventos se acumulan por ruta y se entregan cuando el archivo lleva debounce sin cambiar
+	type pendingEvent struct {
+		op   string
+		last time.Time
+	}
+	pending := map[string]*pendingEvent{}
+	ticker := time.NewTicker(debounce / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			if !errors.Is(err, fsnotify.ErrEventOverflow) {
+				reportError(fmt.Errorf("error watching %s: %v", opts.Scan.Root, err))
+				continue
+			}
+			reportError(fmt.Errorf("event queue overflow watching %s: rescanning", opts.Scan.Root))
+			// Pueden haberse perdido directorios nuevos, así que también se vuelven a vigilar
+			if err := watchTree(watcher, opts.Scan.Root, opts.Scan.Recursive, reportError); err != nil {
+				reportError(err)
+			}
+			results, err := ScanCertificates(opts.Scan)
+			if err != nil {
+				reportError(err)
+				continue
+			}
+			seen := map[string]bool{}
+			for _, event := range scanEvents(results, reportError) {
+				event.Op = WatchRescan
+				seen[event.Path] = true
+				delete(pending, event.Path)
+				deliver(event)
+			}
+			var gone []string
+			for path := range known {
+				if !seen[path] {
+					gone = append(gone, path)
+				}
+			}
+			sort.Strings(gone)
+			for _, path := range gone {
+				delete(pending, path)
+				event := WatchEvent{Op: WatchRemove, Path: path}
+				if _, err := os.Stat(path); err == nil {
+					// Sigue existiendo, pero ya no tiene certificados
+					event.Op, event.Results = WatchWrite, readScanFile(path)
+				}
+				deliver(event)
+			}
+
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if ev.Has(fsnotify.Create) && opts.Scan.Recursive {
+				if info, err := os.Stat(ev.Name); err == nil && info.IsDir() {
+					// Un directorio nuevo puede traer ya archivos dentro
+					if err := watchTree(watcher, ev.Name, true, reportError); err != nil {
+						reportError(err)
+					}
+					results, _ := ScanCertificates(ScanOptions{Root: ev.Name, Recursive: true, Include: include})
+					for _, event := range scanEvents(results, reportError) {
+						event.Op = WatchCreate
+						deliver(event)
+					}
+					continue
+				}
+			}
+			if !matchesAny(filepath.Base(ev.Name), include) {
+				continue
+			}
+			op := watchOp(ev.Op)
+			if op == "" {
+				continue
+			}
+			if p, ok := pending[ev.Name]; ok {
+				// Un create seguido de escrituras sigue siendo un create
+				if p.op != WatchCreate || op == WatchRename || op == WatchRemove {
+					p.op = op
+				}
+				p.last = time.Now()
+			} else {
+				pending[ev.Name] = &pendingEvent{op: op, last: time.Now()}
+			}
+
+		case now := <-ticker.C:
+			var ready []string
+			for path, p := range pending {
+				if now.Sub(p.last) >= debounce {
+					ready = append(ready, path)
+				}
+			}
+			sort.Strings(ready)
+			for _, path := range ready {
+				event := WatchEvent{Op: pending[path].op, Path: path}
+				delete(pending, path)
+				if event.Op == WatchCreate || event.Op == WatchWrite {
+					if _, err := os.Stat(path); err != nil {
+						// Se creó y se borró o renombró antes de leerlo
+						continue
+					}
+					event.Results = readScanFile(path)
+				}
+				deliver(event)
+			}
+		}
+	}
+}
+
+// watchTree añade root (y, con recursive, todos sus subdirectorios) al watcher. Solo devuelve error
+// si no se puede vigilar root; los subdirectorios que no se pueden leer o vigilar se pasan a
+// reportError y se omiten.
+func watchTree(watcher *fsnotify.Watcher, root string, recursive bool, reportError func(error)) error {
+	info, err := os.Stat(root)
+	if err != nil {
+		return fmt.Errorf("error reading path: %v", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", root)
+	}
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			reportError(fmt.Errorf("error reading path: %v", err))
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != root && !recursive {
+			return filepath.SkipDir
+		}
+		if err := watcher.Add(path); err != nil {
+			err = fmt.Errorf("error watching %s: %v", path, err)
+			if path == root {
+				return err
+			}
+			reportError(err)
+			return filepath.SkipDir
+		}
+		return nil
+	})
+}
+
+func watchOp(op fsnotify.Op) string {
+	switch {
+	case op.Has(fsnotify.Remove):
+		return WatchRemove
+	case op.Has(fsnotify.Rename):
+		return WatchRename
+	case op.Has(fsnotify.Create):
+		return WatchCreate
+	case op.Has(fsnotify.Write):
+		return WatchWrite
+	default:
+		return ""
+	}
+}
+
+// scanEvents agrupa los resultados de un escaneo por archivo. Los subdirectorios que no se han
+// podido leer no son archivos cambiados, así que se pasan a reportError.
+func scanEvents(results []ScanResult, reportError func(error)) []WatchEvent {
+	var files []ScanResult
+	for _, r := range results {
+		if r.Err != nil {
+			if info, err := os.Stat(r.Path); err == nil && info.IsDir() {
+				reportError(r.Err)
+				continue
+			}
+		}
+		files = append(files, r)
+	}
+	return groupByFile(files)
+}
+
+// groupByFile agrupa los resultados de un escaneo en un evento inicial por archivo.
+func groupByFile(results []ScanResult) []WatchEvent {
+	var events []WatchEvent
+	for _, r := range results {
+		if len(events) == 0 || events[len(events)-1].Path != r.Path {
+			events = append(events, WatchEvent{Op: WatchInitial, Path: r.Path})
+		}
+		last := &events[len(events)-1]
+		last.Results = append(last.Results, r)
+	}
+	return events
+}
@@ -0,0 +1,109 @@
+package ssltool
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/fs"
+
+	"github.com/luisenrique-varelarodriguez/ssl-tool/internal"
+)
+
+// Tipos de clave de GenerateCSR.
+const (
+	KeyTypeRSA2048 = internal.KeyTypeRSA2048
+	KeyTypeRSA4096 = internal.KeyTypeRSA4096
+	KeyTypeEC256   = internal.KeyTypeEC256
+	KeyTypeEC384   = internal.KeyTypeEC384
+)
+
+// CSROptions son los datos de la clave y el CSR que genera GenerateCSR.
+type CSROptions struct {
+	Domain       string   // Common Name; obligatorio
+	DNSNames     []string // SANs de tipo DNS
+	Country      string   // código ISO 3166-1 alfa-2
+	Locality     string
+	Organization string
+	KeyType      string // rsa2048 (por defecto), rsa4096, ec256 o ec384
+	// Directorio en el que guardar <dominio>.key y <dominio>.csr (example.com -> example_com.key);
+	// si está vacío no se escribe nada
+	OutputDir string
+}
+
+// CSR es una clave recién generada y su CSR.
+type CSR struct {
+	Request *x509.CertificateRequest
+	Key     crypto.Signer
+	KeyPEM  []byte // PKCS#1 para RSA, SEC1 para EC
+	CSRPEM  []byte
+	KeyPath string // rutas de los archivos guardados; vacías sin OutputDir
+	CSRPath string
+}
+
+// GenerateCSR genera una clave y un CSR firmado con ella y, si se indica OutputDir, los guarda.
+func GenerateCSR(ctx context.Context, opts CSROptions) (*CSR, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if opts.Domain == "" {
+		return nil, &OptionError{Option: "Domain", Reason: "domain cannot be empty"}
+	}
+	if opts.Country != "" && !internal.IsCountryCode(opts.Country) {
+		return nil, &OptionError{Option: "Country", Reason: "country " + opts.Country + " is not an ISO 3166-1 alpha-2 code"}
+	}
+	if opts.KeyType == "" {
+		opts.KeyType = KeyTypeRSA2048
+	}
+	key, err := internal.GeneratePrivateKey(opts.KeyType)
+	if err != nil {
+		return nil, &OptionError{Option: "KeyType", Reason: err.Error()}
+	}
+
+	format := internal.KeyFormatSEC1
+	if _, ok := key.(*rsa.PrivateKey); ok {
+		format = internal.KeyFormatPKCS1
+	}
+	keyPEM, err := internal.EncodePrivateKeyPEM(key, format, nil)
+	if err != nil {
+		return nil, err
+	}
+	der, err := internal.CreateCSR(internal.CSRRequest{
+		Domain:       opts.Domain,
+		DNSNames:     opts.DNSNames,
+		Country:      opts.Country,
+		Locality:     opts.Locality,
+		Organization: opts.Organization,
+	}, key)
+	if err != nil {
+		return nil, err
+	}
+	request, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		return nil, err
+	}
+	result := &CSR{
+		Request: request,
+		Key:     key,
+		KeyPEM:  keyPEM,
+		CSRPEM:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}),
+	}
+
+	if opts.OutputDir != "" {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		files, err := internal.WriteCSRFiles(opts.OutputDir, internal.CSRDirName(opts.Domain), result.KeyPEM, result.CSRPEM)
+		var pathErr *fs.PathError
+		if errors.As(err, &pathErr) {
+			return nil, fileError(pathErr.Path, err)
+		}
+		if err != nil {
+			return nil, &FileError{Path: opts.OutputDir, Err: err}
+		}
+		result.KeyPath, result.CSRPath = files.KeyPath, files.CSRPath
+	}
+	return result, nil
+}
@@ -0,0 +1,41 @@
+package ssltool
+
+import (
+	"context"
+	"time"
+
+	"github.com/luisenrique-varelarodriguez/ssl-tool/internal"
+)
+
+// Estados de ExpiryStatus.
+const (
+	ExpiryValid       = internal.ExpiryValid
+	ExpiryExpiring    = internal.ExpiryExpiring
+	ExpiryExpired     = internal.ExpiryExpired
+	ExpiryNotYetValid = internal.ExpiryNotYetValid
+)
+
+// ExpiryStatus describe la validez de un certificado en un instante dado.
+type ExpiryStatus = internal.ExpiryStatus
+
+// ExpiryOptions indica el certificado y el instante en el que se evalúa.
+type ExpiryOptions struct {
+	File string        // se evalúa el primer certificado del archivo
+	At   time.Time     // vacío significa ahora
+	Warn time.Duration // margen a partir del cual el estado es "expiring"
+}
+
+// CheckExpiry evalúa la validez del primer certificado de un archivo.
+func CheckExpiry(ctx context.Context, opts ExpiryOptions) (ExpiryStatus, error) {
+	if err := checkFile(ctx, "File", opts.File); err != nil {
+		return ExpiryStatus{}, err
+	}
+	if opts.Warn < 0 {
+		return ExpiryStatus{}, &OptionError{Option: "Warn", Reason: "warn cannot be negative"}
+	}
+	certs, err := internal.LoadCertificates(opts.File)
+	if err != nil {
+		return ExpiryStatus{}, fileError(opts.File, err)
+	}
+	return internal.CheckExpiry(certs[0], opts.At, opts.Warn), nil
+}
@@ -0,0 +1,87 @@
+package ssltool
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/luisenrique-varelarodriguez/ssl-tool/internal"
+)
+
+// Formatos de Fingerprint.
+const (
+	FingerprintHex    = internal.FingerprintHex
+	FingerprintColon  = internal.FingerprintColon
+	FingerprintBase64 = internal.FingerprintBase64
+)
+
+// FingerprintOptions indica el archivo, el algoritmo y el formato de la huella.
+type FingerprintOptions struct {
+	File       string // certificado; con SPKI también un CSR o una clave
+	Algorithm  string // sha1, sha256 (por defecto), sha384 o sha512
+	Format     string // hex (por defecto), colon o base64
+	SPKI       bool   // huella de la clave pública en lugar del certificado completo
+	Passphrase []byte // si File es una clave privada cifrada
+}
+
+// Fingerprint calcula la huella del primer certificado de un archivo PEM o, con SPKI, la de la
+// clave pública de un certificado, CSR o clave.
+func Fingerprint(ctx context.Context, opts FingerprintOptions) (string, error) {
+	if err := checkDigestOptions(opts); err != nil {
+		return "", err
+	}
+	if err := checkFile(ctx, "File", opts.File); err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(opts.File)
+	if err != nil {
+		return "", fileError(opts.File, err)
+	}
+
+	if opts.SPKI {
+		pub, err := internal.ParsePublicKeyPEM(data, opts.Passphrase)
+		if err != nil {
+			return "", fileError(opts.File, err)
+		}
+		der, err := x509.MarshalPKIXPublicKey(pub)
+		if err != nil {
+			return "", fileError(opts.File, err)
+		}
+		return internal.FormatDigest(der, opts.Algorithm, opts.Format)
+	}
+
+	if block, _ := pem.Decode(data); block != nil && block.Type != "CERTIFICATE" {
+		return "", &FileError{Path: opts.File, Err: fmt.Errorf("found a %s instead of a certificate; only SPKI fingerprints are available", strings.ToLower(block.Type))}
+	}
+	certs, err := internal.ParseCertificates(data)
+	if err != nil {
+		return "", fileError(opts.File, err)
+	}
+	return FingerprintCertificate(certs[0], opts)
+}
+
+// FingerprintCertificate calcula la huella de un certificado ya leído; se ignoran File y Passphrase.
+func FingerprintCertificate(cert *x509.Certificate, opts FingerprintOptions) (string, error) {
+	if err := checkDigestOptions(opts); err != nil {
+		return "", err
+	}
+	return internal.FingerprintCertificate(cert, internal.FingerprintOptions{
+		Algorithm: opts.Algorithm,
+		Format:    opts.Format,
+		SPKI:      opts.SPKI,
+	})
+}
+
+// checkDigestOptions valida el algoritmo y el formato antes de leer nada.
+func checkDigestOptions(opts FingerprintOptions) error {
+	if _, err := internal.FormatDigest(nil, opts.Algorithm, FingerprintHex); err != nil {
+		return &OptionError{Option: "Algorithm", Reason: err.Error()}
+	}
+	if _, err := internal.FormatDigest(nil, "", opts.Format); err != nil {
+		return &OptionError{Option: "Format", Reason: err.Error()}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package ssltool
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+
+	"github.com/luisenrique-varelarodriguez/ssl-tool/internal"
+)
+
+// Info son los datos del sujeto, los SANs y la clave de un certificado o CSR.
+type Info struct {
+	CommonName         string
+	Country            string
+	Locality           string
+	Organization       string
+	OrganizationalUnit string
+	Email              string
+	SANs               []string // DNS e IP
+	KeyAlgorithm       string   // RSA, ECDSA o Ed25519
+	KeySize            int
+}
+
+// ConfigOptions indica el archivo de configuración YAML de ssl-tool en el que guardar Info.
+type ConfigOptions struct {
+	Path    string // obligatorio; se crea si no existe
+	Profile string // perfil con nombre; vacío para los valores por defecto
+}
+
+// Object es un certificado o un CSR de un archivo; solo uno de los dos campos está presente.
+type Object struct {
+	Certificate *x509.Certificate
+	CSR         *x509.CertificateRequest
+}
+
+// ExtractInfo lee el primer certificado o CSR de un archivo PEM y devuelve sus datos.
+func ExtractInfo(ctx context.Context, path string) (*Info, error) {
+	if err := checkFile(ctx, "path", path); err != nil {
+		return nil, err
+	}
+	config, err := internal.ExtractConfig(path)
+	if err != nil {
+		return nil, fileError(path, err)
+	}
+	return &Info{
+		CommonName:         config.DefaultDomain,
+		Country:            config.DefaultCountry,
+		Locality:           config.DefaultLocality,
+		Organization:       config.DefaultOrganization,
+		OrganizationalUnit: config.DefaultOrganizationalUnit,
+		Email:              config.DefaultEmail,
+		SANs:               config.DefaultSANs,
+		KeyAlgorithm:       config.DefaultKeyAlgorithm,
+		KeySize:            config.DefaultKeySize,
+	}, nil
+}
+
+// SaveInfo combina info con el archivo de configuración: solo se sobrescriben los campos no
+// vacíos de info y se conserva el resto del archivo, como hace extract-info.
+func SaveInfo(ctx context.Context, info *Info, opts ConfigOptions) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if opts.Path == "" {
+		return &OptionError{Option: "Path", Reason: "configuration path cannot be empty"}
+	}
+	extracted := internal.Config{
+		DefaultDomain:             info.CommonName,
+		DefaultCountry:            info.Country,
+		DefaultLocality:           info.Locality,
+		DefaultOrganization:       info.Organization,
+		DefaultOrganizationalUnit: info.OrganizationalUnit,
+		DefaultEmail:              info.Email,
+		DefaultSANs:               info.SANs,
+		DefaultKeyAlgorithm:       info.KeyAlgorithm,
+		DefaultKeySize:            info.KeySize,
+	}
+	if err := internal.MergeExtractedConfig(opts.Path, opts.Profile, extracted); err != nil {
+		return fileError(opts.Path, err)
+	}
+	return nil
+}
+
+// Inspect devuelve todos los certificados y CSRs de un archivo PEM, en orden.
+func Inspect(ctx context.Context, path string) ([]Object, error) {
+	if err := checkFile(ctx, "path", path); err != nil {
+		return nil, err
+	}
+	parsed, err := internal.LoadX509Objects(path)
+	if err != nil {
+		return nil, fileError(path, err)
+	}
+	if len(parsed) == 0 {
+		return nil, &FileError{Path: path, Err: errors.New("no certificate or CSR found")}
+	}
+	objects := make([]Object, len(parsed))
+	for i, object := range parsed {
+		objects[i] = Object{Certificate: object.Cert, CSR: object.CSR}
+	}
+	return objects, nil
+}
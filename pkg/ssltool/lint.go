@@ -0,0 +1,46 @@
+package ssltool
+
+import (
+	"context"
+	"crypto/x509"
+
+	"github.com/luisenrique-varelarodriguez/ssl-tool/internal"
+)
+
+// Severidades de LintFinding, de mayor a menor.
+const (
+	LintError  = internal.LintError
+	LintWarn   = internal.LintWarn
+	LintNotice = internal.LintNotice
+)
+
+// LintResult son los hallazgos de un certificado o CSR de un archivo.
+type LintResult = internal.LintResult
+
+// LintFinding es el incumplimiento de una regla de lint.
+type LintFinding = internal.LintFinding
+
+// Lint revisa todos los certificados y CSRs de los archivos PEM o DER indicados, en orden.
+func Lint(ctx context.Context, files ...string) ([]LintResult, error) {
+	var results []LintResult
+	for _, file := range files {
+		if err := checkFile(ctx, "files", file); err != nil {
+			return nil, err
+		}
+		fileResults, err := internal.LintFile(file)
+		if err != nil {
+			return nil, fileError(file, err)
+		}
+		results = append(results, fileResults...)
+	}
+	return results, nil
+}
+
+// LintCertificates revisa certificados ya leídos, por ejemplo los devueltos por FetchChain.
+func LintCertificates(certs []*x509.Certificate) []LintResult {
+	results := make([]LintResult, len(certs))
+	for i, cert := range certs {
+		results[i] = LintResult{Index: i, Kind: internal.InventoryCertificate, Subject: cert.Subject.String(), Findings: internal.LintCertificate(cert)}
+	}
+	return results
+}
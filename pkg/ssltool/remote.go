@@ -0,0 +1,51 @@
+package ssltool
+
+import (
+	"context"
+	"crypto/x509"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/luisenrique-varelarodriguez/ssl-tool/internal"
+)
+
+// RemoteOptions describe el endpoint TLS del que FetchChain obtiene la cadena.
+type RemoteOptions struct {
+	Address  string        // host:port; sin puerto se usa el 443 o el del protocolo STARTTLS
+	SNI      string        // nombre enviado en el ClientHello; por defecto, el host
+	Timeout  time.Duration // límite para la conexión y el handshake; por defecto 10s
+	StartTLS string        // smtp, imap, pop3, ftp, ldap, postgres... (ver StartTLSProtocols)
+}
+
+// StartTLSProtocols devuelve los valores admitidos en RemoteOptions.StartTLS.
+func StartTLSProtocols() []string {
+	return internal.StartTLSProtocols()
+}
+
+// FetchChain realiza un handshake TLS y devuelve la cadena tal y como la presenta el servidor, sin
+// validarla (ver VerifyChain). Cancelar ctx interrumpe la conexión y el handshake.
+func FetchChain(ctx context.Context, opts RemoteOptions) ([]*x509.Certificate, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if opts.Address == "" {
+		return nil, &OptionError{Option: "Address", Reason: "remote address cannot be empty"}
+	}
+	if opts.StartTLS != "" && !slices.Contains(StartTLSProtocols(), opts.StartTLS) {
+		return nil, &OptionError{Option: "StartTLS", Reason: "unsupported STARTTLS protocol: " + opts.StartTLS + " (supported: " + strings.Join(StartTLSProtocols(), ", ") + ")"}
+	}
+	chain, err := internal.FetchRemoteChainContext(ctx, internal.RemoteOptions{
+		Address:  opts.Address,
+		SNI:      opts.SNI,
+		Timeout:  opts.Timeout,
+		StartTLS: opts.StartTLS,
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, &RemoteError{Address: opts.Address, Err: err}
+	}
+	return chain, nil
+}
@@ -0,0 +1,96 @@
+// Package ssltool expone las operaciones de ssl-tool para usarlas desde otros programas en Go.
+//
+// Las funciones reciben un context.Context y una estructura de opciones y devuelven sus
+// resultados: no escriben en la salida estándar ni crean archivos en el directorio actual, y solo
+// escriben en las rutas indicadas expresamente en las opciones. Los errores de las opciones, los
+// archivos y las comprobaciones son de los tipos de este paquete (OptionError, FileError,
+// HashMismatchError, ChainError, RemoteError), y el del contexto si se cancela antes de terminar.
+// Cualquier otro error (por ejemplo, al codificar una clave recién generada) es un fallo interno.
+package ssltool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// ErrInvalidOption es el error base de OptionError: errors.Is(err, ErrInvalidOption) identifica
+// cualquier opción inválida.
+var ErrInvalidOption = errors.New("invalid option")
+
+// OptionError indica que falta una opción obligatoria o que su valor no es válido.
+type OptionError struct {
+	Option string // campo de la estructura de opciones
+	Reason string
+}
+
+func (e *OptionError) Error() string { return e.Reason }
+
+// Is permite comparar con ErrInvalidOption.
+func (e *OptionError) Is(target error) bool { return target == ErrInvalidOption }
+
+// FileError indica que un archivo de entrada no existe, no se puede leer o no tiene el contenido
+// esperado. Err es la causa (fs.ErrNotExist si no existe).
+type FileError struct {
+	Path string
+	Err  error
+}
+
+func (e *FileError) Error() string { return fmt.Sprintf("%s: %v", e.Path, e.Err) }
+
+func (e *FileError) Unwrap() error { return e.Err }
+
+// HashMismatchError indica que la clave, el CSR y el certificado no comparten la clave pública.
+type HashMismatchError struct {
+	Hashes PublicKeyHashes
+}
+
+func (e *HashMismatchError) Error() string {
+	return fmt.Sprintf("hashes do not match: key %s, CSR %s, certificate %s", e.Hashes.Key, e.Hashes.CSR, e.Hashes.Certificate)
+}
+
+// ChainError indica que no se ha podido construir una cadena válida; Reason explica el motivo concreto.
+type ChainError struct {
+	Reason string
+}
+
+func (e *ChainError) Error() string { return "chain verification failed: " + e.Reason }
+
+// RemoteError indica que no se ha podido conectar o completar el handshake con un endpoint TLS.
+type RemoteError struct {
+	Address string
+	Err     error
+}
+
+func (e *RemoteError) Error() string { return e.Err.Error() }
+
+func (e *RemoteError) Unwrap() error { return e.Err }
+
+// checkFile comprueba el contexto y que la opción option apunte a un archivo existente.
+func checkFile(ctx context.Context, option, path string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if path == "" {
+		return &OptionError{Option: option, Reason: option + " cannot be empty"}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileError(path, err)
+	}
+	if info.IsDir() {
+		return &FileError{Path: path, Err: errors.New("is a directory")}
+	}
+	return nil
+}
+
+// fileError envuelve err en un FileError, sin repetir la ruta si es un *fs.PathError.
+func fileError(path string, err error) error {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		err = pathErr.Err
+	}
+	return &FileError{Path: path, Err: err}
+}
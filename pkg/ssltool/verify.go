@@ -0,0 +1,142 @@
+package ssltool
+
+import (
+	"context"
+	"crypto/x509"
+	"time"
+
+	"github.com/luisenrique-varelarodriguez/ssl-tool/internal"
+)
+
+// Propósitos de VerifyChain.
+const (
+	PurposeServer = internal.PurposeServer
+	PurposeClient = internal.PurposeClient
+	PurposeAny    = internal.PurposeAny
+)
+
+// HashesOptions son los tres archivos que compara VerifyHashes.
+type HashesOptions struct {
+	KeyFile    string // clave privada en PKCS#1, PKCS#8 o SEC1
+	CSRFile    string
+	CertFile   string
+	Passphrase []byte // si la clave está cifrada
+}
+
+// PublicKeyHashes es el MD5 en hexadecimal de la clave pública (SPKI) de cada archivo.
+type PublicKeyHashes struct {
+	Key         string
+	CSR         string
+	Certificate string
+}
+
+// Match indica si los tres archivos comparten la clave pública.
+func (h PublicKeyHashes) Match() bool {
+	return h.Key == h.CSR && h.CSR == h.Certificate
+}
+
+// VerifyHashes comprueba que la clave privada, el CSR y el certificado comparten la misma clave
+// pública. Si no coinciden devuelve los hashes y un *HashMismatchError.
+func VerifyHashes(ctx context.Context, opts HashesOptions) (PublicKeyHashes, error) {
+	var hashes PublicKeyHashes
+	for _, file := range []struct {
+		option string
+		path   string
+		hash   *string
+	}{
+		{"KeyFile", opts.KeyFile, &hashes.Key},
+		{"CSRFile", opts.CSRFile, &hashes.CSR},
+		{"CertFile", opts.CertFile, &hashes.Certificate},
+	} {
+		if err := checkFile(ctx, file.option, file.path); err != nil {
+			return PublicKeyHashes{}, err
+		}
+		hash, err := internal.PublicKeyHash(file.path, opts.Passphrase)
+		if err != nil {
+			return PublicKeyHashes{}, fileError(file.path, err)
+		}
+		*file.hash = hash
+	}
+	if !hashes.Match() {
+		return hashes, &HashMismatchError{Hashes: hashes}
+	}
+	return hashes, nil
+}
+
+// ChainOptions son el certificado a verificar y las CAs en las que confiar.
+type ChainOptions struct {
+	// Certificado hoja; si el archivo tiene más certificados se usan como intermedios
+	CertFile string
+	// Alternativa a CertFile: la hoja seguida de los intermedios, p. ej. los de FetchChain
+	Certificates      []*x509.Certificate
+	IntermediatesFile string
+	RootsFile         string    // sin él se usan las raíces del sistema
+	SystemRoots       bool      // usar también las raíces del sistema con RootsFile
+	Hostname          string    // nombre que debe cubrir la hoja
+	At                time.Time // instante de la verificación; vacío significa ahora
+	Purpose           string    // server (por defecto), client o any
+}
+
+// VerifyChain construye y valida todas las rutas desde la hoja hasta una raíz de confianza. Si no
+// hay ninguna, devuelve un *ChainError con el motivo concreto.
+func VerifyChain(ctx context.Context, opts ChainOptions) ([][]*x509.Certificate, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	certs := opts.Certificates
+	if opts.CertFile != "" {
+		if err := checkFile(ctx, "CertFile", opts.CertFile); err != nil {
+			return nil, err
+		}
+		loaded, err := internal.LoadCertificates(opts.CertFile)
+		if err != nil {
+			return nil, fileError(opts.CertFile, err)
+		}
+		certs = loaded
+	}
+	if len(certs) == 0 {
+		return nil, &OptionError{Option: "CertFile", Reason: "a certificate file or certificates are required"}
+	}
+	switch opts.Purpose {
+	case "", PurposeServer, PurposeClient, PurposeAny:
+	default:
+		return nil, &OptionError{Option: "Purpose", Reason: "unsupported purpose: " + opts.Purpose}
+	}
+
+	chainOpts := internal.ChainOptions{
+		Intermediates: certs[1:],
+		SystemRoots:   opts.SystemRoots || opts.RootsFile == "",
+		Hostname:      opts.Hostname,
+		At:            opts.At,
+		Purpose:       opts.Purpose,
+	}
+	if chainOpts.Purpose == "" {
+		chainOpts.Purpose = PurposeServer
+	}
+	for _, bundle := range []struct {
+		option string
+		path   string
+		certs  *[]*x509.Certificate
+	}{
+		{"IntermediatesFile", opts.IntermediatesFile, &chainOpts.Intermediates},
+		{"RootsFile", opts.RootsFile, &chainOpts.Roots},
+	} {
+		if bundle.path == "" {
+			continue
+		}
+		if err := checkFile(ctx, bundle.option, bundle.path); err != nil {
+			return nil, err
+		}
+		loaded, err := internal.LoadCertificates(bundle.path)
+		if err != nil {
+			return nil, fileError(bundle.path, err)
+		}
+		*bundle.certs = append(*bundle.certs, loaded...)
+	}
+
+	chains, err := internal.VerifyChain(certs[0], chainOpts)
+	if err != nil {
+		return nil, &ChainError{Reason: err.Error()}
+	}
+	return chains, nil
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// pebbleArgs devuelve los argumentos para usar un servidor Pebble local, o salta el test si no hay.
+// Pebble se arranca con su configuración de ejemplo y PEBBLE_VA_NOSLEEP=1:
+//
+//	PEBBLE_DIRECTORY=https://localhost:14000/dir PEBBLE_CA=test/certs/pebble.minica.pem go test ./tests
+func pebbleArgs(t *testing.T, dir string) []string {
+	directory := os.Getenv("PEBBLE_DIRECTORY")
+	if directory == "" {
+		t.Skip("set PEBBLE_DIRECTORY (and PEBBLE_CA) to run the ACME tests against Pebble")
+	}
+	args := []string{"--directory", directory, "--account-key", filepath.Join(dir, "account.key")}
+	if ca := os.Getenv("PEBBLE_CA"); ca != "" {
+		args = append(args, "--ca-roots", ca)
+	}
+	return args
+}
+
+// pebbleHTTPListen devuelve --http-listen con el puerto al que Pebble envía la validación HTTP-01
+// (PEBBLE_HTTP_PORT, por defecto 5002).
+func pebbleHTTPListen() []string {
+	port := os.Getenv("PEBBLE_HTTP_PORT")
+	if port == "" {
+		port = "5002"
+	}
+	return []string{"--http-listen", ":" + port}
+}
+
+// Test para acme issue contra Pebble con el servidor HTTP-01 propio y con un webroot
+func TestACMEIssuePebble(t *testing.T) {
+	dir := t.TempDir()
+	args := append(pebbleArgs(t, dir), pebbleHTTPListen()...)
+
+	out, err := runCommand(t, append([]string{"acme", "issue", "--domain", "localhost", "--email", "test@example.com", "--output-dir", dir, "--key-type", "ec256"}, args...)...)
+	if err != nil {
+		t.Fatalf("Error running acme issue: %v\n%s", err, out)
+	}
+	for _, expected := range []string{"Registered ACME account", "Certificate issued for localhost"} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("Expected %q in the output:\n%s", expected, out)
+		}
+	}
+	certPin, _ := runCommand(t, "fingerprint", "--spki", "--file", filepath.Join(dir, "localhost.crt"))
+	keyPin, _ := runCommand(t, "fingerprint", "--spki", "--file", filepath.Join(dir, "localhost.key"))
+	if certPin == "" || certPin != keyPin {
+		t.Fatalf("The issued certificate does not match its key:\n%s\n%s", certPin, keyPin)
+	}
+
+	// La segunda emisión reutiliza la cuenta
+	out, err = runCommand(t, append([]string{"acme", "issue", "--domain", "localhost", "--output-dir", dir}, args...)...)
+	if err != nil || !strings.Contains(out, "Using ACME account") {
+		t.Fatalf("Expected the account to be reused: %v\n%s", err, out)
+	}
+}
+
+// Test para las validaciones que no necesitan servidor ACME
+func TestACMEIssueValidation(t *testing.T) {
+	out, err := runCommand(t, "acme", "issue")
+	if err == nil || !strings.Contains(out, "please provide --domain") {
+		t.Fatalf("Expected an error without --domain:\n%s", out)
+	}
+	out, err = runCommand(t, "acme", "issue", "--domain", "*.example.com")
+	if err == nil || !strings.Contains(out, "cannot be validated with HTTP-01") {
+		t.Fatalf("Expected wildcard domains to be rejected:\n%s", out)
+	}
+}
+
+// Test para acme account y acme revoke contra Pebble: ciclo de vida completo de la cuenta
+func TestACMEAccountPebble(t *testing.T) {
+	dir := t.TempDir()
+	args := pebbleArgs(t, dir)
+	acme := func(extra ...string) (string, error) {
+		return runCommand(t, append(append([]string{"acme"}, extra...), args...)...)
+	}
+
+	out, err := acme("account", "register", "--email", "first@example.com", "--key-type", "rsa2048")
+	if err != nil || !strings.Contains(out, "Registered ACME account") || !strings.Contains(out, "(RSA 2048)") {
+		t.Fatalf("Error registering the account: %v\n%s", err, out)
+	}
+	out, err = acme("account", "register")
+	if err != nil || !strings.Contains(out, "already has an ACME account") {
+		t.Fatalf("Expected the existing account to be reported: %v\n%s", err, out)
+	}
+
+	out, err = acme("account", "update-contact", "--email", "second@example.com,third@example.com")
+	if err != nil || !strings.Contains(out, "Contacts: mailto:second@example.com, mailto:third@example.com") {
+		t.Fatalf("Error updating the contacts: %v\n%s", err, out)
+	}
+
+	// Tras el cambio de clave la cuenta sigue siendo la misma
+	before, _ := acme("account", "show")
+	out, err = acme("account", "key-rollover", "--key-type", "ec384")
+	if err != nil || !strings.Contains(out, "(ECDSA P-384)") {
+		t.Fatalf("Error rolling over the key: %v\n%s", err, out)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "account.key.old")); err != nil {
+		t.Fatalf("Expected the previous key to be kept: %v", err)
+	}
+	after, err := acme("account", "show")
+	if err != nil || strings.Split(before, "\n")[0] != strings.Split(after, "\n")[0] || before == after {
+		t.Fatalf("Expected the same account with a new key:\n%s\n%s", before, after)
+	}
+
+	// Revocación con la clave de la cuenta y con la del certificado
+	for i, keyFlag := range []string{"", "--cert-key"} {
+		outDir := filepath.Join(dir, fmt.Sprintf("cert%d", i))
+		if out, err := acme(append([]string{"issue", "--domain", "localhost", "--output-dir", outDir}, pebbleHTTPListen()...)...); err != nil {
+			t.Fatalf("Error running acme issue: %v\n%s", err, out)
+		}
+		revoke := []string{"revoke", "--cert", filepath.Join(outDir, "localhost.crt"), "--reason", "superseded"}
+		if keyFlag != "" {
+			revoke = append(revoke, keyFlag, filepath.Join(outDir, "localhost.key"))
+		}
+		out, err := acme(revoke...)
+		if err != nil || !strings.Contains(out, "Revoked certificate for localhost") {
+			t.Fatalf("Error revoking the certificate: %v\n%s", err, out)
+		}
+	}
+
+	out, err = acme("account", "deactivate", "--yes")
+	if err != nil || !strings.Contains(out, "Deactivated the ACME account") {
+		t.Fatalf("Error deactivating the account: %v\n%s", err, out)
+	}
+	out, err = acme("account", "show")
+	if err == nil || !strings.Contains(out, "deactivated") {
+		t.Fatalf("Expected the account to be deactivated:\n%s", out)
+	}
+}
+
+// Test para las validaciones de acme account y acme revoke que no necesitan servidor ACME
+func TestACMEAccountValidation(t *testing.T) {
+	dir := t.TempDir()
+	config := filepath.Join(dir, "conf", "ssl-tool-config.yaml")
+
+	// Sin --account-key la clave se busca junto a la configuración
+	out, err := runCommand(t, "acme", "account", "show", "--config", config)
+	if err == nil || !strings.Contains(out, filepath.Join(dir, "conf", "acme-account.key")+" does not exist") {
+		t.Fatalf("Expected the missing account key next to the config:\n%s", out)
+	}
+	out, err = runCommand(t, "acme", "account", "deactivate", "--config", config)
+	if err == nil || !strings.Contains(out, "confirm with --yes") {
+		t.Fatalf("Expected deactivate to require --yes:\n%s", out)
+	}
+
+	// La EAB se valida antes de contactar con el directorio (que aquí no existe)
+	register := func(args ...string) (string, error) {
+		return runCommand(t, append([]string{"acme", "account", "register", "--directory", "http://127.0.0.1:1/directory",
+			"--account-key", filepath.Join(dir, "eab", "account.key")}, args...)...)
+	}
+	out, err = register("--eab-kid", "kid-1", "--eab-hmac-key", "not+base64/url==!")
+	if err == nil || !strings.Contains(out, "invalid EAB HMAC key (expected base64url)") {
+		t.Fatalf("Expected a non-base64url EAB HMAC key to be rejected:\n%s", out)
+	}
+	out, err = register("--eab-hmac-key", "c2VjcmV0")
+	if err == nil || !strings.Contains(out, "needs both the key ID and the HMAC key") {
+		t.Fatalf("Expected an EAB HMAC key without --eab-kid to be rejected:\n%s", out)
+	}
+
+	out, err = runCommand(t, "acme", "revoke")
+	if err == nil || !strings.Contains(out, "please provide --cert") {
+		t.Fatalf("Expected an error without --cert:\n%s", out)
+	}
+
+	// La clave del certificado debe corresponder al certificado
+	cert := newTestLeaf(t, newTestCA(t, "Test CA", nil), "www.example.com")
+	other := newTestLeaf(t, newTestCA(t, "Other CA", nil), "www.example.com")
+	certPath, keyPath := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "other.key")
+	writePEM(t, certPath, cert)
+	writeKey(t, keyPath, other)
+	out, err = runCommand(t, "acme", "revoke", "--cert", certPath, "--cert-key", keyPath)
+	if err == nil || !strings.Contains(out, "does not match the certificate") {
+		t.Fatalf("Expected a mismatched key to be rejected:\n%s", out)
+	}
+	out, err = runCommand(t, "acme", "revoke", "--cert", certPath, "--reason", "whatever", "--account-key", keyPath)
+	if err == nil || !strings.Contains(out, "unsupported revocation reason") {
+		t.Fatalf("Expected an unknown reason to be rejected:\n%s", out)
+	}
+}
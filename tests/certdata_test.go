@@ -0,0 +1,135 @@
+package main
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// certdataOctal codifica data como un valor MULTILINE_OCTAL de certdata.txt.
+func certdataOctal(data []byte) string {
+	var b strings.Builder
+	b.WriteString("MULTILINE_OCTAL\n")
+	for i, c := range data {
+		fmt.Fprintf(&b, "\\%03o", c)
+		if i%16 == 15 || i == len(data)-1 {
+			b.WriteString("\n")
+		}
+	}
+	b.WriteString("END\n")
+	return b.String()
+}
+
+// certdataObject escribe un certificado y su objeto de confianza con el formato de NSS.
+// distrustAfter (para servidor y correo) vacío equivale a CK_FALSE.
+func certdataObject(c *testCert, label, serverTrust, emailTrust string, inPolicy bool, distrustAfter string) string {
+	policy := "CK_TRUE"
+	if !inPolicy {
+		policy = "CK_FALSE"
+	}
+	distrust := "CK_BBOOL CK_FALSE\n"
+	if distrustAfter != "" {
+		distrust = certdataOctal([]byte(distrustAfter))
+	}
+	sum := sha1.Sum(c.cert.Raw)
+	return fmt.Sprintf(`
+#
+# Certificate "%[1]s"
+#
+CKA_CLASS CK_OBJECT_CLASS CKO_CERTIFICATE
+CKA_TOKEN CK_BBOOL CK_TRUE
+CKA_LABEL UTF8 "%[1]s"
+CKA_CERTIFICATE_TYPE CK_CERTIFICATE_TYPE CKC_X_509
+CKA_VALUE %[2]sCKA_NSS_MOZILLA_CA_POLICY CK_BBOOL %[3]s
+CKA_NSS_SERVER_DISTRUST_AFTER %[4]sCKA_NSS_EMAIL_DISTRUST_AFTER %[4]s
+
+# Trust for "%[1]s"
+CKA_CLASS CK_OBJECT_CLASS CKO_NSS_TRUST
+CKA_TOKEN CK_BBOOL CK_TRUE
+CKA_LABEL UTF8 "%[1]s"
+CKA_CERT_SHA1_HASH %[5]sCKA_TRUST_SERVER_AUTH CK_TRUST %[6]s
+CKA_TRUST_EMAIL_PROTECTION CK_TRUST %[7]s
+CKA_TRUST_CODE_SIGNING CK_TRUST CKT_NSS_MUST_VERIFY_TRUST
+CKA_TRUST_STEP_UP_APPROVED CK_BBOOL CK_FALSE
+`, label, certdataOctal(c.cert.Raw), policy, distrust, certdataOctal(sum[:]), serverTrust, emailTrust)
+}
+
+// Test para truststore import-certdata y diff
+func TestTrustStoreCertdata(t *testing.T) {
+	dir := t.TempDir()
+	server := newTestCA(t, "Server Root", nil)
+	email := newTestCA(t, "Email Root", nil)
+	distrusted := newTestCA(t, "Distrusted Root", nil)
+	phasingOut := newTestCA(t, "Phasing Out Root", nil)
+	untrusted := newTestCA(t, "Untrusted Root", nil)
+	outside := newTestCA(t, "Outside Policy Root", nil)
+	future := time.Now().AddDate(1, 0, 0).UTC()
+
+	const trusted, mustVerify, notTrusted = "CKT_NSS_TRUSTED_DELEGATOR", "CKT_NSS_MUST_VERIFY_TRUST", "CKT_NSS_NOT_TRUSTED"
+	certdata := filepath.Join(dir, "certdata.txt")
+	content := "# This Source Code Form is subject to the terms of the Mozilla Public License\n" +
+		"BEGINDATA\nCKA_CLASS CK_OBJECT_CLASS CKO_NSS_BUILTIN_ROOT_LIST\nCKA_TOKEN CK_BBOOL CK_TRUE\nCKA_LABEL UTF8 \"Mozilla Builtin Roots\"\n" +
+		certdataObject(server, "Server Root CA", trusted, mustVerify, true, "") +
+		certdataObject(email, "Email Root CA", mustVerify, trusted, true, "") +
+		certdataObject(distrusted, "Distrusted Root CA", trusted, trusted, true, "200101000000Z") +
+		certdataObject(phasingOut, "Phasing Out Root CA", trusted, mustVerify, true, future.Format("060102")+"000000Z") +
+		certdataObject(untrusted, "Untrusted Root CA", notTrusted, notTrusted, true, "") +
+		certdataObject(outside, "Outside Policy Root CA", trusted, trusted, false, "")
+	if err := os.WriteFile(certdata, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write certdata.txt: %v", err)
+	}
+
+	// Sin --bundle el almacén sale por la salida estándar
+	out, err := runCommand(t, "truststore", "import-certdata", certdata)
+	if err != nil || !strings.Contains(out, "Imported 2 of 6 certificates trusted for server") ||
+		!strings.Contains(out, "Skipped Distrusted Root CA: distrusted after 2020-01-01") ||
+		!strings.Contains(out, "# Label: Server Root CA") || strings.Contains(out, "Email Root CA") ||
+		!strings.Contains(out, "# Distrust After: "+future.Format(time.DateOnly)) {
+		t.Fatalf("Unexpected truststore import-certdata output: %v\n%s", err, out)
+	}
+
+	// Un almacén previo con una raíz que Mozilla ya no incluye
+	bundle := filepath.Join(dir, "bundle.pem")
+	writePEM(t, bundle, server, untrusted)
+	out, err = runCommand(t, "truststore", "import-certdata", certdata, "--bundle", bundle, "--dry-run")
+	if err != nil || !strings.Contains(out, "+ "+testFingerprint(phasingOut)[:16]+" Phasing Out Root CA") ||
+		!strings.Contains(out, "- "+testFingerprint(untrusted)[:16]+" Untrusted Root") || !strings.Contains(out, "1 added, 1 removed, 1 unchanged") {
+		t.Fatalf("Unexpected truststore import-certdata --dry-run output: %v\n%s", err, out)
+	}
+	if data, _ := os.ReadFile(bundle); strings.Contains(string(data), "Phasing Out") {
+		t.Fatalf("--dry-run must not modify the bundle:\n%s", data)
+	}
+
+	out, err = runCommand(t, "truststore", "import-certdata", certdata, "--bundle", bundle)
+	if err != nil || !strings.Contains(out, "Saved 2 certificate(s)") {
+		t.Fatalf("Error running truststore import-certdata: %v\n%s", err, out)
+	}
+	if out, err := runCommand(t, "truststore", "verify", "--bundle", bundle, "--canonical"); err != nil {
+		t.Fatalf("Expected the imported bundle to verify: %v\n%s", err, out)
+	}
+
+	// Correo electrónico, conservando las raíces ya vetadas; diff contra el almacén de servidor
+	emailBundle := filepath.Join(dir, "email.pem")
+	out, err = runCommand(t, "truststore", "import-certdata", certdata, "--purpose", "email", "--keep-distrusted", "--out", emailBundle)
+	if err != nil || !strings.Contains(out, "Imported 2 of 6 certificates trusted for email") {
+		t.Fatalf("Error running truststore import-certdata --purpose email: %v\n%s", err, out)
+	}
+	out, err = runCommand(t, "truststore", "verify", "--bundle", emailBundle)
+	if err == nil || !strings.Contains(out, "distrusted for certificates issued after 2020-01-01") {
+		t.Fatalf("Expected verify to report the distrusted root:\n%s", out)
+	}
+	out, err = runCommand(t, "truststore", "diff", emailBundle, "--bundle", bundle)
+	if err != nil || !strings.Contains(out, "+ "+testFingerprint(email)[:16]) || !strings.Contains(out, "+ "+testFingerprint(distrusted)[:16]) ||
+		!strings.Contains(out, "- "+testFingerprint(server)[:16]) || !strings.Contains(out, "2 added, 2 removed, 0 unchanged") {
+		t.Fatalf("Unexpected truststore diff output: %v\n%s", err, out)
+	}
+
+	out, err = runCommand(t, "truststore", "import-certdata", certdata, "--purpose", "code")
+	if err == nil || !strings.Contains(out, "unsupported purpose: code") {
+		t.Fatalf("Expected an unknown purpose to fail:\n%s", out)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test para verify-chain con raíces e intermedios propios
+func TestVerifyChain(t *testing.T) {
+	dir := t.TempDir()
+	root := newTestCA(t, "Test Root", nil)
+	intermediate := newTestCA(t, "Test Intermediate", root)
+	leaf := newTestLeaf(t, intermediate, "www.example.com")
+
+	leafFile := filepath.Join(dir, "leaf.pem")
+	intermediatesFile := filepath.Join(dir, "intermediates.pem")
+	rootsFile := filepath.Join(dir, "roots.pem")
+	writePEM(t, leafFile, leaf)
+	writePEM(t, intermediatesFile, intermediate)
+	writePEM(t, rootsFile, root)
+
+	out, err := runCommand(t, "verify-chain", "--cert", leafFile, "--intermediates", intermediatesFile, "--roots", rootsFile, "--hostname", "www.example.com")
+	if err != nil {
+		t.Fatalf("Error running verify-chain: %v\n%s", err, out)
+	}
+	if !strings.Contains(out, "1 path(s)") || !strings.Contains(out, "CN=Test Root") {
+		t.Fatalf("Unexpected verify-chain output:\n%s", out)
+	}
+
+	// Un fullchain en --cert también aporta los intermedios
+	fullchainFile := filepath.Join(dir, "fullchain.pem")
+	writePEM(t, fullchainFile, leaf, intermediate)
+	if out, err := runCommand(t, "verify-chain", "--cert", fullchainFile, "--roots", rootsFile); err != nil {
+		t.Fatalf("Error verifying a fullchain: %v\n%s", err, out)
+	}
+
+	cases := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"missing intermediate", []string{"--cert", leafFile, "--roots", rootsFile}, "unknown authority"},
+		{"expired", []string{"--cert", leafFile, "--intermediates", intermediatesFile, "--roots", rootsFile, "--at", time.Now().AddDate(1, 0, 0).Format(time.RFC3339)}, "expired link"},
+		{"hostname", []string{"--cert", leafFile, "--intermediates", intermediatesFile, "--roots", rootsFile, "--hostname", "other.example.org"}, "hostname mismatch"},
+		{"purpose", []string{"--cert", leafFile, "--intermediates", intermediatesFile, "--roots", rootsFile, "--purpose", "client"}, "EKU mismatch"},
+	}
+	for _, c := range cases {
+		out, err := runCommand(t, append([]string{"verify-chain"}, c.args...)...)
+		if err == nil {
+			t.Fatalf("%s: expected verify-chain to fail:\n%s", c.name, out)
+		}
+		if !strings.Contains(out, c.want) {
+			t.Fatalf("%s: expected %q in output:\n%s", c.name, c.want, out)
+		}
+	}
+
+	t.Log("verify-chain passed successfully")
+}
+
+// Test para la explicación de violaciones de name constraints
+func TestVerifyChainNameConstraints(t *testing.T) {
+	dir := t.TempDir()
+	root := newTestCA(t, "Constrained Root", nil)
+	constrained := newTestCert(t, &x509.Certificate{
+		Subject:                     pkix.Name{CommonName: "Constrained Intermediate"},
+		IsCA:                        true,
+		BasicConstraintsValid:       true,
+		KeyUsage:                    x509.KeyUsageCertSign,
+		PermittedDNSDomainsCritical: true,
+		PermittedDNSDomains:         []string{"corp.example"},
+	}, root)
+	leaf := newTestLeaf(t, constrained, "www.example.com")
+
+	leafFile := filepath.Join(dir, "leaf.pem")
+	rootsFile := filepath.Join(dir, "roots.pem")
+	writePEM(t, leafFile, leaf, constrained)
+	writePEM(t, rootsFile, root)
+
+	out, err := runCommand(t, "verify-chain", "--cert", leafFile, "--roots", rootsFile)
+	if err == nil {
+		t.Fatalf("Expected verify-chain to fail:\n%s", out)
+	}
+	if !strings.Contains(out, "name constraint violation") {
+		t.Fatalf("Expected a name constraint explanation:\n%s", out)
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	testDNSZone       = "example.test."
+	testTSIGKey       = "ssl-tool-test."
+	testTSIGSecret    = "c3NsLXRvb2wgdGVzdCBzZWNyZXQga2V5IDAxMjM0NTY3"
+	testTSIGBadSecret = "b3RoZXIgc2VjcmV0IHRoYXQgZG9lcyBub3QgbWF0Y2g="
+)
+
+// dnsServer es un servidor autoritativo mínimo para testDNSZone que acepta actualizaciones RFC 2136
+// firmadas con testTSIGKey y responde a consultas SOA y TXT.
+type dnsServer struct {
+	address string
+	mu      sync.Mutex
+	records map[string][]string
+	updates []string
+}
+
+// newDNSServer arranca el servidor en address (UDP y TCP) o en un puerto libre si está vacía.
+func newDNSServer(t *testing.T, address string) *dnsServer {
+	t.Helper()
+	if address == "" {
+		address = freeAddress(t)
+	}
+	s := &dnsServer{address: address, records: map[string][]string{}}
+	secrets := map[string]string{testTSIGKey: testTSIGSecret}
+
+	packetConn, err := net.ListenPacket("udp", address)
+	if err != nil {
+		t.Fatalf("Failed to listen on %s/udp: %v", address, err)
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		packetConn.Close()
+		t.Fatalf("Failed to listen on %s/tcp: %v", address, err)
+	}
+	// El filtro por defecto de la librería rechaza las actualizaciones con NOTIMP
+	accept := func(dns.Header) dns.MsgAcceptAction { return dns.MsgAccept }
+	for _, server := range []*dns.Server{
+		{PacketConn: packetConn, Handler: s, TsigSecret: secrets, MsgAcceptFunc: accept},
+		{Listener: listener, Handler: s, TsigSecret: secrets, MsgAcceptFunc: accept},
+	} {
+		go server.ActivateAndServe()
+		t.Cleanup(func() { server.Shutdown() })
+	}
+	return s
+}
+
+func (s *dnsServer) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	reply := new(dns.Msg)
+	reply.SetReply(r)
+	reply.Authoritative = true
+	if len(r.Question) != 1 {
+		reply.Rcode = dns.RcodeFormatError
+		w.WriteMsg(reply)
+		return
+	}
+	question := r.Question[0]
+	name := strings.ToLower(question.Name)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.Opcode == dns.OpcodeUpdate {
+		tsig := r.IsTsig()
+		switch {
+		case tsig == nil || w.TsigStatus() != nil:
+			reply.Rcode = dns.RcodeNotAuth
+		case name != testDNSZone:
+			reply.Rcode = dns.RcodeNotZone
+		default:
+			for _, rr := range r.Ns {
+				txt, ok := rr.(*dns.TXT)
+				if !ok {
+					continue
+				}
+				owner, value := strings.ToLower(txt.Hdr.Name), strings.Join(txt.Txt, "")
+				if txt.Hdr.Class == dns.ClassNONE {
+					s.records[owner] = slices.DeleteFunc(s.records[owner], func(v string) bool { return v == value })
+					s.updates = append(s.updates, "delete "+owner+" "+value)
+				} else {
+					s.records[owner] = append(s.records[owner], value)
+					s.updates = append(s.updates, "add "+owner+" "+value)
+				}
+			}
+		}
+		if tsig != nil {
+			reply.SetTsig(tsig.Hdr.Name, tsig.Algorithm, 300, time.Now().Unix())
+		}
+		w.WriteMsg(reply)
+		return
+	}
+
+	if !dns.IsSubDomain(testDNSZone, name) {
+		reply.Rcode = dns.RcodeRefused
+		w.WriteMsg(reply)
+		return
+	}
+	soa := &dns.SOA{
+		Hdr:    dns.RR_Header{Name: testDNSZone, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: 60},
+		Ns:     "ns." + testDNSZone,
+		Mbox:   "hostmaster." + testDNSZone,
+		Serial: 1, Refresh: 3600, Retry: 600, Expire: 86400, Minttl: 60,
+	}
+	switch {
+	case question.Qtype == dns.TypeSOA && name == testDNSZone:
+		reply.Answer = append(reply.Answer, soa)
+	case question.Qtype == dns.TypeTXT && len(s.records[name]) > 0:
+		for _, value := range s.records[name] {
+			reply.Answer = append(reply.Answer, &dns.TXT{
+				Hdr: dns.RR_Header{Name: question.Name, Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: 60},
+				Txt: []string{value},
+			})
+		}
+	default:
+		reply.Ns = append(reply.Ns, soa)
+	}
+	w.WriteMsg(reply)
+}
+
+func (s *dnsServer) getUpdates() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.updates)
+}
+
+func (s *dnsServer) getRecords(name string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.records[name])
+}
+
+// Test para acme dns-check con el proveedor RFC 2136: descubrimiento de la zona, TSIG y limpieza
+func TestDNS01RFC2136(t *testing.T) {
+	server := newDNSServer(t, "")
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "tsig.secret")
+	os.WriteFile(secretFile, []byte(testTSIGSecret+"\n"), 0600)
+
+	args := []string{"acme", "dns-check", "--domain", "www.example.test", "--dns-provider", "rfc2136",
+		"--dns-nameserver", server.address, "--tsig-key", testTSIGKey, "--dns-propagation-timeout", "5s"}
+	out, err := runCommand(t, append(args, "--tsig-secret", "file:"+secretFile)...)
+	if err != nil {
+		t.Fatalf("Error running acme dns-check: %v\n%s", err, out)
+	}
+	updates := server.getUpdates()
+	if len(updates) != 2 || !strings.HasPrefix(updates[0], "add _acme-challenge.www.example.test. ") ||
+		!strings.HasPrefix(updates[1], "delete _acme-challenge.www.example.test. ") {
+		t.Fatalf("Expected the record to be added and deleted, got %v", updates)
+	}
+	value := strings.Fields(updates[0])[2]
+	if !strings.Contains(out, "Published TXT _acme-challenge.www.example.test.") || !strings.Contains(out, value) {
+		t.Fatalf("Unexpected output:\n%s", out)
+	}
+	if records := server.getRecords("_acme-challenge.www.example.test."); len(records) != 0 {
+		t.Fatalf("Expected the record to be removed, got %v", records)
+	}
+
+	// Un secreto TSIG incorrecto se rechaza
+	out, err = runCommand(t, append(args, "--tsig-secret", "pass:"+testTSIGBadSecret)...)
+	if err == nil || !strings.Contains(out, "error publishing") {
+		t.Fatalf("Expected a bad TSIG secret to fail:\n%s", out)
+	}
+
+	// Las zonas fuera del servidor no se pueden descubrir
+	out, err = runCommand(t, "acme", "dns-check", "--domain", "other.invalid", "--dns-provider", "rfc2136",
+		"--dns-nameserver", server.address, "--tsig-key", testTSIGKey, "--tsig-secret", testTSIGSecret)
+	if err == nil || !strings.Contains(out, "could not find the zone") {
+		t.Fatalf("Expected the zone lookup to fail:\n%s", out)
+	}
+}
+
+// Test para acme dns-check con un script propio
+func TestDNS01Exec(t *testing.T) {
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "hook.log")
+	script := filepath.Join(dir, "hook.sh")
+	os.WriteFile(script, []byte("#!/bin/sh\necho \"$1 $2 $SSL_TOOL_DNS_DOMAIN $3\" >> "+logFile+"\n"), 0755)
+
+	out, err := runCommand(t, "acme", "dns-check", "--domain", "*.example.test", "--dns-provider", "exec", "--dns-exec", script)
+	if err != nil {
+		t.Fatalf("Error running acme dns-check: %v\n%s", err, out)
+	}
+	data, _ := os.ReadFile(logFile)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "present _acme-challenge.example.test. example.test ssl-tool-check-") ||
+		!strings.HasPrefix(lines[1], "cleanup _acme-challenge.example.test. example.test ssl-tool-check-") {
+		t.Fatalf("Unexpected hook calls:\n%s", data)
+	}
+
+	// Un script que falla detiene la comprobación con su salida
+	os.WriteFile(script, []byte("#!/bin/sh\necho 'zone is locked' >&2\nexit 1\n"), 0755)
+	out, err = runCommand(t, "acme", "dns-check", "--domain", "example.test", "--dns-provider", "exec", "--dns-exec", script)
+	if err == nil || !strings.Contains(out, "zone is locked") {
+		t.Fatalf("Expected the hook error in the output:\n%s", out)
+	}
+}
+
+// Test para acme issue con DNS-01 contra Pebble. Pebble debe resolver con el servidor DNS del
+// test, arrancado en PEBBLE_DNS_SERVER (p. ej. pebble -dnsserver 127.0.0.1:8053).
+func TestACMEIssueDNS01Pebble(t *testing.T) {
+	dir := t.TempDir()
+	args := pebbleArgs(t, dir)
+	address := os.Getenv("PEBBLE_DNS_SERVER")
+	if address == "" {
+		t.Skip("set PEBBLE_DNS_SERVER to the -dnsserver address given to Pebble to run the DNS-01 test")
+	}
+	server := newDNSServer(t, address)
+
+	out, err := runCommand(t, append([]string{"acme", "issue", "--domain", "example.test,*.example.test", "--output-dir", dir,
+		"--dns-provider", "rfc2136", "--dns-nameserver", server.address, "--tsig-key", testTSIGKey, "--tsig-secret", testTSIGSecret}, args...)...)
+	if err != nil {
+		t.Fatalf("Error running acme issue: %v\n%s", err, out)
+	}
+	for _, expected := range []string{"Solving dns-01 challenge for *.example.test", "Certificate issued for example.test, *.example.test"} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("Expected %q in the output:\n%s", expected, out)
+		}
+	}
+	if records := server.getRecords("_acme-challenge.example.test."); len(records) != 0 {
+		t.Fatalf("Expected the challenge records to be removed, got %v", records)
+	}
+}
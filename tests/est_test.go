@@ -0,0 +1,209 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/smallstep/pkcs7"
+)
+
+const (
+	testESTUser     = "est-user"
+	testESTPassword = "est-secret"
+)
+
+// newESTServer arranca un servidor EST mínimo que emite con ca. simpleenroll acepta HTTP basic o
+// un certificado de cliente de ca; simplereenroll exige un certificado de cliente con el mismo
+// sujeto que el CSR. La etiqueta "pending" responde 202 como una CA con aprobación manual.
+func newESTServer(t *testing.T, ca *testCert) (*httptest.Server, string) {
+	t.Helper()
+	pkcs7Response := func(w http.ResponseWriter, certs ...*x509.Certificate) {
+		var raw []byte
+		for _, cert := range certs {
+			raw = append(raw, cert.Raw...)
+		}
+		der, err := pkcs7.DegenerateCertificate(raw)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/pkcs7-mime; smime-type=certs-only")
+		w.Header().Set("Content-Transfer-Encoding", "base64")
+		w.Write([]byte(base64.StdEncoding.EncodeToString(der)))
+	}
+
+	handler := http.NewServeMux()
+	handler.HandleFunc("GET /.well-known/est/cacerts", func(w http.ResponseWriter, r *http.Request) {
+		pkcs7Response(w, ca.cert)
+	})
+	handler.HandleFunc("POST /.well-known/est/pending/simpleenroll", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusAccepted)
+	})
+	handler.HandleFunc("POST /.well-known/est/{operation}", func(w http.ResponseWriter, r *http.Request) {
+		user, password, basic := r.BasicAuth()
+		clientCerts := r.TLS.PeerCertificates
+		body, _ := io.ReadAll(r.Body)
+		der, err := base64.StdEncoding.DecodeString(string(body))
+		if err != nil || r.Header.Get("Content-Type") != "application/pkcs10" {
+			http.Error(w, "expected a base64 application/pkcs10 body", http.StatusBadRequest)
+			return
+		}
+		csr, err := x509.ParseCertificateRequest(der)
+		if err != nil || csr.CheckSignature() != nil {
+			http.Error(w, "invalid CSR", http.StatusBadRequest)
+			return
+		}
+
+		switch r.PathValue("operation") {
+		case "simpleenroll":
+			if len(clientCerts) == 0 && !(basic && user == testESTUser && password == testESTPassword) {
+				w.Header().Set("WWW-Authenticate", `Basic realm="est"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		case "simplereenroll":
+			if len(clientCerts) == 0 || clientCerts[0].Subject.String() != csr.Subject.String() {
+				http.Error(w, "re-enrollment requires the current certificate", http.StatusForbidden)
+				return
+			}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+
+		serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+		template := &x509.Certificate{
+			SerialNumber: serial,
+			Subject:      csr.Subject,
+			DNSNames:     csr.DNSNames,
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(30 * 24 * time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		}
+		issued, err := x509.CreateCertificate(rand.Reader, template, ca.cert, csr.PublicKey, ca.key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		leaf, _ := x509.ParseCertificate(issued)
+		// La CA va primero a propósito: el cliente debe encontrar el certificado emitido
+		pkcs7Response(w, ca.cert, leaf)
+	})
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	server := httptest.NewUnstartedServer(handler)
+	server.TLS = &tls.Config{ClientAuth: tls.VerifyClientCertIfGiven, ClientCAs: pool}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	rootsFile := filepath.Join(t.TempDir(), "est-server.pem")
+	os.WriteFile(rootsFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0644)
+	return server, rootsFile
+}
+
+// Test para est cacerts, enroll y reenroll contra el servidor EST de prueba
+func TestEST(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, "EST CA", nil)
+	server, roots := newESTServer(t, ca)
+	est := func(args ...string) (string, error) {
+		return runCommand(t, append(append([]string{"est"}, args...), "--server", server.URL, "--ca-roots", roots)...)
+	}
+
+	// cacerts
+	caFile := filepath.Join(dir, "ca.pem")
+	out, err := est("cacerts", "--out", caFile)
+	if err != nil || !strings.Contains(out, "Saved 1 CA certificate(s)") {
+		t.Fatalf("Error running est cacerts: %v\n%s", err, out)
+	}
+	data, _ := os.ReadFile(caFile)
+	if block, _ := pem.Decode(data); block == nil || string(block.Bytes) != string(ca.cert.Raw) {
+		t.Fatalf("Expected the EST CA in %s:\n%s", caFile, data)
+	}
+
+	// enroll con la generación de generate-csr y autenticación HTTP basic
+	t.Cleanup(func() { os.RemoveAll("est_example_com") })
+	out, err = est("enroll", "--domain", "est.example.com", "--country", "US", "--locality", "Boston", "--organization", "TestOrg",
+		"--username", testESTUser, "--password", "env:EST_TEST_PASSWORD")
+	if err == nil || !strings.Contains(out, "environment variable EST_TEST_PASSWORD is not set") {
+		t.Fatalf("Expected the missing password variable to be reported:\n%s", out)
+	}
+	os.Setenv("EST_TEST_PASSWORD", testESTPassword)
+	defer os.Unsetenv("EST_TEST_PASSWORD")
+	out, err = est("enroll", "--domain", "est.example.com", "--country", "US", "--locality", "Boston", "--organization", "TestOrg",
+		"--username", testESTUser, "--password", "env:EST_TEST_PASSWORD")
+	if err != nil || !strings.Contains(out, "Certificate enrolled for CN=est.example.com") {
+		t.Fatalf("Error running est enroll: %v\n%s", err, out)
+	}
+	certFile, keyFile := filepath.Join("est_example_com", "est_example_com.crt"), filepath.Join("est_example_com", "est_example_com.key")
+	chain, err := os.ReadFile(certFile)
+	if err != nil || strings.Count(string(chain), "BEGIN CERTIFICATE") != 2 {
+		t.Fatalf("Expected the issued certificate and the CA in %s:\n%s", certFile, chain)
+	}
+	if out, err := runCommand(t, "verify-hashes", "--key", keyFile, "--csr", filepath.Join("est_example_com", "est_example_com.csr"), "--cert", certFile); err != nil {
+		t.Fatalf("The issued certificate does not match the key and CSR: %v\n%s", err, out)
+	}
+	if out, err := runCommand(t, "verify-chain", "--cert", certFile, "--roots", caFile); err != nil {
+		t.Fatalf("The issued certificate does not chain to the EST CA: %v\n%s", err, out)
+	}
+
+	// Sin credenciales el servidor rechaza la petición
+	out, err = est("enroll", "--csr", filepath.Join("est_example_com", "est_example_com.csr"), "--out", filepath.Join(dir, "denied.crt"))
+	if err == nil || !strings.Contains(out, "requires authentication") {
+		t.Fatalf("Expected enrollment without credentials to fail:\n%s", out)
+	}
+
+	// enroll de un CSR existente con autenticación por certificado de cliente
+	clientCert := newTestCert(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "client.example.com"},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca)
+	clientCertFile, clientKeyFile := filepath.Join(dir, "client.pem"), filepath.Join(dir, "client.key")
+	writePEM(t, clientCertFile, clientCert)
+	writeKey(t, clientKeyFile, clientCert)
+	out, err = est("enroll", "--csr", filepath.Join("est_example_com", "est_example_com.csr"), "--out", filepath.Join(dir, "tls.crt"),
+		"--client-cert", clientCertFile, "--client-key", clientKeyFile)
+	if err != nil || !strings.Contains(out, filepath.Join(dir, "tls.crt")) {
+		t.Fatalf("Error running est enroll with a client certificate: %v\n%s", err, out)
+	}
+
+	// reenroll se autentica con el certificado actual y conserva la clave
+	renewed := filepath.Join(dir, "renewed.crt")
+	out, err = est("reenroll", "--cert", certFile, "--key", keyFile, "--out", renewed)
+	if err != nil || !strings.Contains(out, "Certificate enrolled for CN=est.example.com") {
+		t.Fatalf("Error running est reenroll: %v\n%s", err, out)
+	}
+	certPin, _ := runCommand(t, "fingerprint", "--spki", "--file", renewed)
+	keyPin, _ := runCommand(t, "fingerprint", "--spki", "--file", keyFile)
+	if certPin == "" || certPin != keyPin {
+		t.Fatalf("The renewed certificate does not keep the key:\n%s\n%s", certPin, keyPin)
+	}
+	out, err = est("reenroll", "--cert", certFile, "--key", keyFile, "--out", renewed, "--client-cert", clientCertFile, "--client-key", clientKeyFile)
+	if err == nil || !strings.Contains(out, "re-enrollment requires the current certificate") {
+		t.Fatalf("Expected re-enrollment with another certificate to fail:\n%s", out)
+	}
+
+	// Una CA con aprobación manual responde 202
+	out, err = est("enroll", "--label", "pending", "--csr", filepath.Join("est_example_com", "est_example_com.csr"), "--out", filepath.Join(dir, "pending.crt"),
+		"--username", testESTUser, "--password", testESTPassword)
+	if err == nil || !strings.Contains(out, "pending approval; retry after 60 seconds") {
+		t.Fatalf("Expected the pending enrollment to be reported:\n%s", out)
+	}
+}
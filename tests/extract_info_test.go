@@ -0,0 +1,86 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Test para extract-info con --config: combina los campos extraídos sin perder el resto
+func TestExtractInfoMerge(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "custom.yaml")
+	keyFile := filepath.Join(dir, "ec.key")
+	csrFile := filepath.Join(dir, "ec.csr")
+
+	if err := os.WriteFile(configFile, []byte("# Valores del equipo\ndefault_email: keep@example.com\ndefault_country: FR # se sobrescribe\nowner: ops-team\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cmd := exec.Command("openssl", "req", "-new", "-newkey", "ec", "-pkeyopt", "ec_paramgen_curve:P-384", "-nodes",
+		"-keyout", keyFile, "-out", csrFile,
+		"-subj", "/C=ES/L=Madrid/O=Acme/OU=Ops/CN=www.acme.es",
+		"-addext", "subjectAltName=DNS:www.acme.es,DNS:acme.es")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Failed to generate CSR: %v\n%s", err, output)
+	}
+
+	out, err := runCommand(t, "--config", configFile, "extract-info", "--file", csrFile)
+	if err != nil {
+		t.Fatalf("Error running extract-info: %v\n%s", err, out)
+	}
+
+	cfg := readConfig(t, configFile)
+	if cfg["default_email"] != "keep@example.com" {
+		t.Fatalf("default_email was not preserved: %v", cfg)
+	}
+	// Los comentarios y las claves que Config no conoce se conservan
+	if data, _ := os.ReadFile(configFile); !strings.Contains(string(data), "# Valores del equipo") || !strings.Contains(string(data), "# se sobrescribe") ||
+		cfg["owner"] != "ops-team" {
+		t.Fatalf("Comments or unknown keys were lost:\n%s", data)
+	}
+	if cfg["default_country"] != "ES" || cfg["default_organizational_unit"] != "Ops" {
+		t.Fatalf("Extracted subject fields were not merged: %v", cfg)
+	}
+	if cfg["default_key_algorithm"] != "ECDSA" || cfg["default_key_size"] != 384 {
+		t.Fatalf("Key algorithm and size were not detected: %v", cfg)
+	}
+	if sans, ok := cfg["default_sans"].([]interface{}); !ok || len(sans) != 2 {
+		t.Fatalf("SANs were not extracted: %v", cfg)
+	}
+
+	_, _ = runCommand(t, "generate-csr", "--domain", "example.com", "--country", "US", "--locality", "New York", "--organization", "TestOrg")
+
+	// Con --profile los valores se guardan en el perfil y la raíz no cambia
+	out, err = runCommand(t, "--config", configFile, "--profile", "prod", "extract-info", "--file", "example_com/example_com.csr")
+	if err != nil {
+		t.Fatalf("Error running extract-info --profile: %v\n%s", err, out)
+	}
+	cfg = readConfig(t, configFile)
+	if cfg["default_domain"] != "www.acme.es" {
+		t.Fatalf("Root values changed when saving a profile: %v", cfg)
+	}
+	profiles, _ := cfg["profiles"].(map[string]interface{})
+	prod, _ := profiles["prod"].(map[string]interface{})
+	if prod["default_domain"] != "example.com" || prod["default_key_algorithm"] != "RSA" {
+		t.Fatalf("Profile was not saved: %v", cfg)
+	}
+
+	t.Log("extract-info merge passed successfully")
+}
+
+func readConfig(t *testing.T, path string) map[string]interface{} {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	cfg := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+	return cfg
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// Test para fingerprint con distintos algoritmos y formatos
+func TestFingerprintFormats(t *testing.T) {
+	_, _ = runCommand(t, "generate-csr", "--domain", "example.com", "--country", "US", "--locality", "New York", "--organization", "TestOrg")
+
+	keyFile := "example_com/example_com.key"
+	csrFile := "example_com/example_com.csr"
+	certFile := "example_com/example_com.crt"
+
+	cmd := exec.Command("openssl", "x509", "-req", "-days", "365", "-in", csrFile, "-signkey", keyFile, "-out", certFile)
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to generate self-signed certificate: %v", err)
+	}
+
+	// El formato "colon" en SHA1 debe coincidir con el de OpenSSL
+	output, err := exec.Command("openssl", "x509", "-in", certFile, "-noout", "-fingerprint", "-sha1").Output()
+	if err != nil {
+		t.Fatalf("Failed to compute OpenSSL fingerprint: %v", err)
+	}
+	want := strings.TrimSpace(string(output)[strings.Index(string(output), "=")+1:])
+
+	out, err := runCommand(t, "fingerprint", "--cert", certFile, "--algo", "sha1", "--format", "colon")
+	if err != nil {
+		t.Fatalf("Error running fingerprint: %v\n%s", err, out)
+	}
+	if !strings.Contains(out, want) {
+		t.Fatalf("Expected fingerprint %s, got:\n%s", want, out)
+	}
+
+	// El pin SPKI del certificado, del CSR y de la clave debe ser el mismo
+	var pins []string
+	for _, file := range []string{certFile, csrFile, keyFile} {
+		out, err := runCommand(t, "fingerprint", "--file", file, "--spki", "--format", "base64")
+		if err != nil {
+			t.Fatalf("Error running fingerprint --spki on %s: %v\n%s", file, err, out)
+		}
+		pins = append(pins, strings.TrimSpace(out[strings.Index(out, ":")+1:]))
+	}
+	if pins[0] != pins[1] || pins[1] != pins[2] {
+		t.Fatalf("SPKI pins differ: %v", pins)
+	}
+
+	// Sin --spki un CSR no tiene huella de certificado
+	if _, err := runCommand(t, "fingerprint", "--file", csrFile); err == nil {
+		t.Fatalf("Expected fingerprint of a CSR without --spki to fail")
+	}
+
+	t.Log("fingerprint passed successfully")
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Test para inventory add|scan|list|show|remove y el registro automático desde otros comandos
+func TestInventory(t *testing.T) {
+	dir := t.TempDir()
+	db := filepath.Join(dir, "inventory.db")
+	root := newTestCA(t, "Inventory Root", nil)
+	leaf := newTestLeaf(t, root, "app.example.com", "api.example.com")
+	other := newTestLeaf(t, root, "other.example.com")
+
+	certsDir := filepath.Join(dir, "certs")
+	os.Mkdir(certsDir, 0755)
+	leafFile := filepath.Join(certsDir, "app.pem")
+	writePEM(t, leafFile, leaf)
+	writePEM(t, filepath.Join(certsDir, "other.pem"), other)
+
+	// Sin base de datos, los demás comandos no la crean
+	if out, err := runCommand(t, "check-expiration", "--cert", leafFile, "--inventory", db); err != nil {
+		t.Fatalf("Error running check-expiration: %v\n%s", err, out)
+	}
+	if _, err := os.Stat(db); err == nil {
+		t.Fatalf("check-expiration should not create the inventory")
+	}
+
+	out, err := runCommand(t, "inventory", "add", leafFile, "--owner", "team-a", "--inventory", db)
+	if err != nil || !strings.Contains(out, "Registered ") || !strings.Contains(out, "app.example.com") {
+		t.Fatalf("Error running inventory add: %v\n%s", err, out)
+	}
+
+	out, err = runCommand(t, "inventory", "scan", certsDir, "--inventory", db)
+	if err != nil || !strings.Contains(out, "1 new, 1 already known") {
+		t.Fatalf("Unexpected inventory scan result: %v\n%s", err, out)
+	}
+
+	// Registro automático desde check-expiration y desde un endpoint remoto
+	server := newTestTLSServer(t, leaf, root)
+	if out, err := runCommand(t, "inspect", "--remote", server.Listener.Addr().String(), "--inventory", db); err != nil {
+		t.Fatalf("Error running inspect --remote: %v\n%s", err, out)
+	}
+
+	out, err = runCommand(t, "inventory", "list", "--inventory", db)
+	if err != nil {
+		t.Fatalf("Error running inventory list: %v\n%s", err, out)
+	}
+	for _, expected := range []string{"FINGERPRINT", "app.example.com", "other.example.com", "Inventory Root", "team-a"} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("Expected %q in inventory list:\n%s", expected, out)
+		}
+	}
+
+	fingerprint, _ := runCommand(t, "fingerprint", "--cert", leafFile)
+	fingerprint = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(strings.TrimPrefix(fingerprint, "SHA256 Fingerprint:")), ":", ""))
+	out, err = runCommand(t, "inventory", "show", fingerprint[:12], "--inventory", db)
+	if err != nil {
+		t.Fatalf("Error running inventory show: %v\n%s", err, out)
+	}
+	for _, expected := range []string{
+		"Fingerprint (SHA256): " + fingerprint,
+		"SANs: app.example.com, api.example.com",
+		"Issuer: CN=Inventory Root",
+		"Owner: team-a",
+		"- " + leafFile,
+		"- tls://" + server.Listener.Addr().String(),
+	} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("Expected %q in inventory show:\n%s", expected, out)
+		}
+	}
+
+	// generate-csr registra el CSR generado
+	t.Cleanup(func() { os.RemoveAll("inventory_example_com") })
+	if out, err := runCommand(t, "generate-csr", "--domain", "inventory.example.com", "--country", "US", "--locality", "Boston", "--organization", "TestOrg", "--inventory", db); err != nil {
+		t.Fatalf("Error running generate-csr: %v\n%s", err, out)
+	}
+	out, _ = runCommand(t, "inventory", "list", "--inventory", db)
+	if !strings.Contains(out, "csr") || !strings.Contains(out, "inventory.example.com") {
+		t.Fatalf("Expected the generated CSR in the inventory:\n%s", out)
+	}
+
+	out, err = runCommand(t, "inventory", "remove", fingerprint, "--inventory", db)
+	if err != nil || !strings.Contains(out, "Removed") {
+		t.Fatalf("Error running inventory remove: %v\n%s", err, out)
+	}
+	if out, err := runCommand(t, "inventory", "show", fingerprint, "--inventory", db); err == nil || !strings.Contains(out, "no inventory entry matches") {
+		t.Fatalf("Expected the removed entry to be gone:\n%s", out)
+	}
+}
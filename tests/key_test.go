@@ -0,0 +1,129 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Test para key convert, key info y key pub con una clave RSA generada por generate-csr
+func TestKeyConvertRSA(t *testing.T) {
+	_, err := runCommand(t, "generate-csr", "--domain", "example.com", "--country", "US", "--locality", "New York", "--organization", "TestOrg")
+	if err != nil {
+		t.Fatalf("Error running generate-csr: %v", err)
+	}
+
+	dir := t.TempDir()
+	pkcs8File := filepath.Join(dir, "key.p8")
+	pkcs1File := filepath.Join(dir, "key.p1")
+
+	// PKCS#1 -> PKCS#8 cifrado
+	out, err := runCommand(t, "key", "convert", "--in", "example_com/example_com.key", "--out", pkcs8File, "--to", "pkcs8", "--passout", "pass:secret")
+	if err != nil {
+		t.Fatalf("Error running key convert: %v\n%s", err, out)
+	}
+	data, _ := os.ReadFile(pkcs8File)
+	if !strings.Contains(string(data), "BEGIN ENCRYPTED PRIVATE KEY") {
+		t.Fatalf("Expected an encrypted PKCS#8 key, got:\n%s", data)
+	}
+
+	// Sin passphrase debe fallar
+	if _, err := runCommand(t, "key", "info", "--in", pkcs8File); err == nil {
+		t.Fatalf("Expected key info to fail without a passphrase")
+	}
+
+	// PKCS#8 cifrado -> PKCS#1
+	out, err = runCommand(t, "key", "convert", "--in", pkcs8File, "--passin", "pass:secret", "--out", pkcs1File, "--to", "pkcs1")
+	if err != nil {
+		t.Fatalf("Error decrypting key: %v\n%s", err, out)
+	}
+
+	// La huella SPKI debe coincidir con la de la clave original
+	original, err := runCommand(t, "key", "info", "--in", "example_com/example_com.key")
+	if err != nil {
+		t.Fatalf("Error running key info: %v\n%s", err, original)
+	}
+	converted, err := runCommand(t, "key", "info", "--in", pkcs1File)
+	if err != nil {
+		t.Fatalf("Error running key info: %v\n%s", err, converted)
+	}
+	if original != converted {
+		t.Fatalf("Key info differs after conversion:\n%s\n%s", original, converted)
+	}
+	if !strings.Contains(original, "Algorithm: RSA") || !strings.Contains(original, "Size: 2048 bits") {
+		t.Fatalf("Unexpected key info:\n%s", original)
+	}
+
+	// OpenSSL también debe poder leer la clave cifrada
+	cmd := exec.Command("openssl", "pkey", "-in", pkcs8File, "-passin", "pass:secret", "-noout")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("OpenSSL could not read the encrypted key: %v\n%s", err, output)
+	}
+
+	t.Log("key convert (RSA) passed successfully")
+}
+
+// Test para la conversión de claves EC entre SEC1 y PKCS#8
+func TestKeyConvertEC(t *testing.T) {
+	dir := t.TempDir()
+	sec1File := filepath.Join(dir, "ec.key")
+	pkcs8File := filepath.Join(dir, "ec.p8")
+	backFile := filepath.Join(dir, "ec.sec1")
+
+	// OpenSSL antepone un bloque "EC PARAMETERS" que debe ignorarse
+	cmd := exec.Command("openssl", "ecparam", "-name", "prime256v1", "-genkey", "-out", sec1File)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Failed to generate EC key: %v\n%s", err, output)
+	}
+
+	if out, err := runCommand(t, "key", "convert", "--in", sec1File, "--out", pkcs8File, "--to", "pkcs8"); err != nil {
+		t.Fatalf("Error converting to PKCS#8: %v\n%s", err, out)
+	}
+	if out, err := runCommand(t, "key", "convert", "--in", pkcs8File, "--out", backFile, "--to", "sec1"); err != nil {
+		t.Fatalf("Error converting to SEC1: %v\n%s", err, out)
+	}
+	if out, err := runCommand(t, "key", "convert", "--in", pkcs8File, "--out", filepath.Join(dir, "bad"), "--to", "pkcs1"); err == nil {
+		t.Fatalf("Expected PKCS#1 conversion of an EC key to fail:\n%s", out)
+	}
+
+	out, err := runCommand(t, "key", "info", "--in", backFile)
+	if err != nil {
+		t.Fatalf("Error running key info: %v\n%s", err, out)
+	}
+	if !strings.Contains(out, "Algorithm: ECDSA") || !strings.Contains(out, "Curve: P-256") {
+		t.Fatalf("Unexpected key info:\n%s", out)
+	}
+
+	t.Log("key convert (EC) passed successfully")
+}
+
+// Test para key pub en sus tres formatos
+func TestKeyPub(t *testing.T) {
+	_, _ = runCommand(t, "generate-csr", "--domain", "example.com", "--country", "US", "--locality", "New York", "--organization", "TestOrg")
+
+	formats := map[string]string{
+		"pem":     "BEGIN PUBLIC KEY",
+		"openssh": "ssh-rsa ",
+		"jwk":     `"kty": "RSA"`,
+	}
+	for format, want := range formats {
+		out, err := runCommand(t, "key", "pub", "--in", "example_com/example_com.key", "--format", format)
+		if err != nil {
+			t.Fatalf("Error running key pub --format %s: %v\n%s", format, err, out)
+		}
+		if !strings.Contains(out, want) {
+			t.Fatalf("key pub --format %s: expected %q in output:\n%s", format, want, out)
+		}
+	}
+
+	// La clave pública del CSR debe coincidir con la de la clave privada
+	fromKey, _ := runCommand(t, "key", "pub", "--in", "example_com/example_com.key")
+	fromCSR, _ := runCommand(t, "key", "pub", "--in", "example_com/example_com.csr")
+	if fromKey != fromCSR {
+		t.Fatalf("Public key from CSR does not match the private key")
+	}
+
+	t.Log("key pub passed successfully")
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test para lint con certificados y CSRs correctos e incorrectos
+func TestLint(t *testing.T) {
+	dir := t.TempDir()
+	root := newTestCA(t, "Lint Root", nil)
+	leaf := newTestLeaf(t, root, "www.example.com", "example.com")
+	goodFile := filepath.Join(dir, "good.pem")
+	writePEM(t, goodFile, leaf, root)
+
+	// Una cadena correcta solo tiene el aviso informativo del SKI de la hoja
+	out, err := runCommand(t, "lint", goodFile)
+	if err != nil || !strings.Contains(out, "0 error(s), 0 warning(s), 1 notice(s)") || !strings.Contains(out, "missing_ski") ||
+		!strings.Contains(out, "[1] certificate CN=Lint Root\n  no findings") {
+		t.Fatalf("Unexpected lint output for a valid chain: %v\n%s", err, out)
+	}
+	out, err = runCommand(t, "lint", goodFile, "--fail-on", "notice")
+	if err == nil || !strings.Contains(out, "1 of 2 object(s) have findings of severity notice or higher") {
+		t.Fatalf("Expected --fail-on notice to fail:\n%s", out)
+	}
+
+	// Hoja de dos años, con un guion bajo, país inválido, CN fuera de los SANs y keyCertSign sin ser
+	// CA; firmada por un certificado sin SKI, así que no lleva AKI
+	issuer := newTestLeaf(t, nil, "issuer.example.com")
+	bad := newTestCert(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "bad_host.example.com", Country: []string{"ZZ"}},
+		DNSNames:              []string{"www.example.com"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(2 * 365 * 24 * time.Hour),
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, issuer)
+	badFile := filepath.Join(dir, "bad.pem")
+	writePEM(t, badFile, bad)
+	out, err = runCommand(t, "lint", badFile)
+	for _, rule := range []string{"validity_too_long", "underscore_in_dns_name", "invalid_country", "cn_not_in_san", "leaf_cert_sign", "missing_aki"} {
+		if !strings.Contains(out, rule) {
+			t.Errorf("Expected lint to report %s:\n%s", rule, out)
+		}
+	}
+	if err == nil || !strings.Contains(out, "ERROR") || !strings.Contains(out, "1 of 1 object(s) have findings of severity error or higher") {
+		t.Fatalf("Expected lint to fail for the invalid certificate: %v\n%s", err, out)
+	}
+
+	// CSR con RSA de 1024 bits y SHA-1, en DER
+	weakKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	csrDER, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:            pkix.Name{CommonName: "legacy.example.com", Country: []string{"US"}},
+		SignatureAlgorithm: x509.SHA1WithRSA,
+	}, weakKey)
+	if err != nil {
+		t.Fatalf("Failed to create CSR: %v", err)
+	}
+	csrFile := filepath.Join(dir, "weak.der")
+	os.WriteFile(csrFile, csrDER, 0644)
+	out, err = runCommand(t, "lint", csrFile, "--format", "json")
+	if err == nil {
+		t.Fatalf("Expected lint to fail for the weak CSR:\n%s", out)
+	}
+	var report struct {
+		Results []struct {
+			Kind     string
+			Findings []struct{ Rule, Severity string }
+		}
+		Counts map[string]int
+	}
+	if err := json.Unmarshal([]byte(out[:strings.LastIndex(out, "}")+1]), &report); err != nil {
+		t.Fatalf("Invalid JSON output: %v\n%s", err, out)
+	}
+	severities := map[string]string{}
+	for _, f := range report.Results[0].Findings {
+		severities[f.Rule] = f.Severity
+	}
+	if report.Results[0].Kind != "csr" || severities["weak_signature"] != "error" || severities["weak_key"] != "error" ||
+		severities["missing_san"] != "warn" || report.Counts["error"] != 2 {
+		t.Fatalf("Unexpected lint findings for the weak CSR:\n%s", out)
+	}
+
+	// El CSR de generate-csr no tiene SANs: solo un aviso
+	t.Cleanup(func() { os.RemoveAll("lint_example_com") })
+	if out, err := runCommand(t, "generate-csr", "--domain", "lint.example.com", "--country", "ES", "--locality", "Madrid", "--organization", "TestOrg"); err != nil {
+		t.Fatalf("Error running generate-csr: %v\n%s", err, out)
+	}
+	generated := filepath.Join("lint_example_com", "lint_example_com.csr")
+	out, err = runCommand(t, "lint", generated)
+	if err != nil || !strings.Contains(out, "WARN") || !strings.Contains(out, "missing_san") {
+		t.Fatalf("Unexpected lint output for the generated CSR: %v\n%s", err, out)
+	}
+	out, err = runCommand(t, "lint", generated, "--fail-on", "warn")
+	if err == nil || !strings.Contains(out, "severity warn or higher") {
+		t.Fatalf("Expected --fail-on warn to fail:\n%s", out)
+	}
+
+	// generate-csr ya no acepta códigos de país inexistentes
+	out, err = runCommand(t, "generate-csr", "--domain", "lint.example.com", "--country", "ZZ", "--locality", "Madrid", "--organization", "TestOrg")
+	if err == nil || !strings.Contains(out, "country ZZ is not an ISO 3166-1 alpha-2 code") {
+		t.Fatalf("Expected generate-csr to reject country ZZ:\n%s", out)
+	}
+
+	// La cadena presentada por un servidor
+	intermediate := newTestCA(t, "Lint Intermediate", root)
+	server := newTestTLSServer(t, newTestLeaf(t, intermediate, "remote.example.com"), intermediate)
+	out, err = runCommand(t, "lint", "--remote", server.Listener.Addr().String())
+	if err != nil || !strings.Contains(out, "[1] certificate CN=Lint Intermediate") || !strings.Contains(out, "0 error(s)") {
+		t.Fatalf("Unexpected lint --remote output: %v\n%s", err, out)
+	}
+
+	out, err = runCommand(t, "lint", goodFile, "--fail-on", "fatal")
+	if err == nil || !strings.Contains(out, "unsupported severity: fatal") {
+		t.Fatalf("Expected an unknown severity to fail:\n%s", out)
+	}
+	keyFile := filepath.Join(dir, "key.pem")
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte{0}}), 0600)
+	if out, err := runCommand(t, "lint", keyFile); err == nil || !strings.Contains(out, "no certificates or CSRs found") {
+		t.Fatalf("Expected a file without certificates or CSRs to fail:\n%s", out)
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"os"
 	"os/exec"
+	"strings"
 	"testing"
 )
 
@@ -48,6 +49,20 @@ func TestGenerateCSR(t *testing.T) {
 		t.Fatalf("CSR file was not created")
 	}
 
+	// --key-type acepta los mismos tipos que acme account
+	t.Cleanup(func() { os.RemoveAll("ec_example_com") })
+	if out, err := runCommand(t, "generate-csr", "--domain", "ec.example.com", "--country", "US", "--locality", "New York", "--organization", "TestOrg", "--key-type", "ec384"); err != nil {
+		t.Fatalf("Error running generate-csr --key-type ec384: %v\n%s", err, out)
+	}
+	key, _ := os.ReadFile("ec_example_com/ec_example_com.key")
+	if !strings.Contains(string(key), "EC PRIVATE KEY") {
+		t.Fatalf("Expected an EC key:\n%s", key)
+	}
+	out, err := runCommand(t, "generate-csr", "--domain", "bad.example.com", "--country", "US", "--locality", "New York", "--organization", "TestOrg", "--key-type", "dsa")
+	if err == nil || !strings.Contains(out, "unsupported key type") {
+		t.Fatalf("Expected an unsupported --key-type to fail:\n%s", out)
+	}
+
 	t.Log("generate-csr passed successfully")
 }
 
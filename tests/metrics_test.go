@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer permite leer la salida de un proceso mientras sigue escribiendo.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// startDaemon arranca ssl-tool en segundo plano y lo detiene al terminar el test.
+func startDaemon(t *testing.T, args ...string) *syncBuffer {
+	output := &syncBuffer{}
+	cmd := exec.Command("./ssl-tool", args...)
+	cmd.Stdout = output
+	cmd.Stderr = output
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start ssl-tool %v: %v", args, err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Signal(os.Interrupt)
+		done := make(chan struct{})
+		go func() { cmd.Wait(); close(done) }()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			cmd.Process.Kill()
+		}
+	})
+	return output
+}
+
+// freeAddress devuelve una dirección local con un puerto libre.
+func freeAddress(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	return listener.Addr().String()
+}
+
+// waitForHTTP repite la petición hasta que el servidor responde o se agota el plazo.
+func waitForHTTP(t *testing.T, url string, output *syncBuffer) string {
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		resp, err := http.Get(url)
+		if err == nil {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			return string(body)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatalf("%s did not respond:\n%s", url, output)
+	return ""
+}
+
+// Test para serve-metrics con objetivos de archivo, directorio y endpoint remoto
+func TestServeMetrics(t *testing.T) {
+	dir := t.TempDir()
+	root := newTestCA(t, "Metrics Root", nil)
+	intermediate := newTestCA(t, "Metrics Intermediate", root)
+	leaf := newTestLeaf(t, intermediate, "www.example.com")
+	server := newTestTLSServer(t, leaf, intermediate)
+
+	certsDir := filepath.Join(dir, "certs")
+	os.Mkdir(certsDir, 0755)
+	writePEM(t, filepath.Join(certsDir, "fullchain.pem"), leaf, intermediate)
+	writePEM(t, filepath.Join(dir, "root.pem"), root)
+	writePEM(t, filepath.Join(dir, "lonely.pem"), leaf)
+
+	targets := filepath.Join(dir, "targets.yaml")
+	os.WriteFile(targets, []byte(`interval: 1h
+roots: `+filepath.Join(dir, "root.pem")+`
+targets:
+  - dir: `+certsDir+`
+  - file: `+filepath.Join(dir, "lonely.pem")+`
+  - remote: `+server.Listener.Addr().String()+`
+    sni: www.example.com
+  - remote: 127.0.0.1:1
+    timeout: 1s
+`), 0644)
+
+	address := freeAddress(t)
+	output := startDaemon(t, "serve-metrics", "--listen", address, "--targets", targets)
+	body := waitForHTTP(t, "http://"+address+"/metrics", output)
+
+	fullchain := filepath.Join(certsDir, "fullchain.pem")
+	serial := leaf.cert.SerialNumber.Text(16)
+	for _, expected := range []string{
+		"# TYPE ssl_cert_not_after_seconds gauge",
+		"# TYPE ssl_probe_errors_total counter",
+		`ssl_cert_not_after_seconds{path="` + fullchain + `",endpoint="",subject="CN=www.example.com",issuer="CN=Metrics Intermediate",serial="` + serial + `"} ` + strconv.FormatInt(leaf.cert.NotAfter.Unix(), 10),
+		`ssl_cert_not_before_seconds{path="` + fullchain + `",endpoint="",subject="CN=Metrics Intermediate",issuer="CN=Metrics Root"`,
+		`ssl_verify_ok{path="` + filepath.Join(dir, "lonely.pem") + `",endpoint="",subject="CN=www.example.com",issuer="CN=Metrics Intermediate",serial="` + serial + `"} 0`,
+		`ssl_verify_ok{path="",endpoint="` + server.Listener.Addr().String() + `",subject="CN=www.example.com",issuer="CN=Metrics Intermediate",serial="` + serial + `"} 1`,
+		`ssl_probe_errors_total{target="127.0.0.1:1"} 1`,
+		`ssl_probe_errors_total{target="` + certsDir + `"} 0`,
+		"ssl_last_scan_timestamp_seconds ",
+	} {
+		if !strings.Contains(body, expected) {
+			t.Fatalf("Expected %q in metrics:\n%s", expected, body)
+		}
+	}
+	if !strings.Contains(body, `ssl_verify_ok{path="`+fullchain+`",endpoint="",subject="CN=www.example.com",issuer="CN=Metrics Intermediate",serial="`+serial+`"} 1`) {
+		t.Fatalf("Expected the full chain to verify:\n%s", body)
+	}
+
+	// Un archivo de objetivos sin objetivos es un error de configuración
+	empty := filepath.Join(dir, "empty.yaml")
+	os.WriteFile(empty, []byte("interval: 1m\n"), 0644)
+	if out, err := runCommand(t, "serve-metrics", "--targets", empty); err == nil || !strings.Contains(out, "does not define any target") {
+		t.Fatalf("Expected an error for an empty targets file:\n%s", out)
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookRecorder guarda los cuerpos recibidos por cada ruta del servidor de webhooks.
+type webhookRecorder struct {
+	mu     sync.Mutex
+	bodies map[string][]string
+}
+
+func newWebhookServer(t *testing.T) (*httptest.Server, *webhookRecorder) {
+	recorder := &webhookRecorder{bodies: map[string][]string{}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		recorder.mu.Lock()
+		recorder.bodies[r.URL.Path] = append(recorder.bodies[r.URL.Path], r.Header.Get("X-Token")+" "+string(body))
+		recorder.mu.Unlock()
+	}))
+	t.Cleanup(server.Close)
+	return server, recorder
+}
+
+func (r *webhookRecorder) get(path string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string{}, r.bodies[path]...)
+}
+
+// smtpRecorder es un servidor SMTP mínimo que acepta todos los mensajes y los guarda.
+type smtpRecorder struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func newSMTPServer(t *testing.T) (string, *smtpRecorder) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	recorder := &smtpRecorder{}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go recorder.serve(conn)
+		}
+	}()
+	return listener.Addr().String(), recorder
+}
+
+func (s *smtpRecorder) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	io.WriteString(conn, "220 localhost ESMTP stand-in\r\n")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			io.WriteString(conn, "250-localhost\r\n250 8BITMIME\r\n")
+		case command == "DATA":
+			io.WriteString(conn, "354 End data with <CR><LF>.<CR><LF>\r\n")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, data.String())
+			s.mu.Unlock()
+			io.WriteString(conn, "250 OK\r\n")
+		case command == "QUIT":
+			io.WriteString(conn, "221 Bye\r\n")
+			return
+		default:
+			io.WriteString(conn, "250 OK\r\n")
+		}
+	}
+}
+
+func (s *smtpRecorder) get() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.messages...)
+}
+
+// writeNotifyConfig crea un archivo de configuración con la sección notify apuntando a los servidores de prueba.
+func writeNotifyConfig(t *testing.T, dir, webhookURL, smtpAddress string) string {
+	host, port, _ := net.SplitHostPort(smtpAddress)
+	path := filepath.Join(dir, "config.yaml")
+	config := `default_domain: example.com
+notify:
+  thresholds: [30, 7, 1]
+  state_file: ` + filepath.Join(dir, "notify-state.json") + `
+  webhooks:
+    - url: ` + webhookURL + `/generic
+      template: '{"cert": {{json .CommonName}}, "days": {{.Days}}, "threshold": {{.Threshold}}}'
+      headers:
+        X-Token: secret
+    - url: ` + webhookURL + `/slack
+      type: slack
+    - url: ` + webhookURL + `/teams
+      type: teams
+  smtp:
+    host: ` + host + `
+    port: ` + port + `
+    from: ssl-tool@example.com
+    to: [ops@example.com]
+`
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return path
+}
+
+// Test para check-expiration --notify con umbrales y deduplicación
+func TestCheckExpirationNotify(t *testing.T) {
+	dir := t.TempDir()
+	certsDir := filepath.Join(dir, "certs")
+	os.Mkdir(certsDir, 0755)
+	writePEM(t, filepath.Join(certsDir, "soon.pem"), newTestCertExpiring(t, "soon.example.com", 5*24*time.Hour))
+	writePEM(t, filepath.Join(certsDir, "fine.pem"), newTestCertExpiring(t, "fine.example.com", 90*24*time.Hour))
+	writePEM(t, filepath.Join(certsDir, "expired.pem"), newTestCertExpiring(t, "expired.example.com", -2*time.Hour))
+
+	webhooks, recorder := newWebhookServer(t)
+	smtpAddress, mails := newSMTPServer(t)
+	config := writeNotifyConfig(t, dir, webhooks.URL, smtpAddress)
+
+	out, err := runCommand(t, "check-expiration", "--path", certsDir, "--notify", "--config", config)
+	if code := exitCode(t, err); code != 2 {
+		t.Fatalf("Expected exit code 2, got %d:\n%s", code, out)
+	}
+	if strings.Contains(out, "Notification error") || strings.Count(out, "Notified: ") != 2 {
+		t.Fatalf("Expected two notifications:\n%s", out)
+	}
+
+	generic := recorder.get("/generic")
+	if len(generic) != 2 {
+		t.Fatalf("Expected 2 generic webhooks, got %d: %v", len(generic), generic)
+	}
+	var payload map[string]interface{}
+	for _, body := range generic {
+		if !strings.HasPrefix(body, "secret ") {
+			t.Fatalf("Expected the configured header in the generic webhook: %q", body)
+		}
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(body, "secret ")), &payload); err != nil {
+			t.Fatalf("Generic webhook body is not valid JSON: %v\n%s", err, body)
+		}
+		if payload["cert"] == "soon.example.com" && payload["threshold"] != float64(7) {
+			t.Fatalf("Expected the 7 day threshold for soon.example.com: %v", payload)
+		}
+	}
+	if slack := recorder.get("/slack"); len(slack) != 2 || !strings.Contains(strings.Join(slack, "\n"), `"text":"Certificate expired.example.com`) {
+		t.Fatalf("Unexpected Slack webhooks: %v", slack)
+	}
+	if teams := recorder.get("/teams"); len(teams) != 2 || !strings.Contains(teams[0], `"@type":"MessageCard"`) {
+		t.Fatalf("Unexpected Teams webhooks: %v", teams)
+	}
+	messages := mails.get()
+	if len(messages) != 2 || !strings.Contains(strings.Join(messages, "\n"), "Subject: [ssl-tool] Certificate expiring: soon.example.com") {
+		t.Fatalf("Unexpected emails: %v", messages)
+	}
+
+	// Una segunda ejecución no repite avisos ya enviados
+	out, _ = runCommand(t, "check-expiration", "--path", certsDir, "--notify", "--config", config)
+	if strings.Contains(out, "Notified: ") || len(recorder.get("/slack")) != 2 {
+		t.Fatalf("Expected no repeated notifications:\n%s", out)
+	}
+
+	// --at evalúa un instante hipotético: no se envían avisos ni se toca el estado
+	state, _ := os.ReadFile(filepath.Join(dir, "notify-state.json"))
+	later := time.Now().Add(4*24*time.Hour + 12*time.Hour).UTC().Format(time.RFC3339)
+	out, err = runCommand(t, "check-expiration", "--path", certsDir, "--notify", "--config", config, "--at", later)
+	if err == nil || !strings.Contains(out, "--notify cannot be used with --at") || len(recorder.get("/slack")) != 2 {
+		t.Fatalf("Expected --notify with --at to be rejected:\n%s", out)
+	}
+	if after, _ := os.ReadFile(filepath.Join(dir, "notify-state.json")); string(after) != string(state) {
+		t.Fatalf("The notification state changed with --at:\n%s", after)
+	}
+
+	// Al alcanzar un umbral menor se avisa de nuevo: el mismo certificado (origen y número de serie)
+	// caduca ahora en 12 horas
+	soon, _ := os.ReadFile(filepath.Join(certsDir, "soon.pem"))
+	block, _ := pem.Decode(soon)
+	previous, _ := x509.ParseCertificate(block.Bytes)
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber: previous.SerialNumber,
+		Subject:      previous.Subject,
+		NotBefore:    previous.NotBefore,
+		NotAfter:     time.Now().Add(12 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	renewed, _ := x509.ParseCertificate(der)
+	writePEM(t, filepath.Join(certsDir, "soon.pem"), &testCert{cert: renewed})
+	out, _ = runCommand(t, "check-expiration", "--path", certsDir, "--notify", "--config", config)
+	if strings.Count(out, "Notified: ") != 1 || !strings.Contains(out, "soon.example.com") {
+		t.Fatalf("Expected one notification for the 1 day threshold:\n%s", out)
+	}
+
+	// Sin sección notify, --notify lo indica por la salida de error
+	plain := filepath.Join(dir, "plain.yaml")
+	os.WriteFile(plain, []byte("default_domain: example.com\n"), 0644)
+	out, _ = runCommand(t, "check-expiration", "--cert", filepath.Join(certsDir, "soon.pem"), "--notify", "--config", plain)
+	if !strings.Contains(out, "requires a notify section") {
+		t.Fatalf("Expected a missing notify section error:\n%s", out)
+	}
+}
+
+// Test para los avisos de serve-metrics --notify
+func TestServeMetricsNotify(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "soon.pem")
+	writePEM(t, certFile, newTestCertExpiring(t, "daemon.example.com", 3*24*time.Hour))
+
+	webhooks, recorder := newWebhookServer(t)
+	smtpAddress, _ := newSMTPServer(t)
+	config := writeNotifyConfig(t, dir, webhooks.URL, smtpAddress)
+	targets := filepath.Join(dir, "targets.yaml")
+	os.WriteFile(targets, []byte("targets:\n  - file: "+certFile+"\n"), 0644)
+
+	address := freeAddress(t)
+	output := startDaemon(t, "serve-metrics", "--listen", address, "--targets", targets, "--notify", "--config", config)
+	waitForHTTP(t, "http://"+address+"/metrics", output)
+
+	slack := recorder.get("/slack")
+	if len(slack) != 1 || !strings.Contains(slack[0], "daemon.example.com") {
+		t.Fatalf("Expected one Slack notification from the daemon: %v\n%s", slack, output)
+	}
+}
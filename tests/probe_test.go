@@ -0,0 +1,115 @@
+package main
+
+import (
+	"crypto/tls"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// newProbeServer arranca un servidor TLS con la configuración indicada que completa el handshake y
+// cierra la conexión.
+func newProbeServer(t *testing.T, config *tls.Config) string {
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", config)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				if err := conn.(*tls.Conn).Handshake(); err != nil {
+					return
+				}
+				// Mantiene la conexión abierta para que el cliente reciba los tickets de TLS 1.3
+				conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+				conn.Read(make([]byte, 1))
+			}(conn)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+// Test para probe contra un servidor restringido al perfil intermediate y otro que acepta TLS 1.0
+func TestProbe(t *testing.T) {
+	root := newTestCA(t, "Probe Root", nil)
+	leaf := newTestLeaf(t, root, "probe.example.com")
+
+	staple, err := ocsp.CreateResponse(root.cert, root.cert, ocsp.Response{
+		Status:       ocsp.Good,
+		SerialNumber: leaf.cert.SerialNumber,
+		ThisUpdate:   time.Now().Add(-time.Hour),
+		NextUpdate:   time.Now().Add(24 * time.Hour),
+	}, root.key)
+	if err != nil {
+		t.Fatalf("Failed to create OCSP response: %v", err)
+	}
+	certificate := tls.Certificate{
+		Certificate: [][]byte{leaf.cert.Raw, root.cert.Raw},
+		PrivateKey:  leaf.key,
+		OCSPStaple:  staple,
+	}
+
+	address := newProbeServer(t, &tls.Config{
+		Certificates:     []tls.Certificate{certificate},
+		MinVersion:       tls.VersionTLS12,
+		CipherSuites:     []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256},
+		CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256},
+		NextProtos:       []string{"h2", "http/1.1"},
+	})
+	out, err := runCommand(t, "probe", address, "--require", "intermediate")
+	if err != nil {
+		t.Fatalf("Error running probe: %v\n%s", err, out)
+	}
+	for _, expected := range []string{
+		"Certificate: probe.example.com",
+		"TLS 1.0  no",
+		"TLS 1.2  yes",
+		"TLS 1.3  yes",
+		"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+		"TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256",
+		"Curves: X25519, CurveP256\n",
+		"ALPN: h2, http/1.1",
+		"OCSP stapling: good",
+		"Session resumption: yes",
+		"TLS 1.2 is not allowed",
+		"Grade: intermediate",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("Expected %q in probe output:\n%s", expected, out)
+		}
+	}
+	if strings.Contains(out, "TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384") {
+		t.Fatalf("Probe reported a cipher suite the server does not accept:\n%s", out)
+	}
+
+	// TLS 1.0 y suites CBC solo cumplen el perfil old
+	legacy := newProbeServer(t, &tls.Config{
+		Certificates:     []tls.Certificate{{Certificate: [][]byte{leaf.cert.Raw}, PrivateKey: leaf.key}},
+		MinVersion:       tls.VersionTLS10,
+		MaxVersion:       tls.VersionTLS12,
+		CipherSuites:     []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA, tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+		CurvePreferences: []tls.CurveID{tls.CurveP256},
+	})
+	out, err = runCommand(t, "probe", legacy, "--require", "intermediate")
+	if err == nil || !strings.Contains(out, "does not meet the Mozilla intermediate profile") {
+		t.Fatalf("Expected probe --require intermediate to fail:\n%s", out)
+	}
+	for _, expected := range []string{"TLS 1.0  yes", "TLS 1.3  no", "TLS 1.0 is not allowed", "cipher suite TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA is not allowed", "OCSP stapling: not stapled", "Grade: old"} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("Expected %q in probe output:\n%s", expected, out)
+		}
+	}
+
+	if out, err := runCommand(t, "probe", address, "--require", "strict"); err == nil || !strings.Contains(out, "invalid --require profile") {
+		t.Fatalf("Expected an invalid profile error:\n%s", out)
+	}
+}
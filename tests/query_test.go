@@ -0,0 +1,98 @@
+package main
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test para --where sobre el escaneo de un directorio y sobre el inventario, en texto y en JSON
+func TestQueryWhere(t *testing.T) {
+	dir := t.TempDir()
+	certsDir := filepath.Join(dir, "certs")
+	os.Mkdir(certsDir, 0755)
+
+	corpCA := newTestCA(t, "Corp Issuing CA", nil)
+	publicCA := newTestCA(t, "Public Issuing CA", nil)
+	expiring := newTestCert(t, &x509.Certificate{
+		Subject:  pkix.Name{CommonName: "intranet.corp"},
+		DNSNames: []string{"intranet.corp", "wiki.corp"},
+		NotAfter: time.Now().Add(10 * 24 * time.Hour),
+	}, corpCA)
+	healthy := newTestLeaf(t, corpCA, "mail.corp")
+	public := newTestCert(t, &x509.Certificate{
+		Subject:  pkix.Name{CommonName: "www.example.com"},
+		DNSNames: []string{"www.example.com"},
+		NotAfter: time.Now().Add(20 * 24 * time.Hour),
+	}, publicCA)
+	writePEM(t, filepath.Join(certsDir, "intranet.pem"), expiring)
+	writePEM(t, filepath.Join(certsDir, "mail.pem"), healthy)
+	writePEM(t, filepath.Join(certsDir, "www.pem"), public)
+
+	where := `days < 30 && issuer.cn ~ "^Corp" && san contains "*.corp"`
+	out, err := runCommand(t, "check-expiration", "--path", certsDir, "--where", where)
+	if code := exitCode(t, err); code != 1 {
+		t.Fatalf("Expected exit code 1 (WARNING), got %d\n%s", code, out)
+	}
+	if !strings.Contains(out, "1 certificate(s)") || !strings.Contains(out, "intranet.pem") || strings.Contains(out, "www.pem") {
+		t.Fatalf("Expected only intranet.pem to match:\n%s", out)
+	}
+
+	// El filtro también se aplica a la salida JSON, que expone el modelo de campos
+	out, err = runCommand(t, "check-expiration", "--path", certsDir, "--where", `key.type == "ECDSA" && !ca && (san == "mail.corp" || days <= 20)`, "--format", "json")
+	if code := exitCode(t, err); code != 1 {
+		t.Fatalf("Expected exit code 1 (WARNING), got %d\n%s", code, out)
+	}
+	var report struct {
+		State        string                   `json:"state"`
+		Certificates []map[string]interface{} `json:"certificates"`
+	}
+	if err := json.Unmarshal([]byte(out), &report); err != nil {
+		t.Fatalf("Invalid JSON output: %v\n%s", err, out)
+	}
+	if report.State != "WARNING" || len(report.Certificates) != 3 {
+		t.Fatalf("Unexpected JSON report:\n%s", out)
+	}
+	first := report.Certificates[0]
+	if first["subject.cn"] != "intranet.corp" || first["key.size"] != float64(256) || first["state"] != "WARNING" {
+		t.Fatalf("Unexpected fields for the first certificate:\n%s", out)
+	}
+
+	// Errores de sintaxis y campos desconocidos
+	for _, expr := range []string{`days <`, `unknown == 1`, `days ~ "1"`, `(days < 1`} {
+		out, err := runCommand(t, "check-expiration", "--path", certsDir, "--where", expr)
+		if err == nil || !strings.Contains(out, "invalid query") {
+			t.Fatalf("Expected %q to be rejected:\n%s", expr, out)
+		}
+	}
+
+	// Inventario: los campos propios (owner, source) también se pueden consultar
+	db := filepath.Join(dir, "inventory.db")
+	if out, err := runCommand(t, "inventory", "add", filepath.Join(certsDir, "intranet.pem"), filepath.Join(certsDir, "mail.pem"), "--owner", "infra", "--inventory", db); err != nil {
+		t.Fatalf("Error running inventory add: %v\n%s", err, out)
+	}
+	if out, err := runCommand(t, "inventory", "add", filepath.Join(certsDir, "www.pem"), "--owner", "web", "--inventory", db); err != nil {
+		t.Fatalf("Error running inventory add: %v\n%s", err, out)
+	}
+
+	out, err = runCommand(t, "inventory", "list", "--inventory", db, "--where", `owner == "infra" && days > 30`)
+	if err != nil || !strings.Contains(out, "mail.corp") || strings.Contains(out, "intranet.corp") || strings.Contains(out, "www.example.com") {
+		t.Fatalf("Unexpected inventory list result: %v\n%s", err, out)
+	}
+
+	out, err = runCommand(t, "inventory", "list", "--inventory", db, "--where", `source contains "*www.pem"`, "--format", "json")
+	var entries []map[string]interface{}
+	if err != nil || json.Unmarshal([]byte(out), &entries) != nil || len(entries) != 1 || entries[0]["owner"] != "web" {
+		t.Fatalf("Unexpected inventory JSON: %v\n%s", err, out)
+	}
+
+	out, err = runCommand(t, "inventory", "list", "--inventory", db, "--where", "expired")
+	if err != nil || !strings.Contains(out, "No inventory entries match the query") {
+		t.Fatalf("Expected no expired entries: %v\n%s", err, out)
+	}
+}
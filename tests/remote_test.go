@@ -0,0 +1,112 @@
+package main
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestTLSServer arranca un servidor HTTPS local que presenta la cadena hoja + intermedio.
+func newTestTLSServer(t *testing.T, leaf, intermediate *testCert) *httptest.Server {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{{
+			Certificate: [][]byte{leaf.cert.Raw, intermediate.cert.Raw},
+			PrivateKey:  leaf.key,
+		}},
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+// Test para --remote en inspect, fingerprint, check-expiration y verify-chain
+func TestRemoteEndpoint(t *testing.T) {
+	dir := t.TempDir()
+	root := newTestCA(t, "Remote Root", nil)
+	intermediate := newTestCA(t, "Remote Intermediate", root)
+	leaf := newTestLeaf(t, intermediate, "www.example.com")
+	server := newTestTLSServer(t, leaf, intermediate)
+	address := server.Listener.Addr().String()
+
+	leafFile := filepath.Join(dir, "leaf.pem")
+	rootsFile := filepath.Join(dir, "roots.pem")
+	writePEM(t, leafFile, leaf)
+	writePEM(t, rootsFile, root)
+
+	out, err := runCommand(t, "inspect", "--remote", address)
+	if err != nil {
+		t.Fatalf("Error running inspect --remote: %v\n%s", err, out)
+	}
+	if !strings.Contains(out, "2 certificate(s)") || !strings.Contains(out, "www.example.com") || !strings.Contains(out, "Remote Intermediate") {
+		t.Fatalf("Unexpected inspect output:\n%s", out)
+	}
+
+	remote, err := runCommand(t, "fingerprint", "--remote", address, "--sni", "www.example.com")
+	if err != nil {
+		t.Fatalf("Error running fingerprint --remote: %v\n%s", err, remote)
+	}
+	local, _ := runCommand(t, "fingerprint", "--cert", leafFile)
+	if remote != local {
+		t.Fatalf("Remote fingerprint %q does not match the leaf %q", remote, local)
+	}
+
+	out, err = runCommand(t, "check-expiration", "--remote", address)
+	if code := exitCode(t, err); code != 0 || !strings.Contains(out, "CERT OK - 2 certificate(s)") {
+		t.Fatalf("Unexpected check-expiration --remote result (exit %d):\n%s", code, out)
+	}
+
+	// La cadena presentada se valida con la raíz propia y el nombre enviado por SNI
+	out, err = runCommand(t, "verify-chain", "--remote", address, "--sni", "www.example.com", "--roots", rootsFile)
+	if err != nil {
+		t.Fatalf("Error running verify-chain --remote: %v\n%s", err, out)
+	}
+	out, err = runCommand(t, "verify-chain", "--remote", address, "--sni", "other.example.com", "--roots", rootsFile)
+	if err == nil || !strings.Contains(out, "hostname mismatch") {
+		t.Fatalf("Expected a hostname mismatch:\n%s", out)
+	}
+
+	t.Log("--remote passed successfully")
+}
+
+// Test para el timeout de --remote contra un servidor que nunca responde
+func TestRemoteTimeout(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		// Acepta conexiones pero nunca responde al ClientHello
+		var conns []net.Conn
+		defer func() {
+			for _, conn := range conns {
+				conn.Close()
+			}
+		}()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conns = append(conns, conn)
+		}
+	}()
+
+	start := time.Now()
+	out, err := runCommand(t, "inspect", "--remote", listener.Addr().String(), "--timeout", "500ms")
+	if err == nil {
+		t.Fatalf("Expected inspect to time out:\n%s", out)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("Timeout was not honoured: took %s", elapsed)
+	}
+	if !strings.Contains(out, "handshake") {
+		t.Fatalf("Expected a handshake error:\n%s", out)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/csv"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test para report en los tres formatos, desde un directorio y desde el inventario
+func TestReport(t *testing.T) {
+	dir := t.TempDir()
+	certsDir := filepath.Join(dir, "certs")
+	os.Mkdir(certsDir, 0755)
+
+	ca := newTestCA(t, "Report CA", nil)
+	writePEM(t, filepath.Join(certsDir, "soon.pem"), newTestCert(t, &x509.Certificate{
+		Subject:  pkix.Name{CommonName: "soon.example.com"},
+		NotAfter: time.Now().Add(5 * 24 * time.Hour),
+	}, ca))
+	writePEM(t, filepath.Join(certsDir, "later.pem"), newTestLeaf(t, ca, "later.example.com"))
+	writePEM(t, filepath.Join(certsDir, "expired.pem"), newTestCert(t, &x509.Certificate{
+		Subject:   pkix.Name{CommonName: "expired.example.com"},
+		NotBefore: time.Now().Add(-48 * time.Hour),
+		NotAfter:  time.Now().Add(-24 * time.Hour),
+	}, ca))
+
+	// Certificado autofirmado con una clave RSA de 1024 bits
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1024),
+		Subject:      pkix.Name{CommonName: "legacy.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(60 * 24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	legacy, _ := x509.ParseCertificate(der)
+	writePEM(t, filepath.Join(certsDir, "legacy.pem"), &testCert{cert: legacy})
+
+	out, err := runCommand(t, "report", "--path", certsDir, "--inventory", filepath.Join(dir, "none.db"))
+	if err != nil {
+		t.Fatalf("Error running report: %v\n%s", err, out)
+	}
+	for _, expected := range []string{
+		"| expired | 1 |",
+		"| under 7 days | 1 |",
+		"| under 30 days | 0 |",
+		"| under 90 days | 2 |",
+		"| Report CA | 3 |",
+		"| legacy.example.com | 1 |",
+		"| legacy.example.com |  | RSA key of 1024 bits (minimum 2048) |",
+		"## Owner: (no owner)",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("Expected %q in the markdown report:\n%s", expected, out)
+		}
+	}
+
+	// Desde el inventario, con una tabla por propietario y filtrado con --where
+	db := filepath.Join(dir, "inventory.db")
+	runCommand(t, "inventory", "add", filepath.Join(certsDir, "soon.pem"), "--owner", "web", "--inventory", db)
+	runCommand(t, "inventory", "add", filepath.Join(certsDir, "legacy.pem"), "--owner", "legacy-team", "--inventory", db)
+	runCommand(t, "inventory", "add", filepath.Join(certsDir, "later.pem"), "--inventory", db)
+
+	csvFile := filepath.Join(dir, "report.csv")
+	out, err = runCommand(t, "report", "--format", "csv", "--output", csvFile, "--inventory", db, "--where", `issuer.cn == "Report CA"`)
+	if err != nil || !strings.Contains(out, "Report with 2 certificate(s) saved to "+csvFile) {
+		t.Fatalf("Error running report --format csv: %v\n%s", err, out)
+	}
+	data, _ := os.ReadFile(csvFile)
+	reader := csv.NewReader(strings.NewReader(string(data)))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		t.Fatalf("Invalid CSV report: %v\n%s", err, data)
+	}
+	owners := map[string]bool{}
+	inCertificates := false
+	for _, record := range records {
+		if inCertificates && record[0] != "owner" {
+			owners[record[1]+"="+record[0]] = true
+		}
+		if record[0] == "Certificates" {
+			inCertificates = true
+		}
+	}
+	if !owners["soon.example.com=web"] || !owners["later.example.com="] || len(owners) != 2 {
+		t.Fatalf("Unexpected certificate rows in the CSV report:\n%s", data)
+	}
+
+	out, err = runCommand(t, "report", "--format", "html", "--inventory", db)
+	if err != nil {
+		t.Fatalf("Error running report --format html: %v\n%s", err, out)
+	}
+	for _, expected := range []string{
+		"<h2>Owner: legacy-team</h2>",
+		"<h2>Owner: web</h2>",
+		"<h2>Owner: (no owner)</h2>",
+		"RSA key of 1024 bits",
+		`<table class="sortable">`,
+		"<script>",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("Expected %q in the HTML report:\n%s", expected, out)
+		}
+	}
+	if strings.Contains(out, "<link") || strings.Contains(out, "src=") {
+		t.Fatalf("The HTML report should not reference external resources:\n%s", out)
+	}
+
+	if out, err := runCommand(t, "report", "--format", "pdf", "--path", certsDir); err == nil || !strings.Contains(out, "unsupported report format") {
+		t.Fatalf("Expected an error for an unsupported format:\n%s", out)
+	}
+}
+
+// Test para report --format csv con un CN que una hoja de cálculo ejecutaría como fórmula
+func TestReportCSVFormula(t *testing.T) {
+	dir := t.TempDir()
+	name := `=HYPERLINK("https://evil.example.com","open")`
+	writePEM(t, filepath.Join(dir, "formula.pem"), newTestCertExpiring(t, name, 60*24*time.Hour))
+
+	csvFile := filepath.Join(dir, "report.csv")
+	out, err := runCommand(t, "report", "--path", dir, "--format", "csv", "--output", csvFile, "--inventory", filepath.Join(dir, "none.db"))
+	if err != nil {
+		t.Fatalf("Error running report --format csv: %v\n%s", err, out)
+	}
+	data, _ := os.ReadFile(csvFile)
+	reader := csv.NewReader(strings.NewReader(string(data)))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		t.Fatalf("Invalid CSV report: %v\n%s", err, data)
+	}
+	escaped := false
+	for _, record := range records {
+		for _, cell := range record {
+			if strings.HasPrefix(cell, "=") {
+				t.Fatalf("Formula cell in the CSV report: %q\n%s", cell, data)
+			}
+			escaped = escaped || cell == "'"+name
+		}
+	}
+	if !escaped {
+		t.Fatalf("Expected the CN to be prefixed with an apostrophe:\n%s", data)
+	}
+}
@@ -0,0 +1,357 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/smallstep/pkcs7"
+	"github.com/smallstep/scep"
+)
+
+const testSCEPChallenge = "scep-secret"
+
+// Atributos SCEP para construir las respuestas pendientes y las de CertPoll, que la librería no genera
+var (
+	oidSCEPMessageType    = asn1.ObjectIdentifier{2, 16, 840, 1, 113733, 1, 9, 2}
+	oidSCEPPKIStatus      = asn1.ObjectIdentifier{2, 16, 840, 1, 113733, 1, 9, 3}
+	oidSCEPFailInfo       = asn1.ObjectIdentifier{2, 16, 840, 1, 113733, 1, 9, 4}
+	oidSCEPSenderNonce    = asn1.ObjectIdentifier{2, 16, 840, 1, 113733, 1, 9, 5}
+	oidSCEPRecipientNonce = asn1.ObjectIdentifier{2, 16, 840, 1, 113733, 1, 9, 6}
+	oidSCEPTransactionID  = asn1.ObjectIdentifier{2, 16, 840, 1, 113733, 1, 9, 7}
+)
+
+// scepTransaction es una petición que el servidor de prueba ha dejado pendiente.
+type scepTransaction struct {
+	challenge string
+	csr       *x509.CertificateRequest
+}
+
+// newSCEPServer arranca un servidor SCEP mínimo con una CA RSA que también hace de RA. Emite si
+// el challenge es testSCEPChallenge; con "pending" deja la petición pendiente y la emite en el
+// primer CertPoll, y con "hold" la mantiene pendiente hasta que se llama a approve. El resto de
+// challenges se rechazan con badRequest, y un segundo PKCSReq de una transacción pendiente con un
+// error HTTP 400.
+func newSCEPServer(t *testing.T) (server *httptest.Server, caCert *x509.Certificate, approve func()) {
+	t.Helper()
+	caKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "SCEP CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	caCert, _ = x509.ParseCertificate(der)
+
+	var mu sync.Mutex
+	transactions := map[scep.TransactionID]*scepTransaction{}
+	approved := false
+	approve = func() {
+		mu.Lock()
+		approved = true
+		mu.Unlock()
+	}
+
+	issue := func(csr *x509.CertificateRequest) (*x509.Certificate, error) {
+		serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+		leaf := &x509.Certificate{
+			SerialNumber: serial,
+			Subject:      csr.Subject,
+			DNSNames:     csr.DNSNames,
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(30 * 24 * time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		}
+		issued, err := x509.CreateCertificate(rand.Reader, leaf, caCert, csr.PublicKey, caKey)
+		if err != nil {
+			return nil, err
+		}
+		return x509.ParseCertificate(issued)
+	}
+	// certRep construye un CertRep; con SUCCESS, el certificado va cifrado para requester
+	certRep := func(txID scep.TransactionID, nonce scep.SenderNonce, status scep.PKIStatus, failInfo scep.FailInfo, cert, requester *x509.Certificate) ([]byte, error) {
+		var content []byte
+		if cert != nil {
+			deg, err := scep.DegenerateCertificates([]*x509.Certificate{cert})
+			if err != nil {
+				return nil, err
+			}
+			if content, err = pkcs7.Encrypt(deg, []*x509.Certificate{requester}); err != nil {
+				return nil, err
+			}
+		}
+		sd, err := pkcs7.NewSignedData(content)
+		if err != nil {
+			return nil, err
+		}
+		if cert != nil {
+			sd.AddCertificate(cert)
+		}
+		attributes := []pkcs7.Attribute{
+			{Type: oidSCEPTransactionID, Value: txID},
+			{Type: oidSCEPPKIStatus, Value: status},
+			{Type: oidSCEPMessageType, Value: scep.CertRep},
+			{Type: oidSCEPSenderNonce, Value: nonce},
+			{Type: oidSCEPRecipientNonce, Value: nonce},
+		}
+		if status == scep.FAILURE {
+			attributes = append(attributes, pkcs7.Attribute{Type: oidSCEPFailInfo, Value: failInfo})
+		}
+		if err := sd.AddSigner(caCert, caKey, pkcs7.SignerInfoConfig{ExtraSignedAttributes: attributes}); err != nil {
+			return nil, err
+		}
+		return sd.Finish()
+	}
+
+	// pkcsReq responde a un PKCSReq, que la librería sabe leer
+	pkcsReq := func(body []byte) ([]byte, error) {
+		msg, err := scep.ParsePKIMessage(body)
+		if err == nil {
+			err = msg.DecryptPKIEnvelope(caCert, caKey)
+		}
+		if err != nil {
+			return nil, err
+		}
+		challenge := msg.CSRReqMessage.ChallengePassword
+		mu.Lock()
+		defer mu.Unlock()
+		if transactions[msg.TransactionID] != nil {
+			return nil, errors.New("duplicate PKCSReq for a pending transaction")
+		}
+
+		switch challenge {
+		case "pending", "hold":
+			transactions[msg.TransactionID] = &scepTransaction{challenge: challenge, csr: msg.CSRReqMessage.CSR}
+			return certRep(msg.TransactionID, msg.SenderNonce, scep.PENDING, "", nil, nil)
+		case testSCEPChallenge:
+			cert, err := issue(msg.CSRReqMessage.CSR)
+			if err != nil {
+				return nil, err
+			}
+			rep, err := msg.Success(caCert, caKey, cert)
+			if err != nil {
+				return nil, err
+			}
+			return rep.Raw, nil
+		default:
+			rep, err := msg.Fail(caCert, caKey, scep.BadRequest)
+			if err != nil {
+				return nil, err
+			}
+			return rep.Raw, nil
+		}
+	}
+
+	// certPoll responde a un CertPoll, que la librería no sabe leer: se comprueba su contenido
+	// IssuerAndSubject y se emite o se sigue esperando según el challenge del PKCSReq
+	certPoll := func(p7 *pkcs7.PKCS7, txID scep.TransactionID, nonce scep.SenderNonce) ([]byte, error) {
+		envelope, err := pkcs7.Parse(p7.Content)
+		if err != nil {
+			return nil, err
+		}
+		content, err := envelope.Decrypt(caCert, caKey)
+		if err != nil {
+			return nil, err
+		}
+		var ias struct {
+			Issuer  asn1.RawValue
+			Subject asn1.RawValue
+		}
+		if _, err := asn1.Unmarshal(content, &ias); err != nil {
+			return nil, err
+		}
+		if len(p7.Certificates) == 0 {
+			return nil, errors.New("CertPoll without signer certificate")
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		tx := transactions[txID]
+		if tx == nil || !bytes.Equal(ias.Issuer.FullBytes, caCert.RawSubject) || !bytes.Equal(ias.Subject.FullBytes, tx.csr.RawSubject) {
+			return certRep(txID, nonce, scep.FAILURE, scep.BadCertID, nil, nil)
+		}
+		if tx.challenge == "hold" && !approved {
+			return certRep(txID, nonce, scep.PENDING, "", nil, nil)
+		}
+		cert, err := issue(tx.csr)
+		if err != nil {
+			return nil, err
+		}
+		delete(transactions, txID)
+		return certRep(txID, nonce, scep.SUCCESS, "", cert, p7.Certificates[0])
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("operation") {
+		case "GetCACaps":
+			w.Write([]byte("POSTPKIOperation\nSHA-256\nAES\n"))
+		case "GetCACert":
+			w.Header().Set("Content-Type", "application/x-x509-ca-cert")
+			w.Write(caCert.Raw)
+		case "PKIOperation":
+			body, _ := io.ReadAll(r.Body)
+			var messageType scep.MessageType
+			var txID scep.TransactionID
+			var nonce scep.SenderNonce
+			p7, err := pkcs7.Parse(body)
+			if err == nil {
+				err = p7.Verify()
+			}
+			if err == nil {
+				err = p7.UnmarshalSignedAttribute(oidSCEPMessageType, &messageType)
+			}
+			if err == nil {
+				err = p7.UnmarshalSignedAttribute(oidSCEPTransactionID, &txID)
+			}
+			if err == nil {
+				err = p7.UnmarshalSignedAttribute(oidSCEPSenderNonce, &nonce)
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			var reply []byte
+			switch messageType {
+			case scep.PKCSReq:
+				reply, err = pkcsReq(body)
+			case scep.CertPoll:
+				reply, err = certPoll(p7, txID, nonce)
+			default:
+				err = errors.New("unsupported message type " + string(messageType))
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Content-Type", "application/x-pki-message")
+			w.Write(reply)
+		default:
+			http.Error(w, "unknown operation", http.StatusBadRequest)
+		}
+	})
+
+	server = httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server, caCert, approve
+}
+
+// Test para scep getcacert y enroll contra el servidor SCEP de prueba
+func TestSCEP(t *testing.T) {
+	dir := t.TempDir()
+	server, caCert, approve := newSCEPServer(t)
+	scepURL := server.URL + "/scep"
+	sum := sha256.Sum256(caCert.Raw)
+	caFingerprint := hex.EncodeToString(sum[:])
+
+	// getcacert
+	caFile := filepath.Join(dir, "ca.pem")
+	out, err := runCommand(t, "scep", "getcacert", "--server", scepURL, "--out", caFile)
+	if err != nil || !strings.Contains(out, "Saved 1 CA certificate(s)") || !strings.Contains(out, strings.ToUpper(caFingerprint)) {
+		t.Fatalf("Error running scep getcacert: %v\n%s", err, out)
+	}
+	data, _ := os.ReadFile(caFile)
+	if block, _ := pem.Decode(data); block == nil || string(block.Bytes) != string(caCert.Raw) {
+		t.Fatalf("Expected the SCEP CA in %s:\n%s", caFile, data)
+	}
+
+	// enroll con la generación de generate-csr y la CA fijada por su huella
+	t.Cleanup(func() { os.RemoveAll("scep_example_com") })
+	os.Setenv("SCEP_TEST_CHALLENGE", testSCEPChallenge)
+	defer os.Unsetenv("SCEP_TEST_CHALLENGE")
+	out, err = runCommand(t, "scep", "enroll", "--server", scepURL, "--challenge", "env:SCEP_TEST_CHALLENGE", "--ca-fingerprint", caFingerprint,
+		"--domain", "scep.example.com", "--country", "US", "--locality", "Boston", "--organization", "TestOrg")
+	if err != nil || !strings.Contains(out, "Certificate enrolled for CN=scep.example.com") {
+		t.Fatalf("Error running scep enroll: %v\n%s", err, out)
+	}
+	csrFile, keyFile := filepath.Join("scep_example_com", "scep_example_com.csr"), filepath.Join("scep_example_com", "scep_example_com.key")
+	certFile := filepath.Join("scep_example_com", "scep_example_com.crt")
+	if out, err := runCommand(t, "verify-hashes", "--key", keyFile, "--csr", csrFile, "--cert", certFile); err != nil {
+		t.Fatalf("The issued certificate does not match the key and CSR: %v\n%s", err, out)
+	}
+	if out, err := runCommand(t, "verify-chain", "--cert", certFile, "--roots", caFile); err != nil {
+		t.Fatalf("The issued certificate does not chain to the SCEP CA: %v\n%s", err, out)
+	}
+	csrPEM, _ := os.ReadFile(csrFile)
+	if block, _ := pem.Decode(csrPEM); block == nil || strings.Contains(string(block.Bytes), testSCEPChallenge) {
+		t.Fatalf("The challenge password must not be stored in the CSR file")
+	}
+
+	// Validaciones
+	enroll := func(args ...string) (string, error) {
+		return runCommand(t, append([]string{"scep", "enroll", "--server", scepURL, "--csr", csrFile, "--key", keyFile}, args...)...)
+	}
+	out, err = enroll("--challenge", "wrong", "--out", filepath.Join(dir, "wrong.crt"))
+	if err == nil || !strings.Contains(out, "rejected the request: badRequest") {
+		t.Fatalf("Expected a wrong challenge to be rejected:\n%s", out)
+	}
+	out, err = enroll("--challenge", testSCEPChallenge, "--ca-fingerprint", strings.Repeat("00", 32))
+	if err == nil || !strings.Contains(out, "matches the expected fingerprint") {
+		t.Fatalf("Expected a fingerprint mismatch to be reported:\n%s", out)
+	}
+	out, err = runCommand(t, "scep", "enroll", "--server", scepURL, "--csr", csrFile)
+	if err == nil || !strings.Contains(out, "--csr and --key must be used together") {
+		t.Fatalf("Expected --csr without --key to fail:\n%s", out)
+	}
+
+	// Una petición pendiente se sondea con CertPoll hasta que la CA la aprueba
+	pendingOut := filepath.Join(dir, "pending.crt")
+	out, err = enroll("--challenge", "pending", "--out", pendingOut, "--poll-interval", "100ms")
+	if err != nil || !strings.Contains(out, "Request pending approval") || !strings.Contains(out, pendingOut) {
+		t.Fatalf("Error running scep enroll with a pending request: %v\n%s", err, out)
+	}
+	holdOut := filepath.Join(dir, "hold.crt")
+	out, err = enroll("--challenge", "hold", "--out", holdOut, "--poll-interval", "100ms", "--poll-timeout", "300ms")
+	if err == nil || !strings.Contains(out, "still pending approval") || !strings.Contains(out, "scep enroll --resume") {
+		t.Fatalf("Expected the poll timeout to be reported:\n%s", out)
+	}
+
+	// Repetir el PKCSReq de una transacción pendiente se rechaza; --resume sigue sondeándola
+	out, err = enroll("--challenge", "hold", "--out", holdOut, "--poll-interval", "100ms", "--poll-timeout", "300ms")
+	if err == nil || !strings.Contains(out, "duplicate PKCSReq") {
+		t.Fatalf("Expected a second PKCSReq for a pending transaction to be rejected:\n%s", out)
+	}
+	approve()
+	out, err = enroll("--resume", "--out", holdOut, "--poll-interval", "100ms")
+	if err != nil || !strings.Contains(out, holdOut) {
+		t.Fatalf("Error running scep enroll --resume: %v\n%s", err, out)
+	}
+	if out, err := runCommand(t, "verify-hashes", "--key", keyFile, "--csr", csrFile, "--cert", holdOut); err != nil {
+		t.Fatalf("The polled certificate does not match the key and CSR: %v\n%s", err, out)
+	}
+	out, err = enroll("--resume", "--out", filepath.Join(dir, "unknown.crt"))
+	if err == nil || !strings.Contains(out, "badCertID") {
+		t.Fatalf("Expected --resume without a pending transaction to be rejected:\n%s", out)
+	}
+	out, err = runCommand(t, "scep", "enroll", "--server", scepURL, "--resume")
+	if err == nil || !strings.Contains(out, "--resume needs the --csr and --key") {
+		t.Fatalf("Expected --resume without --csr to fail:\n%s", out)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luisenrique-varelarodriguez/ssl-tool/pkg/ssltool"
+)
+
+// Test para el paquete pkg/ssltool usado como librería, sin pasar por la CLI
+func TestLibraryAPI(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	cwd, _ := os.Getwd()
+
+	// GenerateCSR solo escribe en OutputDir
+	csr, err := ssltool.GenerateCSR(ctx, ssltool.CSROptions{
+		Domain:       "lib.example.com",
+		DNSNames:     []string{"lib.example.com", "www.lib.example.com"},
+		Country:      "ES",
+		Organization: "TestOrg",
+		KeyType:      ssltool.KeyTypeEC256,
+		OutputDir:    filepath.Join(dir, "out"),
+	})
+	if err != nil {
+		t.Fatalf("GenerateCSR failed: %v", err)
+	}
+	if csr.CSRPath != filepath.Join(dir, "out", "lib_example_com.csr") || csr.Request.Subject.CommonName != "lib.example.com" ||
+		!strings.Contains(string(csr.KeyPEM), "EC PRIVATE KEY") {
+		t.Fatalf("Unexpected CSR result: %+v", csr)
+	}
+	if _, err := os.Stat(filepath.Join(cwd, "lib_example_com")); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("GenerateCSR wrote to the current directory")
+	}
+	inMemory, err := ssltool.GenerateCSR(ctx, ssltool.CSROptions{Domain: "memory.example.com"})
+	if err != nil || inMemory.CSRPath != "" || !strings.Contains(string(inMemory.KeyPEM), "RSA PRIVATE KEY") {
+		t.Fatalf("Expected an in-memory RSA CSR without files: %v", err)
+	}
+
+	// Opciones inválidas
+	var optErr *ssltool.OptionError
+	if _, err := ssltool.GenerateCSR(ctx, ssltool.CSROptions{}); !errors.As(err, &optErr) || optErr.Option != "Domain" {
+		t.Fatalf("Expected an OptionError for an empty domain, got %v", err)
+	}
+	if _, err := ssltool.GenerateCSR(ctx, ssltool.CSROptions{Domain: "x.example.com", Country: "ZZ"}); !errors.Is(err, ssltool.ErrInvalidOption) {
+		t.Fatalf("Expected ErrInvalidOption for country ZZ, got %v", err)
+	}
+
+	// Un OutputDir que no se puede crear es un FileError
+	blocker := filepath.Join(dir, "blocker")
+	os.WriteFile(blocker, []byte("not a directory"), 0644)
+	var fileErr *ssltool.FileError
+	if _, err := ssltool.GenerateCSR(ctx, ssltool.CSROptions{Domain: "x.example.com", OutputDir: filepath.Join(blocker, "out")}); !errors.As(err, &fileErr) || !strings.HasPrefix(fileErr.Path, blocker) {
+		t.Fatalf("Expected a FileError for an OutputDir under a regular file, got %v", err)
+	}
+
+	// ExtractInfo y SaveInfo
+	info, err := ssltool.ExtractInfo(ctx, csr.CSRPath)
+	if err != nil || info.CommonName != "lib.example.com" || info.Country != "ES" || info.KeyAlgorithm != "ECDSA" || len(info.SANs) != 2 {
+		t.Fatalf("Unexpected ExtractInfo result: %+v (%v)", info, err)
+	}
+	configFile := filepath.Join(dir, "config.yaml")
+	if err := ssltool.SaveInfo(ctx, info, ssltool.ConfigOptions{Path: configFile, Profile: "lib"}); err != nil {
+		t.Fatalf("SaveInfo failed: %v", err)
+	}
+	if data, _ := os.ReadFile(configFile); !strings.Contains(string(data), "lib:") || !strings.Contains(string(data), "default_domain: lib.example.com") {
+		t.Fatalf("Unexpected configuration file:\n%s", data)
+	}
+
+	// VerifyHashes: la clave del CSR no es la del certificado
+	ca := newTestCA(t, "Library CA", nil)
+	leaf := newTestLeaf(t, ca, "lib.example.com")
+	certFile := filepath.Join(dir, "leaf.pem")
+	keyFile := filepath.Join(dir, "leaf.key")
+	writePEM(t, certFile, leaf, ca)
+	writeKey(t, keyFile, leaf)
+	var mismatch *ssltool.HashMismatchError
+	hashes, err := ssltool.VerifyHashes(ctx, ssltool.HashesOptions{KeyFile: keyFile, CSRFile: csr.CSRPath, CertFile: certFile})
+	if !errors.As(err, &mismatch) || hashes.Match() || hashes.Key != hashes.Certificate {
+		t.Fatalf("Expected a HashMismatchError, got %v (%+v)", err, hashes)
+	}
+
+	// Fingerprint, Inspect, CheckExpiry y VerifyChain
+	fp, err := ssltool.Fingerprint(ctx, ssltool.FingerprintOptions{File: certFile})
+	if err != nil || fp != testFingerprint(leaf) {
+		t.Fatalf("Unexpected fingerprint %s (%v)", fp, err)
+	}
+	if _, err := ssltool.Fingerprint(ctx, ssltool.FingerprintOptions{File: csr.CSRPath}); err == nil || !strings.Contains(err.Error(), "only SPKI fingerprints are available") {
+		t.Fatalf("Expected a full fingerprint of a CSR to fail, got %v", err)
+	}
+	if _, err := ssltool.Fingerprint(ctx, ssltool.FingerprintOptions{File: certFile, Algorithm: "md5"}); !errors.As(err, &optErr) || optErr.Option != "Algorithm" {
+		t.Fatalf("Expected an OptionError for md5, got %v", err)
+	}
+	objects, err := ssltool.Inspect(ctx, certFile)
+	if err != nil || len(objects) != 2 || objects[1].Certificate.Subject.CommonName != "Library CA" {
+		t.Fatalf("Unexpected Inspect result: %v", err)
+	}
+	status, err := ssltool.CheckExpiry(ctx, ssltool.ExpiryOptions{File: certFile, Warn: 365 * 24 * time.Hour})
+	if err != nil || status.State != ssltool.ExpiryExpiring {
+		t.Fatalf("Unexpected expiry status %+v (%v)", status, err)
+	}
+	rootsFile := filepath.Join(dir, "roots.pem")
+	writePEM(t, rootsFile, ca)
+	if chains, err := ssltool.VerifyChain(ctx, ssltool.ChainOptions{CertFile: certFile, RootsFile: rootsFile, Hostname: "lib.example.com"}); err != nil || len(chains) != 1 {
+		t.Fatalf("VerifyChain failed: %v", err)
+	}
+	var chainErr *ssltool.ChainError
+	if _, err := ssltool.VerifyChain(ctx, ssltool.ChainOptions{CertFile: certFile, RootsFile: rootsFile, Hostname: "other.example.com"}); !errors.As(err, &chainErr) {
+		t.Fatalf("Expected a ChainError for the wrong hostname, got %v", err)
+	}
+
+	// FetchChain y Lint sobre la cadena remota
+	intermediate := newTestCA(t, "Library Intermediate", ca)
+	server := newTestTLSServer(t, newTestLeaf(t, intermediate, "remote.example.com"), intermediate)
+	chain, err := ssltool.FetchChain(ctx, ssltool.RemoteOptions{Address: server.Listener.Addr().String()})
+	if err != nil || len(chain) != 2 {
+		t.Fatalf("FetchChain failed: %v", err)
+	}
+	if results := ssltool.LintCertificates(chain); len(results) != 2 || results[1].Subject != "CN=Library Intermediate" {
+		t.Fatalf("Unexpected lint results: %+v", results)
+	}
+	var remoteErr *ssltool.RemoteError
+	closed := newTestTLSServer(t, leaf, ca)
+	closed.Close()
+	if _, err := ssltool.FetchChain(ctx, ssltool.RemoteOptions{Address: closed.Listener.Addr().String(), Timeout: time.Second}); !errors.As(err, &remoteErr) {
+		t.Fatalf("Expected a RemoteError for a closed port, got %v", err)
+	}
+
+	// Archivos inexistentes y contexto cancelado
+	missing := filepath.Join(dir, "missing.pem")
+	if _, err := ssltool.Inspect(ctx, missing); !errors.As(err, &fileErr) || fileErr.Path != missing || !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Expected a FileError wrapping fs.ErrNotExist, got %v", err)
+	}
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := ssltool.GenerateCSR(cancelled, ssltool.CSROptions{Domain: "lib.example.com"}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled from GenerateCSR, got %v", err)
+	}
+	if _, err := ssltool.FetchChain(cancelled, ssltool.RemoteOptions{Address: server.Listener.Addr().String()}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled from FetchChain, got %v", err)
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+// startFakeServer arranca un servidor que ejecuta preamble en claro y después completa un handshake TLS.
+func startFakeServer(t *testing.T, cert *testCert, preamble func(conn net.Conn, r *bufio.Reader) error) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	config := &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{cert.cert.Raw}, PrivateKey: cert.key}}}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				if err := preamble(conn, r); err != nil {
+					return
+				}
+				// El ClientHello puede haber llegado ya al búfer junto con el preámbulo
+				tlsConn := tls.Server(bufferedConn{conn, r}, config)
+				_ = tlsConn.Handshake()
+				tlsConn.Close()
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+// bufferedConn lee a través del bufio.Reader del preámbulo para no perder lo que ya tenga.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c bufferedConn) Read(b []byte) (int, error) { return c.r.Read(b) }
+
+// expectLine lee una línea y comprueba que empieza por el prefijo indicado.
+func expectLine(r *bufio.Reader, prefix string) error {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, prefix) {
+		return fmt.Errorf("unexpected line %q", line)
+	}
+	return nil
+}
+
+// fakePreambles implementa la parte del servidor de cada protocolo hasta el paso a TLS.
+var fakePreambles = map[string]func(conn net.Conn, r *bufio.Reader) error{
+	"smtp": func(conn net.Conn, r *bufio.Reader) error {
+		io.WriteString(conn, "220-mail.example.com ESMTP\r\n220 ready\r\n")
+		if err := expectLine(r, "EHLO "); err != nil {
+			return err
+		}
+		io.WriteString(conn, "250-mail.example.com\r\n250-PIPELINING\r\n250 STARTTLS\r\n")
+		if err := expectLine(r, "STARTTLS"); err != nil {
+			return err
+		}
+		_, err := io.WriteString(conn, "220 Go ahead\r\n")
+		return err
+	},
+	"imap": func(conn net.Conn, r *bufio.Reader) error {
+		io.WriteString(conn, "* OK [CAPABILITY IMAP4rev1 STARTTLS] ready\r\n")
+		if err := expectLine(r, "a001 STARTTLS"); err != nil {
+			return err
+		}
+		_, err := io.WriteString(conn, "a001 OK Begin TLS negotiation now\r\n")
+		return err
+	},
+	"pop3": func(conn net.Conn, r *bufio.Reader) error {
+		io.WriteString(conn, "+OK POP3 ready\r\n")
+		if err := expectLine(r, "STLS"); err != nil {
+			return err
+		}
+		_, err := io.WriteString(conn, "+OK Begin TLS negotiation\r\n")
+		return err
+	},
+	"ftp": func(conn net.Conn, r *bufio.Reader) error {
+		io.WriteString(conn, "220 FTP ready\r\n")
+		if err := expectLine(r, "AUTH TLS"); err != nil {
+			return err
+		}
+		_, err := io.WriteString(conn, "234 AUTH TLS successful\r\n")
+		return err
+	},
+	"ldap": func(conn net.Conn, r *bufio.Reader) error {
+		request := make([]byte, 31)
+		if _, err := io.ReadFull(r, request); err != nil {
+			return err
+		}
+		if !bytes.Contains(request, []byte("1.3.6.1.4.1.1466.20037")) {
+			return fmt.Errorf("unexpected LDAP request %x", request)
+		}
+		// ExtendedResponse: messageID 1, resultCode success, matchedDN y diagnosticMessage vacíos
+		_, err := conn.Write([]byte{0x30, 0x0c, 0x02, 0x01, 0x01, 0x78, 0x07, 0x0a, 0x01, 0x00, 0x04, 0x00, 0x04, 0x00})
+		return err
+	},
+	"postgres": func(conn net.Conn, r *bufio.Reader) error {
+		request := make([]byte, 8)
+		if _, err := io.ReadFull(r, request); err != nil {
+			return err
+		}
+		if binary.BigEndian.Uint32(request[4:]) != 80877103 {
+			return fmt.Errorf("unexpected SSLRequest %x", request)
+		}
+		_, err := conn.Write([]byte{'S'})
+		return err
+	},
+	"mysql": func(conn net.Conn, r *bufio.Reader) error {
+		// Handshake v10 con CLIENT_SSL en las capacidades
+		var payload []byte
+		payload = append(payload, 10)
+		payload = append(payload, "8.0.36-fake\x00"...)
+		payload = append(payload, 1, 0, 0, 0)
+		payload = append(payload, "abcdefgh"...)
+		payload = append(payload, 0)
+		payload = append(payload, 0x00, 0x8a) // 0x8a00: CLIENT_SSL | CLIENT_PROTOCOL_41 | CLIENT_SECURE_CONNECTION
+		payload = append(payload, 33, 2, 0, 0, 0)
+		packet := []byte{byte(len(payload)), 0, 0, 0}
+		conn.Write(append(packet, payload...))
+
+		request := make([]byte, 36)
+		if _, err := io.ReadFull(r, request); err != nil {
+			return err
+		}
+		if request[3] != 1 || binary.LittleEndian.Uint32(request[4:8])&0x0800 == 0 {
+			return fmt.Errorf("unexpected SSLRequest %x", request)
+		}
+		return nil
+	},
+	"xmpp": func(conn net.Conn, r *bufio.Reader) error {
+		if _, err := r.ReadString('>'); err != nil { // <?xml ...?>
+			return err
+		}
+		if _, err := r.ReadString('>'); err != nil { // <stream:stream ...>
+			return err
+		}
+		io.WriteString(conn, "<?xml version='1.0'?><stream:stream from='chat.example.com' id='1' version='1.0' "+
+			"xmlns='jabber:client' xmlns:stream='http://etherx.jabber.org/streams'>"+
+			"<stream:features><starttls xmlns='urn:ietf:params:xml:ns:xmpp-tls'><required/></starttls></stream:features>")
+		request, err := r.ReadString('>')
+		if err != nil {
+			return err
+		}
+		if !strings.Contains(request, "<starttls") {
+			return fmt.Errorf("unexpected request %q", request)
+		}
+		_, err = io.WriteString(conn, "<proceed xmlns='urn:ietf:params:xml:ns:xmpp-tls'/>")
+		return err
+	},
+}
+
+// Test para --starttls contra un servidor falso de cada protocolo
+func TestStartTLS(t *testing.T) {
+	cert := newTestLeaf(t, nil, "starttls.example.com")
+
+	for protocol, preamble := range fakePreambles {
+		t.Run(protocol, func(t *testing.T) {
+			address := startFakeServer(t, cert, preamble)
+			out, err := runCommand(t, "inspect", "--remote", address, "--starttls", protocol, "--timeout", "5s")
+			if err != nil {
+				t.Fatalf("Error running inspect --starttls %s: %v\n%s", protocol, err, out)
+			}
+			if !strings.Contains(out, "starttls.example.com") {
+				t.Fatalf("Unexpected inspect output:\n%s", out)
+			}
+		})
+	}
+
+	// Un servidor que no ofrece STARTTLS debe dar un error claro
+	address := startFakeServer(t, cert, func(conn net.Conn, r *bufio.Reader) error {
+		io.WriteString(conn, "220 ready\r\n")
+		expectLine(r, "EHLO ")
+		io.WriteString(conn, "250 mail.example.com\r\n")
+		return fmt.Errorf("no STARTTLS")
+	})
+	out, err := runCommand(t, "check-expiration", "--remote", address, "--starttls", "smtp")
+	if exitCode(t, err) != 3 || !strings.Contains(out, "does not advertise STARTTLS") {
+		t.Fatalf("Expected an UNKNOWN result for a server without STARTTLS:\n%s", out)
+	}
+
+	// Una respuesta LDAP que anuncia una longitud enorme se rechaza sin reservarla
+	address = startFakeServer(t, cert, func(conn net.Conn, r *bufio.Reader) error {
+		io.ReadFull(r, make([]byte, 31))
+		conn.Write([]byte{0x30, 0x84, 0xff, 0xff, 0xff, 0xff})
+		return fmt.Errorf("oversized response")
+	})
+	if out, err := runCommand(t, "inspect", "--remote", address, "--starttls", "ldap", "--timeout", "5s"); err == nil || !strings.Contains(out, "BER element too large") {
+		t.Fatalf("Expected an oversized LDAP response to be rejected:\n%s", out)
+	}
+
+	if out, err := runCommand(t, "inspect", "--remote", address, "--starttls", "gopher"); err == nil || !strings.Contains(out, "unsupported STARTTLS protocol") {
+		t.Fatalf("Expected an unsupported protocol error:\n%s", out)
+	}
+}
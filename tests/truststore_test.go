@@ -0,0 +1,119 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testFingerprint(c *testCert) string {
+	sum := sha256.Sum256(c.cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// Test para truststore list, add, remove, dedupe y verify
+func TestTrustStore(t *testing.T) {
+	dir := t.TempDir()
+	alpha := newTestCA(t, "Alpha Root", nil)
+	beta := newTestCA(t, "beta Root", nil)
+	expired := newTestCert(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "Old Root"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		NotBefore:             time.Now().Add(-48 * time.Hour),
+		NotAfter:              time.Now().Add(-24 * time.Hour),
+	}, nil)
+	intermediate := newTestCA(t, "Alpha Intermediate", alpha)
+
+	// Un almacén mantenido a mano: desordenado, con texto libre, un duplicado y una raíz caducada
+	bundle := filepath.Join(dir, "bundle.pem")
+	writePEM(t, bundle, beta, alpha, expired, beta)
+	data, _ := os.ReadFile(bundle)
+	os.WriteFile(bundle, append([]byte("Hand-written notes\n"), data...), 0644)
+
+	trust := func(args ...string) (string, error) {
+		return runCommand(t, append(append([]string{"truststore"}, args...), "--bundle", bundle)...)
+	}
+	out, err := trust("list")
+	if err != nil || !strings.Contains(out, "4 certificate(s)") || !strings.Contains(out, "expired") ||
+		!strings.Contains(out, testFingerprint(alpha)[:16]) || !strings.Contains(out, "CN=beta Root") {
+		t.Fatalf("Unexpected truststore list output: %v\n%s", err, out)
+	}
+
+	out, err = trust("verify")
+	if err == nil || !strings.Contains(out, "duplicate") || !strings.Contains(out, "expired on") || !strings.Contains(out, "has 2 problem(s)") {
+		t.Fatalf("Expected verify to report the duplicate and the expired root:\n%s", out)
+	}
+
+	// dedupe deja un archivo ordenado y comentado, idéntico en cada ejecución
+	out, err = trust("dedupe")
+	if err != nil || !strings.Contains(out, "Removed duplicate") || !strings.Contains(out, "Removed expired") || !strings.Contains(out, "Saved 2 certificate(s)") {
+		t.Fatalf("Error running truststore dedupe: %v\n%s", err, out)
+	}
+	first, _ := os.ReadFile(bundle)
+	if _, err := trust("dedupe"); err != nil {
+		t.Fatalf("Error running truststore dedupe again: %v", err)
+	}
+	second, _ := os.ReadFile(bundle)
+	if string(first) != string(second) {
+		t.Fatalf("dedupe is not deterministic:\n%s\n%s", first, second)
+	}
+	text := string(second)
+	if !strings.HasPrefix(text, "# CA bundle maintained with ssl-tool truststore.") || strings.Contains(text, "Hand-written") ||
+		!strings.Contains(text, "# SHA-256: "+testFingerprint(alpha)) || strings.Index(text, "CN=Alpha Root") > strings.Index(text, "CN=beta Root") {
+		t.Fatalf("Unexpected bundle contents:\n%s", text)
+	}
+	if out, err := trust("verify", "--canonical"); err != nil || !strings.Contains(out, "2 certificate(s), no problems found") {
+		t.Fatalf("Expected the deduplicated bundle to verify: %v\n%s", err, out)
+	}
+
+	// add con etiqueta; repetirlo no duplica
+	intermediateFile := filepath.Join(dir, "intermediate.pem")
+	writePEM(t, intermediateFile, intermediate)
+	out, err = trust("add", intermediateFile, "--label", "Custom Intermediate")
+	if err != nil || !strings.Contains(out, "Added "+testFingerprint(intermediate)[:16]+" Custom Intermediate") {
+		t.Fatalf("Error running truststore add: %v\n%s", err, out)
+	}
+	out, err = trust("add", intermediateFile)
+	if err != nil || !strings.Contains(out, "Already in the bundle") {
+		t.Fatalf("Expected the certificate to be reported as present: %v\n%s", err, out)
+	}
+	if data, _ := os.ReadFile(bundle); !strings.Contains(string(data), "# Label: Custom Intermediate") {
+		t.Fatalf("Expected the label to be kept:\n%s", data)
+	}
+	out, err = trust("verify")
+	if err == nil || !strings.Contains(out, "not self-signed (issued by CN=Alpha Root)") {
+		t.Fatalf("Expected verify to reject the intermediate:\n%s", out)
+	}
+
+	// remove por prefijo de la huella, con --out
+	cleaned := filepath.Join(dir, "cleaned.pem")
+	out, err = trust("remove", testFingerprint(intermediate)[:10], "--out", cleaned)
+	if err != nil || !strings.Contains(out, "Removed "+testFingerprint(intermediate)[:16]) {
+		t.Fatalf("Error running truststore remove: %v\n%s", err, out)
+	}
+	if data, _ := os.ReadFile(cleaned); string(data) != string(second) {
+		t.Fatalf("Expected the bundle without the intermediate:\n%s", data)
+	}
+	out, err = trust("remove", "ffffffffffff")
+	if err == nil || !strings.Contains(out, "no certificate in the bundle matches") {
+		t.Fatalf("Expected an unknown fingerprint to fail:\n%s", out)
+	}
+
+	// Cualquier bloque que no sea un certificado se rechaza
+	keyFile := filepath.Join(dir, "key.pem")
+	writeKey(t, keyFile, alpha)
+	key, _ := os.ReadFile(keyFile)
+	os.WriteFile(bundle, append(second, key...), 0644)
+	out, err = trust("list")
+	if err == nil || !strings.Contains(out, "block 3: unexpected PRIVATE KEY block") {
+		t.Fatalf("Expected the private key block to be rejected:\n%s", out)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitForEvent espera a que watch registre un evento JSON que cumpla match.
+func waitForEvent(t *testing.T, output *syncBuffer, description string, match func(event map[string]interface{}) bool) map[string]interface{} {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, line := range strings.Split(output.String(), "\n") {
+			var event map[string]interface{}
+			if json.Unmarshal([]byte(line), &event) == nil && match(event) {
+				return event
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("Timed out waiting for %s:\n%s", description, output)
+	return nil
+}
+
+// Test para watch: lectura inicial, archivos nuevos, modificados y renombrados
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing.pem")
+	writePEM(t, existing, newTestCertExpiring(t, "existing.example.com", 200*24*time.Hour))
+
+	output := startDaemon(t, "watch", "--path", dir, "--log-format", "json", "--debounce", "100ms", "--inventory", filepath.Join(dir, "none.db"))
+	waitForEvent(t, output, "the initial check", func(e map[string]interface{}) bool {
+		return e["event"] == "initial" && e["path"] == existing && e["state"] == "OK" && e["level"] == "INFO"
+	})
+
+	// Un certificado nuevo que caduca pronto se registra como CRITICAL
+	created := filepath.Join(dir, "new.pem")
+	writePEM(t, created, newTestCertExpiring(t, "new.example.com", 3*24*time.Hour))
+	event := waitForEvent(t, output, "the created certificate", func(e map[string]interface{}) bool {
+		return e["event"] == "create" && e["path"] == created
+	})
+	if event["state"] != "CRITICAL" || event["level"] != "ERROR" || event["subject"] != "CN=new.example.com" {
+		t.Fatalf("Unexpected event for the created certificate: %v", event)
+	}
+
+	// Al sustituir el contenido se vuelve a comprobar
+	writePEM(t, existing, newTestCertExpiring(t, "renewed.example.com", 20*24*time.Hour))
+	waitForEvent(t, output, "the modified certificate", func(e map[string]interface{}) bool {
+		return e["event"] == "write" && e["path"] == existing && e["subject"] == "CN=renewed.example.com" && e["state"] == "WARNING"
+	})
+
+	// Un renombrado genera rename en la ruta antigua y create en la nueva
+	renamed := filepath.Join(dir, "renamed.crt")
+	if err := os.Rename(created, renamed); err != nil {
+		t.Fatalf("Failed to rename: %v", err)
+	}
+	waitForEvent(t, output, "the rename event", func(e map[string]interface{}) bool {
+		return e["event"] == "rename" && e["path"] == created
+	})
+	waitForEvent(t, output, "the renamed certificate", func(e map[string]interface{}) bool {
+		return e["event"] == "create" && e["path"] == renamed && e["subject"] == "CN=new.example.com"
+	})
+
+	// Los archivos que no coinciden con --include se ignoran
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a certificate"), 0644)
+	time.Sleep(500 * time.Millisecond)
+	if strings.Contains(output.String(), "notes.txt") {
+		t.Fatalf("Files outside --include should be ignored:\n%s", output)
+	}
+}
+
+// Test para watch --recursive con un subdirectorio ilegible: se registra el error y se vigila el resto
+func TestWatchUnreadableDir(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can read directories with mode 0o000")
+	}
+	dir := t.TempDir()
+	private := filepath.Join(dir, "private")
+	nested := filepath.Join(dir, "nginx")
+	for _, d := range []string{private, nested} {
+		if err := os.Mkdir(d, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+	existing := filepath.Join(nested, "site.pem")
+	writePEM(t, existing, newTestCertExpiring(t, "site.example.com", 200*24*time.Hour))
+	if err := os.Chmod(private, 0o000); err != nil {
+		t.Fatalf("Failed to change permissions: %v", err)
+	}
+	t.Cleanup(func() { os.Chmod(private, 0755) })
+
+	output := startDaemon(t, "watch", "--path", dir, "--recursive", "--log-format", "json", "--debounce", "100ms", "--inventory", filepath.Join(dir, "none.db"))
+	waitForEvent(t, output, "the unreadable directory error", func(e map[string]interface{}) bool {
+		message, _ := e["error"].(string)
+		return e["msg"] == "watch error" && strings.Contains(message, private)
+	})
+	waitForEvent(t, output, "the initial check", func(e map[string]interface{}) bool {
+		return e["event"] == "initial" && e["path"] == existing && e["state"] == "OK"
+	})
+
+	// El resto del árbol se sigue vigilando
+	created := filepath.Join(nested, "new.pem")
+	writePEM(t, created, newTestCertExpiring(t, "new.example.com", 200*24*time.Hour))
+	waitForEvent(t, output, "the created certificate", func(e map[string]interface{}) bool {
+		return e["event"] == "create" && e["path"] == created
+	})
+}